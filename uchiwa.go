@@ -1,22 +1,42 @@
 package main
 
 import (
+	"bufio"
 	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/sensu/uchiwa/uchiwa"
 	"github.com/sensu/uchiwa/uchiwa/audit"
 	"github.com/sensu/uchiwa/uchiwa/authentication"
+	"github.com/sensu/uchiwa/uchiwa/authentication/certificate"
+	"github.com/sensu/uchiwa/uchiwa/authentication/db"
+	"github.com/sensu/uchiwa/uchiwa/authentication/github"
+	"github.com/sensu/uchiwa/uchiwa/authentication/gitlab"
+	"github.com/sensu/uchiwa/uchiwa/authentication/ldap"
+	"github.com/sensu/uchiwa/uchiwa/authentication/oidc"
+	"github.com/sensu/uchiwa/uchiwa/authentication/proxy"
 	"github.com/sensu/uchiwa/uchiwa/authorization"
 	"github.com/sensu/uchiwa/uchiwa/config"
 	"github.com/sensu/uchiwa/uchiwa/filters"
+	"github.com/sensu/uchiwa/uchiwa/logger"
 )
 
 func main() {
 	configFile := flag.String("c", "./config.json", "Full or relative path to the configuration file")
 	configDir := flag.String("d", "", "Full or relative path to the configuration directory, or comma delimited directories")
 	publicPath := flag.String("p", "public", "Full or relative path to the public directory")
+	hashPassword := flag.Bool("hash-password", false, "Read a password from the standard input, print its hash and exit")
+	hashAlgorithm := flag.String("hash-algorithm", authentication.Bcrypt, "Algorithm used by -hash-password, either bcrypt or argon2id")
 	flag.Parse()
 
+	if *hashPassword {
+		printPasswordHash(*hashAlgorithm)
+		return
+	}
+
 	config := config.Load(*configFile, *configDir)
 
 	u := uchiwa.Init(config)
@@ -24,12 +44,60 @@ func main() {
 	auth := authentication.New(config.Uchiwa.Auth)
 	if config.Uchiwa.Auth.Driver == "simple" {
 		auth.Simple(config.Uchiwa.Users)
+
+		s, err := authentication.NewTwoFactorFile(config.Uchiwa.Auth.TwoFactorFile)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		auth.EnableTwoFactor(s)
+	} else if config.Uchiwa.Auth.Driver == "ldap" {
+		auth.Advanced(ldap.New(config.Uchiwa.Ldap).Login, "ldap")
+	} else if config.Uchiwa.Auth.Driver == "github" {
+		github.New(config.Uchiwa.Github).Register(&auth)
+	} else if config.Uchiwa.Auth.Driver == "gitlab" {
+		gitlab.New(config.Uchiwa.Gitlab).Register(&auth)
+	} else if config.Uchiwa.Auth.Driver == "oidc" {
+		oidc.New(config.Uchiwa.Oidc).Register(&auth)
+	} else if config.Uchiwa.Auth.Driver == "certificate" {
+		certificate.New(config.Uchiwa.Certificate).Register(&auth)
+	} else if config.Uchiwa.Auth.Driver == "proxy" {
+		p, err := proxy.New(config.Uchiwa.Proxy)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		p.Register(&auth)
+	} else if config.Uchiwa.Auth.Driver == "sql" {
+		d, err := db.New(config.Uchiwa.Db)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		d.Register(&auth)
 	} else {
 		auth.None()
 	}
 
+	// API tokens
+	if auth.DriverName != "none" {
+		tokens, err := authentication.NewAPITokens(config.Uchiwa.Auth.TokensFile)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		auth.EnableAPITokens(tokens)
+	}
+
 	// Audit
-	audit.Log = audit.LogMock
+	a := config.Uchiwa.Audit
+	f, err := audit.NewFile(a.Logfile, a.Level, int64(a.MaxSize)*1024*1024, time.Duration(a.MaxAge)*time.Hour)
+	if err != nil {
+		logger.Warningf("The audit log is disabled: %s", err)
+		audit.Log = audit.LogMock
+		audit.Search = audit.SearchMock
+	} else {
+		f.MaxBackupAge = time.Duration(a.MaxBackupAge) * 24 * time.Hour
+		f.MaxBackups = a.MaxBackups
+		audit.Log = f.Log
+		audit.Search = f.Search
+	}
 
 	// Authorization
 	uchiwa.Authorization = &authorization.Uchiwa{}
@@ -39,3 +107,20 @@ func main() {
 
 	u.WebServer(publicPath, auth)
 }
+
+// printPasswordHash reads a password from the standard input and prints its
+// hash, which can be used as the passwordhash attribute of a user
+func printPasswordHash(algorithm string) {
+	fmt.Fprint(os.Stderr, "Password: ")
+	password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && password == "" {
+		logger.Fatalf("Could not read the password: %s", err)
+	}
+
+	hash, err := authentication.HashPassword(strings.TrimRight(password, "\r\n"), algorithm)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	fmt.Println(hash)
+}
@@ -1,20 +1,22 @@
 package uchiwa
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/sensu/uchiwa/uchiwa/logger"
+	"github.com/sensu/uchiwa/uchiwa/structs"
 )
 
 // DeleteAggregate deletes a specific aggregate
-func (u *Uchiwa) DeleteAggregate(name, dc string) error {
+func (u *Uchiwa) DeleteAggregate(ctx context.Context, name, dc string) error {
 	api, err := getAPI(u.Datacenters, dc)
 	if err != nil {
 		logger.Warning(err)
 		return err
 	}
 
-	err = api.DeleteAggregate(name)
+	err = api.DeleteAggregate(ctx, name)
 	if err != nil {
 		logger.Warning(err)
 		return err
@@ -24,14 +26,14 @@ func (u *Uchiwa) DeleteAggregate(name, dc string) error {
 }
 
 // GetAggregate retrieves a specific aggregate
-func (u *Uchiwa) GetAggregate(name, dc string) (*map[string]interface{}, error) {
+func (u *Uchiwa) GetAggregate(ctx context.Context, name, dc string) (*map[string]interface{}, error) {
 	api, err := getAPI(u.Datacenters, dc)
 	if err != nil {
 		logger.Warning(err)
 		return nil, err
 	}
 
-	aggregate, err := api.GetAggregate(name)
+	aggregate, err := api.GetAggregate(ctx, name)
 	if err != nil {
 		logger.Warning(err)
 		return nil, err
@@ -41,14 +43,14 @@ func (u *Uchiwa) GetAggregate(name, dc string) (*map[string]interface{}, error)
 }
 
 // GetAggregateChecks retrieves check members of an aggregate
-func (u *Uchiwa) GetAggregateChecks(name, dc string) (*[]interface{}, error) {
+func (u *Uchiwa) GetAggregateChecks(ctx context.Context, name, dc string) (*[]interface{}, error) {
 	api, err := getAPI(u.Datacenters, dc)
 	if err != nil {
 		logger.Warning(err)
 		return nil, err
 	}
 
-	checks, err := api.GetAggregateChecks(name)
+	checks, err := api.GetAggregateChecks(ctx, name)
 	if err != nil {
 		logger.Warning(err)
 		return nil, err
@@ -58,14 +60,14 @@ func (u *Uchiwa) GetAggregateChecks(name, dc string) (*[]interface{}, error) {
 }
 
 // GetAggregateClients retrieves client members of an aggregate
-func (u *Uchiwa) GetAggregateClients(name, dc string) (*[]interface{}, error) {
+func (u *Uchiwa) GetAggregateClients(ctx context.Context, name, dc string) (*[]interface{}, error) {
 	api, err := getAPI(u.Datacenters, dc)
 	if err != nil {
 		logger.Warning(err)
 		return nil, err
 	}
 
-	clients, err := api.GetAggregateClients(name)
+	clients, err := api.GetAggregateClients(ctx, name)
 	if err != nil {
 		logger.Warning(err)
 		return nil, err
@@ -75,14 +77,14 @@ func (u *Uchiwa) GetAggregateClients(name, dc string) (*[]interface{}, error) {
 }
 
 // GetAggregateResults retrieves check result members by severity of an aggregate
-func (u *Uchiwa) GetAggregateResults(name, severity, dc string) (*[]interface{}, error) {
+func (u *Uchiwa) GetAggregateResults(ctx context.Context, name, severity, dc string) (*[]interface{}, error) {
 	api, err := getAPI(u.Datacenters, dc)
 	if err != nil {
 		logger.Warning(err)
 		return nil, err
 	}
 
-	results, err := api.GetAggregateResults(name, severity)
+	results, err := api.GetAggregateResults(ctx, name, severity)
 	if err != nil {
 		logger.Warning(err)
 		return nil, err
@@ -91,22 +93,17 @@ func (u *Uchiwa) GetAggregateResults(name, severity, dc string) (*[]interface{},
 	return &results, nil
 }
 
-func (u *Uchiwa) findAggregate(name string) ([]interface{}, error) {
-	var checks []interface{}
-	for _, c := range u.Data.Aggregates {
-		m, ok := c.(map[string]interface{})
-		if !ok {
-			logger.Warningf("Could not assert this check to an interface %+v", c)
-			continue
-		}
-		if m["name"] == name {
-			checks = append(checks, m)
+func (u *Uchiwa) findAggregate(name string) ([]*structs.Aggregate, error) {
+	var aggregates []*structs.Aggregate
+	for _, a := range u.getData().Aggregates {
+		if a.Name == name {
+			aggregates = append(aggregates, a)
 		}
 	}
 
-	if len(checks) == 0 {
+	if len(aggregates) == 0 {
 		return nil, fmt.Errorf("Could not find any checks with the name '%s'", name)
 	}
 
-	return checks, nil
+	return aggregates, nil
 }
@@ -8,15 +8,12 @@ import (
 )
 
 func TestFindAggregate(t *testing.T) {
-	u := Uchiwa{
-		Data: &structs.Data{},
-	}
-
-	u.Data.Aggregates = []interface{}{
-		map[string]interface{}{"name": "foo", "dc": "us-east-1"},
-		map[string]interface{}{"name": "bar", "dc": "us-east-1"},
-		map[string]interface{}{"name": "foo", "dc": "us-west-1"},
-	}
+	u := Uchiwa{}
+	u.setData(&structs.Data{Aggregates: []*structs.Aggregate{
+		{Name: "foo", Dc: "us-east-1"},
+		{Name: "bar", Dc: "us-east-1"},
+		{Name: "foo", Dc: "us-west-1"},
+	}})
 
 	aggregates, err := u.findAggregate("foo")
 	assert.Nil(t, err)
@@ -2,9 +2,17 @@ package audit
 
 import "github.com/sensu/uchiwa/uchiwa/structs"
 
-// Log writes to audit log (Sensu Enterprise only)
+// Levels of the audit log. The default level only records the actions
+// modifying the Sensu data, while the verbose level records every entry
+const (
+	LevelDefault = "default"
+	LevelVerbose = "verbose"
+)
+
+// Log writes to audit log
 var Log func(structs.AuditLog) error
 
+// LogMock discards the audit log entries
 func LogMock(log structs.AuditLog) error {
 	return nil
 }
@@ -0,0 +1,216 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sensu/uchiwa/uchiwa/logger"
+	"github.com/sensu/uchiwa/uchiwa/structs"
+)
+
+// rename renames the audit log files, replaced in the tests
+var rename = os.Rename
+
+// File represents an audit logger writing JSON lines to a file, which is
+// rotated once it exceeds its maximum size or age. The rotated files beyond
+// MaxBackups or older than MaxBackupAge are deleted, unless they are zero
+type File struct {
+	Level        string
+	MaxAge       time.Duration
+	MaxBackupAge time.Duration
+	MaxBackups   int
+	MaxSize      int64
+	Path         string
+
+	file   *os.File
+	mu     sync.Mutex
+	opened time.Time
+	size   int64
+}
+
+// NewFile opens the audit log file at the provided path. A MaxSize (in bytes)
+// or a MaxAge of zero disables the corresponding rotation
+func NewFile(path, level string, maxSize int64, maxAge time.Duration) (*File, error) {
+	if level != LevelDefault && level != LevelVerbose {
+		return nil, fmt.Errorf("invalid audit level '%s'", level)
+	}
+
+	f := &File{Level: level, MaxAge: maxAge, MaxSize: maxSize, Path: path}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Log writes the entry to the audit log file, unless the entry is verbose
+// and the configured level is not
+func (f *File) Log(log structs.AuditLog) error {
+	if log.Level == "" {
+		log.Level = LevelDefault
+	}
+	if log.Level == LevelVerbose && f.Level != LevelVerbose {
+		return nil
+	}
+	if log.Date.IsZero() {
+		log.Date = time.Now()
+	}
+
+	line, err := json.Marshal(log)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return fmt.Errorf("the audit log file '%s' is closed", f.Path)
+	}
+
+	if f.shouldRotate(int64(len(line))) {
+		if err := f.rotate(); err != nil {
+			// Keep writing to the current file, if any, rather than losing
+			// the entry
+			if f.file == nil {
+				return err
+			}
+			logger.Warning(err)
+		}
+	}
+
+	n, err := f.file.Write(line)
+	f.size += int64(n)
+	return err
+}
+
+// Close closes the audit log file
+func (f *File) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+// open opens, or creates, the audit log file in append mode
+func (f *File) open() error {
+	file, err := os.OpenFile(f.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0640)
+	if err != nil {
+		return fmt.Errorf("could not open the audit log file '%s': %s", f.Path, err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("could not stat the audit log file '%s': %s", f.Path, err)
+	}
+
+	f.file = file
+	f.size = info.Size()
+	f.opened = time.Now()
+
+	// The age of the file is based on its first entry, since its
+	// modification time is updated by every write
+	if f.size != 0 {
+		if date, err := firstDate(f.Path); err == nil && !date.IsZero() {
+			f.opened = date
+		}
+	}
+	return nil
+}
+
+// firstDate returns the date of the first entry of the audit log file
+func firstDate(path string) (time.Time, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxEntrySize)
+	if !scanner.Scan() {
+		return time.Time{}, scanner.Err()
+	}
+
+	var log structs.AuditLog
+	if err := json.Unmarshal(scanner.Bytes(), &log); err != nil {
+		return time.Time{}, err
+	}
+	return log.Date, nil
+}
+
+// shouldRotate returns true if writing n more bytes would exceed the maximum
+// size of the file or if the file exceeded its maximum age
+func (f *File) shouldRotate(n int64) bool {
+	if f.size == 0 {
+		return false
+	}
+	if f.MaxSize > 0 && f.size+n > f.MaxSize {
+		return true
+	}
+	if f.MaxAge > 0 && time.Since(f.opened) > f.MaxAge {
+		return true
+	}
+	return false
+}
+
+// rotate renames the current file with a timestamp suffix and opens a new one.
+// The current file is reopened if it can't be renamed
+func (f *File) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	f.file = nil
+
+	rotated := fmt.Sprintf("%s.%s", f.Path, time.Now().Format("20060102T150405.000000000"))
+	if err := rename(f.Path, rotated); err != nil {
+		if err := f.open(); err != nil {
+			return err
+		}
+		return fmt.Errorf("could not rotate the audit log file '%s': %s", f.Path, err)
+	}
+
+	if err := f.open(); err != nil {
+		return err
+	}
+	f.prune()
+	return nil
+}
+
+// prune deletes the oldest rotated files beyond MaxBackups and the rotated
+// files last written before MaxBackupAge
+func (f *File) prune() {
+	if f.MaxBackups <= 0 && f.MaxBackupAge <= 0 {
+		return
+	}
+
+	paths, err := f.rotated()
+	if err != nil {
+		logger.Warningf("Could not list the rotated audit log files: %s", err)
+		return
+	}
+
+	for i, path := range paths {
+		remove := f.MaxBackups > 0 && i < len(paths)-f.MaxBackups
+		if !remove && f.MaxBackupAge > 0 {
+			info, err := os.Stat(path)
+			remove = err == nil && time.Since(info.ModTime()) > f.MaxBackupAge
+		}
+
+		if remove {
+			if err := os.Remove(path); err != nil {
+				logger.Warningf("Could not delete the rotated audit log file '%s': %s", path, err)
+			}
+		}
+	}
+}
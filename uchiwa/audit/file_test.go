@@ -0,0 +1,201 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/sensu/uchiwa/uchiwa/structs"
+	"github.com/stretchr/testify/assert"
+)
+
+func readLogs(t *testing.T, path string) []structs.AuditLog {
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var logs []structs.AuditLog
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var log structs.AuditLog
+		if err := json.Unmarshal(scanner.Bytes(), &log); err != nil {
+			t.Fatal(err)
+		}
+		logs = append(logs, log)
+	}
+	return logs
+}
+
+func TestFileLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+
+	_, err = NewFile(path, "foo", 0, 0)
+	assert.NotNil(t, err)
+
+	_, err = NewFile(filepath.Join(dir, "missing", "audit.log"), LevelDefault, 0, 0)
+	assert.NotNil(t, err)
+
+	f, err := NewFile(path, LevelDefault, 0, 0)
+	assert.Nil(t, err)
+
+	assert.Nil(t, f.Log(structs.AuditLog{Action: "clientdelete", Dc: "us-east-1", User: "alice"}))
+	assert.Nil(t, f.Log(structs.AuditLog{Action: "loginsuccess", Level: LevelVerbose, User: "alice"}))
+	assert.Nil(t, f.Close())
+
+	// Verbose entries are ignored with the default level
+	logs := readLogs(t, path)
+	assert.Equal(t, 1, len(logs))
+	assert.Equal(t, "clientdelete", logs[0].Action)
+	assert.Equal(t, LevelDefault, logs[0].Level)
+	assert.Equal(t, "us-east-1", logs[0].Dc)
+	assert.False(t, logs[0].Date.IsZero())
+
+	// The file is appended when reopened
+	f, err = NewFile(path, LevelVerbose, 0, 0)
+	assert.Nil(t, err)
+	assert.Nil(t, f.Log(structs.AuditLog{Action: "loginsuccess", Level: LevelVerbose, User: "alice"}))
+	assert.Nil(t, f.Close())
+	assert.Equal(t, 2, len(readLogs(t, path)))
+
+	assert.NotNil(t, f.Log(structs.AuditLog{Action: "clientdelete"}))
+}
+
+func TestFileRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+
+	// Rotation based on the size
+	f, err := NewFile(path, LevelDefault, 150, 0)
+	assert.Nil(t, err)
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, f.Log(structs.AuditLog{Action: "eventresolve", User: "alice"}))
+	}
+
+	files, _ := filepath.Glob(path + ".*")
+	assert.Equal(t, 2, len(files))
+	assert.Equal(t, 1, len(readLogs(t, path)))
+	assert.Nil(t, f.Close())
+
+	// Rotation based on the age
+	f, err = NewFile(path, LevelDefault, 0, time.Hour)
+	assert.Nil(t, err)
+	assert.Nil(t, f.Log(structs.AuditLog{Action: "eventresolve"}))
+	files, _ = filepath.Glob(path + ".*")
+	assert.Equal(t, 2, len(files))
+
+	f.opened = time.Now().Add(-2 * time.Hour)
+	assert.Nil(t, f.Log(structs.AuditLog{Action: "eventresolve"}))
+	files, _ = filepath.Glob(path + ".*")
+	assert.Equal(t, 3, len(files))
+	assert.Equal(t, 1, len(readLogs(t, path)))
+	assert.Nil(t, f.Close())
+}
+
+func TestFileRotationAge(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+
+	// The age is based on the first entry, even if the file was just written
+	f, err := NewFile(path, LevelDefault, 0, time.Hour)
+	assert.Nil(t, err)
+	assert.Nil(t, f.Log(structs.AuditLog{Action: "eventresolve", Date: time.Now().Add(-2 * time.Hour)}))
+	assert.Nil(t, f.Log(structs.AuditLog{Action: "eventresolve"}))
+	assert.Nil(t, f.Close())
+
+	f, err = NewFile(path, LevelDefault, 0, time.Hour)
+	assert.Nil(t, err)
+	assert.Nil(t, f.Log(structs.AuditLog{Action: "eventresolve"}))
+	files, _ := filepath.Glob(path + ".*")
+	assert.Equal(t, 1, len(files))
+	assert.Equal(t, 2, len(readLogs(t, files[0])))
+	assert.Equal(t, 1, len(readLogs(t, path)))
+	assert.Nil(t, f.Close())
+}
+
+func TestFileRotationFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+
+	rename = func(oldpath, newpath string) error {
+		return errors.New("permission denied")
+	}
+	defer func() { rename = os.Rename }()
+
+	// The entries are still written to the current file
+	f, err := NewFile(path, LevelDefault, 1, 0)
+	assert.Nil(t, err)
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, f.Log(structs.AuditLog{Action: "eventresolve", Output: strconv.Itoa(i)}))
+	}
+	files, _ := filepath.Glob(path + ".*")
+	assert.Empty(t, files)
+	assert.Equal(t, 3, len(readLogs(t, path)))
+
+	// The file is rotated once it can be renamed
+	rename = os.Rename
+	assert.Nil(t, f.Log(structs.AuditLog{Action: "eventresolve", Output: "3"}))
+	files, _ = filepath.Glob(path + ".*")
+	assert.Equal(t, 1, len(files))
+	assert.Equal(t, 3, len(readLogs(t, files[0])))
+	assert.Equal(t, "3", readLogs(t, path)[0].Output)
+	assert.Nil(t, f.Close())
+}
+
+func TestFileRetention(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+
+	// Only the most recent rotated files are kept
+	f, err := NewFile(path, LevelDefault, 1, 0)
+	assert.Nil(t, err)
+	f.MaxBackups = 2
+	for i := 0; i < 5; i++ {
+		assert.Nil(t, f.Log(structs.AuditLog{Action: "eventresolve", Output: strconv.Itoa(i)}))
+	}
+
+	files, _ := filepath.Glob(path + ".*")
+	assert.Equal(t, 2, len(files))
+	assert.Equal(t, "2", readLogs(t, files[0])[0].Output)
+	assert.Equal(t, "3", readLogs(t, files[1])[0].Output)
+
+	// The rotated files that are too old are deleted
+	f.MaxBackups = 0
+	f.MaxBackupAge = time.Hour
+	old := time.Now().Add(-2 * time.Hour)
+	os.Chtimes(files[0], old, old)
+	assert.Nil(t, f.Log(structs.AuditLog{Action: "eventresolve", Output: "5"}))
+
+	files, _ = filepath.Glob(path + ".*")
+	assert.Equal(t, 2, len(files))
+	assert.Equal(t, "3", readLogs(t, files[0])[0].Output)
+	assert.Nil(t, f.Close())
+}
@@ -0,0 +1,192 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/sensu/uchiwa/uchiwa/logger"
+	"github.com/sensu/uchiwa/uchiwa/structs"
+)
+
+// Default and maximum number of entries returned by a search
+const (
+	DefaultLimit = 100
+	MaxLimit     = 1000
+)
+
+// maxEntrySize is the maximum size of an entry read from the audit log files
+const maxEntrySize = 1024 * 1024
+
+// Filter contains the criteria of an audit log search. Empty attributes do
+// not restrict the search
+type Filter struct {
+	Action     string
+	Dc         string
+	From       time.Time
+	Limit      int
+	Offset     int
+	RemoteAddr string
+	To         time.Time
+	User       string
+}
+
+// Result contains a page of audit log entries, sorted from the most recent
+// to the oldest, along with the total number of matching entries
+type Result struct {
+	Entries []structs.AuditLog `json:"entries"`
+	Limit   int                `json:"limit"`
+	Offset  int                `json:"offset"`
+	Total   int                `json:"total"`
+}
+
+// Search searches the audit log
+var Search func(Filter) (*Result, error)
+
+// SearchMock returns an empty result
+func SearchMock(filter Filter) (*Result, error) {
+	filter.normalize()
+	return &Result{Entries: []structs.AuditLog{}, Limit: filter.Limit, Offset: filter.Offset}, nil
+}
+
+// Match returns true if the entry satisfies the filter
+func (filter *Filter) Match(log structs.AuditLog) bool {
+	if filter.Action != "" && filter.Action != log.Action {
+		return false
+	}
+	if filter.Dc != "" && filter.Dc != log.Dc {
+		return false
+	}
+	if filter.RemoteAddr != "" && filter.RemoteAddr != log.RemoteAddr {
+		return false
+	}
+	if filter.User != "" && filter.User != log.User {
+		return false
+	}
+	if !filter.From.IsZero() && log.Date.Before(filter.From) {
+		return false
+	}
+	if !filter.To.IsZero() && log.Date.After(filter.To) {
+		return false
+	}
+	return true
+}
+
+// normalize bounds the pagination attributes of the filter
+func (filter *Filter) normalize() {
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultLimit
+	}
+	if filter.Limit > MaxLimit {
+		filter.Limit = MaxLimit
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+}
+
+// Search reads the current and the rotated audit log files and returns the
+// entries matching the filter. The files are only opened while holding the
+// lock, so the search does not block the audit log while reading them
+func (f *File) Search(filter Filter) (*Result, error) {
+	filter.normalize()
+
+	files, readers, err := f.openFiles()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		for _, file := range files {
+			file.Close()
+		}
+	}()
+
+	var matches []structs.AuditLog
+	for i, file := range files {
+		entries, err := readFile(file.Name(), readers[i], &filter)
+		if err != nil {
+			return nil, err
+		}
+		matches = append(matches, entries...)
+	}
+
+	result := &Result{Entries: []structs.AuditLog{}, Limit: filter.Limit, Offset: filter.Offset, Total: len(matches)}
+	for i := len(matches) - 1 - filter.Offset; i >= 0 && len(result.Entries) < filter.Limit; i-- {
+		result.Entries = append(result.Entries, matches[i])
+	}
+	return result, nil
+}
+
+// openFiles opens the rotated and the current audit log files, in
+// chronological order, and returns them along with their readers. The reader
+// of the current file stops at its current size, so an entry being written
+// is not read
+func (f *File) openFiles() ([]*os.File, []io.Reader, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	paths, err := f.rotated()
+	if err != nil {
+		return nil, nil, err
+	}
+	paths = append(paths, f.Path)
+
+	var files []*os.File
+	var readers []io.Reader
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			for _, file := range files {
+				file.Close()
+			}
+			return nil, nil, err
+		}
+
+		files = append(files, file)
+		if path == f.Path {
+			readers = append(readers, io.LimitReader(file, f.size))
+		} else {
+			readers = append(readers, file)
+		}
+	}
+	return files, readers, nil
+}
+
+// rotated returns the paths of the rotated audit log files. They are suffixed
+// with a timestamp so sorting their names also sorts them chronologically
+func (f *File) rotated() ([]string, error) {
+	paths, err := filepath.Glob(f.Path + ".*")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// readFile returns the entries of the audit log file matching the filter
+func readFile(path string, r io.Reader, filter *Filter) ([]structs.AuditLog, error) {
+	var entries []structs.AuditLog
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxEntrySize)
+	for scanner.Scan() {
+		var log structs.AuditLog
+		if err := json.Unmarshal(scanner.Bytes(), &log); err != nil {
+			logger.Debugf("Skipping an invalid entry of the audit log file '%s': %s", path, err)
+			continue
+		}
+		if filter.Match(log) {
+			entries = append(entries, log)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read the audit log file '%s': %s", path, err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,99 @@
+package audit
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sensu/uchiwa/uchiwa/structs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileSearch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+
+	// Rotate the file after every entry so the search spans several files
+	f, err := NewFile(path, LevelVerbose, 1, 0)
+	assert.Nil(t, err)
+	defer f.Close()
+
+	date := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	logs := []structs.AuditLog{
+		{Date: date, Action: "clientdelete", Dc: "us-east-1", RemoteAddr: "10.0.0.1", User: "alice"},
+		{Date: date.Add(time.Hour), Action: "eventresolve", Dc: "us-east-1", RemoteAddr: "10.0.0.2", User: "bob"},
+		{Date: date.Add(2 * time.Hour), Action: "eventresolve", Dc: "us-west-1", RemoteAddr: "10.0.0.1", User: "alice"},
+		{Date: date.Add(3 * time.Hour), Action: "loginsuccess", Level: LevelVerbose, RemoteAddr: "10.0.0.1", User: "alice"},
+	}
+	for _, log := range logs {
+		assert.Nil(t, f.Log(log))
+	}
+
+	// Every entry, from the most recent to the oldest
+	result, err := f.Search(Filter{})
+	assert.Nil(t, err)
+	assert.Equal(t, 4, result.Total)
+	assert.Equal(t, DefaultLimit, result.Limit)
+	assert.Equal(t, "loginsuccess", result.Entries[0].Action)
+	assert.Equal(t, "clientdelete", result.Entries[3].Action)
+
+	result, _ = f.Search(Filter{User: "alice"})
+	assert.Equal(t, 3, result.Total)
+
+	result, _ = f.Search(Filter{Action: "eventresolve", Dc: "us-east-1"})
+	assert.Equal(t, 1, result.Total)
+	assert.Equal(t, "bob", result.Entries[0].User)
+
+	result, _ = f.Search(Filter{RemoteAddr: "10.0.0.1", From: date.Add(time.Hour), To: date.Add(2 * time.Hour)})
+	assert.Equal(t, 1, result.Total)
+	assert.Equal(t, "us-west-1", result.Entries[0].Dc)
+
+	// Pagination
+	result, _ = f.Search(Filter{Limit: 2, Offset: 1})
+	assert.Equal(t, 4, result.Total)
+	assert.Equal(t, 2, len(result.Entries))
+	assert.Equal(t, "eventresolve", result.Entries[0].Action)
+	assert.Equal(t, "us-west-1", result.Entries[0].Dc)
+	assert.Equal(t, "bob", result.Entries[1].User)
+
+	result, _ = f.Search(Filter{Offset: 10})
+	assert.Equal(t, 4, result.Total)
+	assert.Equal(t, 0, len(result.Entries))
+
+	result, _ = f.Search(Filter{Limit: 5000})
+	assert.Equal(t, MaxLimit, result.Limit)
+}
+
+func TestFileSearchLongEntries(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+
+	f, err := NewFile(path, LevelDefault, 0, 0)
+	assert.Nil(t, err)
+	defer f.Close()
+
+	// An entry larger than the default buffer of the scanner
+	assert.Nil(t, f.Log(structs.AuditLog{Action: "stashcreate", Output: strings.Repeat("a", 100*1024)}))
+	assert.Nil(t, f.Log(structs.AuditLog{Action: "clientdelete"}))
+
+	result, err := f.Search(Filter{})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, result.Total)
+	assert.Equal(t, 100*1024, len(result.Entries[1].Output))
+
+	// An entry exceeding the maximum size fails the search
+	assert.Nil(t, f.Log(structs.AuditLog{Action: "stashcreate", Output: strings.Repeat("a", maxEntrySize)}))
+	_, err = f.Search(Filter{})
+	assert.NotNil(t, err)
+}
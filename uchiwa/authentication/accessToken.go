@@ -56,7 +56,7 @@ func findAccessToken(locations ...TokenLocation) TokenLocation {
 // findRoleFromAccessToken finds within the Role slice a role with
 // the corresponding token
 func findRoleFromAccessToken(token string) (*Role, error) {
-	for _, role := range Roles {
+	for _, role := range GetRoles() {
 		if role.AccessToken == token {
 			return &role, nil
 		}
@@ -73,6 +73,20 @@ func verifyAccessToken(r *http.Request) (*jwt.Token, error) {
 		return nil, errors.New("")
 	}
 
+	if apiTokens != nil && strings.HasPrefix(accessToken, apiTokenPrefix) {
+		apiToken, err := apiTokens.Verify(accessToken)
+		if err != nil {
+			logger.Debug(err)
+			return nil, errors.New("")
+		}
+
+		token := jwt.New(jwt.GetSigningMethod("RS256"))
+		token.Claims["APIToken"] = apiToken.ID
+		token.Claims["Role"] = apiToken.Role
+		token.Claims["Username"] = apiToken.Username
+		return token, nil
+	}
+
 	role, err := findRoleFromAccessToken(accessToken)
 	if err != nil {
 		return nil, errors.New("")
@@ -0,0 +1,358 @@
+package authentication
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// apiTokenPrefix identifies the API tokens among the access tokens
+const apiTokenPrefix = "uchiwa_"
+
+// apiTokenIDLength is the length of the encoded IDs of the API tokens, which
+// may contain underscores
+const apiTokenIDLength = 12
+
+// lastUsedPersistInterval limits how often the last-used timestamps alone
+// are written to the file
+const lastUsedPersistInterval = time.Minute
+
+// apiTokens contains the API tokens store, if enabled
+var apiTokens *APITokens
+
+// APIToken contains the attributes of an API token minted by a user. Only the
+// SHA-256 hash of the secret is kept
+type APIToken struct {
+	Created     time.Time  `json:"created"`
+	Datacenters []string   `json:"datacenters"`
+	Expires     *time.Time `json:"expires,omitempty"`
+	Hash        string     `json:"hash,omitempty"`
+	ID          string     `json:"id"`
+	LastUsed    *time.Time `json:"lastused,omitempty"`
+	Name        string     `json:"name"`
+	Readonly    bool       `json:"readonly"`
+	Role        Role       `json:"role"`
+	Token       string     `json:"token,omitempty"`
+	Username    string     `json:"username"`
+}
+
+// APITokens stores the API tokens in memory and, if a path is provided,
+// in a JSON file
+type APITokens struct {
+	Path string
+
+	lookup    userFn
+	mu        sync.Mutex
+	persisted time.Time
+	tokens    map[string]*APIToken
+}
+
+// NewAPITokens returns an API tokens store, loading the tokens previously
+// stored in the file if it exists
+func NewAPITokens(path string) (*APITokens, error) {
+	s := &APITokens{Path: path, tokens: make(map[string]*APIToken)}
+	if path == "" {
+		return s, nil
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("could not read the API tokens file: %s", err)
+	}
+
+	var tokens []*APIToken
+	if err := json.Unmarshal(b, &tokens); err != nil {
+		return nil, fmt.Errorf("could not parse the API tokens file: %s", err)
+	}
+	for _, token := range tokens {
+		s.tokens[token.ID] = token
+	}
+
+	return s, nil
+}
+
+// Create mints a new API token for the user, scoped to the provided role. The
+// returned token contains the secret, which can't be retrieved afterwards
+func (s *APITokens) Create(token APIToken, username string, role *Role) (*APIToken, error) {
+	if strings.TrimSpace(token.Name) == "" {
+		return nil, errors.New("the name of the token can't be empty")
+	}
+	if token.Expires != nil && token.Expires.Before(time.Now()) {
+		return nil, errors.New("the expiration of the token must be in the future")
+	}
+
+	scoped, err := scopeRole(*role, token.Readonly, token.Datacenters)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := RandomString(apiTokenIDLength * 3 / 4)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := RandomString(32)
+	if err != nil {
+		return nil, err
+	}
+
+	token.Created = time.Now()
+	token.Datacenters = scoped.Datacenters
+	token.Hash = hashSecret(secret)
+	token.ID = id
+	token.LastUsed = nil
+	token.Readonly = scoped.Readonly
+	token.Role = scoped
+	token.Token = ""
+	token.Username = username
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tokens[id] = &token
+	if err := s.persist(); err != nil {
+		delete(s.tokens, id)
+		return nil, err
+	}
+
+	created := token
+	created.Hash = ""
+	created.Token = fmt.Sprintf("%s%s_%s", apiTokenPrefix, id, secret)
+	return &created, nil
+}
+
+// Revoke revokes every API token of the user
+func (s *APITokens) Revoke(username string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	revoked := make(map[string]*APIToken)
+	for id, token := range s.tokens {
+		if token.Username == username {
+			revoked[id] = token
+			delete(s.tokens, id)
+		}
+	}
+	if len(revoked) == 0 {
+		return nil
+	}
+
+	if err := s.persist(); err != nil {
+		for id, token := range revoked {
+			s.tokens[id] = token
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete revokes the API token of the user. Admins can revoke any token
+func (s *APITokens) Delete(id, username string, admin bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, ok := s.tokens[id]
+	if !ok || (token.Username != username && !admin) {
+		return fmt.Errorf("no API token found with the ID '%s'", id)
+	}
+
+	delete(s.tokens, id)
+	if err := s.persist(); err != nil {
+		s.tokens[id] = token
+		return err
+	}
+	return nil
+}
+
+// List returns the API tokens of the user, or every token for admins,
+// without their hash
+func (s *APITokens) List(username string, admin bool) []APIToken {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tokens := []APIToken{}
+	for _, token := range s.tokens {
+		if token.Username == username || admin {
+			t := *token
+			t.Hash = ""
+			tokens = append(tokens, t)
+		}
+	}
+	return tokens
+}
+
+// Verify returns the API token corresponding to the provided secret token,
+// as long as it is not expired, and records its usage. The role of the token
+// is the current role of its owner, restricted to the scope of the token, if
+// the owner can be looked up
+func (s *APITokens) Verify(secretToken string) (*APIToken, error) {
+	// The token is made of the prefix, the ID and the secret, separated by an
+	// underscore. The ID has a fixed length since it may contain underscores
+	rest := strings.TrimPrefix(secretToken, apiTokenPrefix)
+	if !strings.HasPrefix(secretToken, apiTokenPrefix) || len(rest) <= apiTokenIDLength+1 || rest[apiTokenIDLength] != '_' {
+		return nil, errors.New("invalid API token")
+	}
+	id, secret := rest[:apiTokenIDLength], rest[apiTokenIDLength+1:]
+
+	s.mu.Lock()
+	token, ok := s.tokens[id]
+	if !ok || subtle.ConstantTimeCompare([]byte(token.Hash), []byte(hashSecret(secret))) != 1 {
+		s.mu.Unlock()
+		return nil, errors.New("invalid API token")
+	}
+
+	now := time.Now()
+	if token.Expires != nil && token.Expires.Before(now) {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("the API token '%s' is expired", token.Name)
+	}
+
+	token.LastUsed = &now
+	if now.Sub(s.persisted) > lastUsedPersistInterval {
+		s.persist()
+	}
+
+	t := *token
+	s.mu.Unlock()
+
+	if s.lookup == nil {
+		return &t, nil
+	}
+
+	// The owner may have lost some permissions since the token was created
+	owner, err := s.lookup(t.Username)
+	if err != nil {
+		return nil, fmt.Errorf("could not find the owner of the API token '%s': %s", t.Name, err)
+	}
+	if owner.Role.Name == "" {
+		return nil, fmt.Errorf("no role is assigned to the owner of the API token '%s'", t.Name)
+	}
+
+	role, err := intersectRole(owner.Role, t.Readonly, t.Datacenters)
+	if err != nil {
+		return nil, fmt.Errorf("the API token '%s' is out of the scope of its owner: %s", t.Name, err)
+	}
+	t.Role = role
+
+	return &t, nil
+}
+
+// persist writes the tokens to the file, if any. The caller must hold the lock
+func (s *APITokens) persist() error {
+	if s.Path == "" {
+		return nil
+	}
+
+	tokens := make([]*APIToken, 0, len(s.tokens))
+	for _, token := range s.tokens {
+		tokens = append(tokens, token)
+	}
+
+	b, err := json.Marshal(tokens)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so the file is never left truncated
+	tmp := s.Path + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0600); err != nil {
+		return fmt.Errorf("could not write the API tokens file: %s", err)
+	}
+	if err := os.Rename(tmp, s.Path); err != nil {
+		return fmt.Errorf("could not write the API tokens file: %s", err)
+	}
+
+	s.persisted = time.Now()
+	return nil
+}
+
+// hashSecret returns the hex-encoded SHA-256 hash of the secret. A fast hash
+// is enough since the secrets are randomly generated
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// scopeRole restricts the role of the user to the scope requested for the
+// token, which can't exceed the permissions of the role
+func scopeRole(role Role, readonly bool, datacenters []string) (Role, error) {
+	role.AccessToken = ""
+	role.Readonly = role.Readonly || readonly
+	if role.Readonly {
+		role.Admin = false
+	}
+
+	if len(datacenters) == 0 {
+		return role, nil
+	}
+
+	if len(role.Datacenters) != 0 {
+		for _, dc := range datacenters {
+			found := false
+			for _, allowed := range role.Datacenters {
+				if dc == allowed {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return role, fmt.Errorf("the role '%s' does not have access to the datacenter '%s'", role.Name, dc)
+			}
+		}
+	}
+
+	role.Datacenters = datacenters
+	return role, nil
+}
+
+// intersectRole restricts the current role of the owner of a token to the
+// scope of the token, keeping only the datacenters allowed by both
+func intersectRole(role Role, readonly bool, datacenters []string) (Role, error) {
+	role.AccessToken = ""
+	role.Readonly = role.Readonly || readonly
+	if role.Readonly {
+		role.Admin = false
+	}
+
+	if len(datacenters) == 0 {
+		return role, nil
+	}
+	if len(role.Datacenters) == 0 {
+		role.Datacenters = datacenters
+		return role, nil
+	}
+
+	allowed := []string{}
+	for _, dc := range datacenters {
+		for _, d := range role.Datacenters {
+			if dc == d {
+				allowed = append(allowed, dc)
+				break
+			}
+		}
+	}
+	if len(allowed) == 0 {
+		return role, fmt.Errorf("the role '%s' does not have access to any of its datacenters", role.Name)
+	}
+
+	role.Datacenters = allowed
+	return role, nil
+}
+
+// RevokeAPITokens revokes every API token of the user, if the API tokens are
+// enabled
+func RevokeAPITokens(username string) error {
+	if apiTokens == nil {
+		return nil
+	}
+	return apiTokens.Revoke(username)
+}
@@ -0,0 +1,287 @@
+package authentication
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gorilla/context"
+	"github.com/sensu/uchiwa/uchiwa/audit"
+	"github.com/sensu/uchiwa/uchiwa/structs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPITokens(t *testing.T) {
+	dir, err := ioutil.TempDir("", "uchiwa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "tokens.json")
+
+	s, err := NewAPITokens(path)
+	assert.Nil(t, err)
+
+	role := &Role{Name: "operators", AccessToken: "static", Datacenters: []string{"us-east-1", "us-west-1"}}
+
+	// Invalid tokens
+	_, err = s.Create(APIToken{}, "alice", role)
+	assert.NotNil(t, err)
+	past := time.Now().Add(-time.Hour)
+	_, err = s.Create(APIToken{Name: "ci", Expires: &past}, "alice", role)
+	assert.NotNil(t, err)
+	_, err = s.Create(APIToken{Name: "ci", Datacenters: []string{"eu-west-1"}}, "alice", role)
+	assert.NotNil(t, err)
+
+	token, err := s.Create(APIToken{Name: "ci", Readonly: true, Datacenters: []string{"us-east-1"}}, "alice", role)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, token.Token)
+	assert.Empty(t, token.Hash)
+	assert.True(t, token.Role.Readonly)
+	assert.Empty(t, token.Role.AccessToken)
+	assert.Equal(t, []string{"us-east-1"}, token.Role.Datacenters)
+
+	// The secret is not stored
+	b, _ := ioutil.ReadFile(path)
+	assert.NotContains(t, string(b), token.Token[len(apiTokenPrefix)+len(token.ID)+1:])
+
+	verified, err := s.Verify(token.Token)
+	assert.Nil(t, err)
+	assert.Equal(t, "alice", verified.Username)
+	assert.NotNil(t, verified.LastUsed)
+
+	_, err = s.Verify(token.Token + "foo")
+	assert.NotNil(t, err)
+	_, err = s.Verify("foo")
+	assert.NotNil(t, err)
+
+	// The tokens are reloaded from the file
+	s, err = NewAPITokens(path)
+	assert.Nil(t, err)
+	_, err = s.Verify(token.Token)
+	assert.Nil(t, err)
+
+	assert.Equal(t, 1, len(s.List("alice", false)))
+	assert.Empty(t, s.List("alice", false)[0].Hash)
+	assert.Equal(t, 0, len(s.List("bob", false)))
+	assert.Equal(t, 1, len(s.List("bob", true)))
+
+	// Only the owner or an admin can revoke a token
+	assert.NotNil(t, s.Delete(token.ID, "bob", false))
+	assert.Nil(t, s.Delete(token.ID, "alice", false))
+	_, err = s.Verify(token.Token)
+	assert.NotNil(t, err)
+
+	// Expired token
+	future := time.Now().Add(time.Hour)
+	token, err = s.Create(APIToken{Name: "ci", Expires: &future}, "alice", role)
+	assert.Nil(t, err)
+	s.tokens[token.ID].Expires = &past
+	_, err = s.Verify(token.Token)
+	assert.NotNil(t, err)
+}
+
+func TestAPITokensUnderscore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "uchiwa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := NewAPITokens(filepath.Join(dir, "tokens.json"))
+	assert.Nil(t, err)
+
+	// Both the ID and the secret may contain underscores once encoded
+	s.tokens["ab_cd_ef_gh_"] = &APIToken{ID: "ab_cd_ef_gh_", Name: "ci", Username: "alice", Hash: hashSecret("_secret_")}
+
+	verified, err := s.Verify("uchiwa_ab_cd_ef_gh___secret_")
+	assert.Nil(t, err)
+	assert.Equal(t, "alice", verified.Username)
+
+	_, err = s.Verify("uchiwa_ab_cd_ef_gh_")
+	assert.NotNil(t, err)
+	_, err = s.Verify("uchiwa_ab_cd_ef_gh_x_secret_")
+	assert.NotNil(t, err)
+}
+
+func TestAPITokensOwnerRole(t *testing.T) {
+	s, err := NewAPITokens("")
+	assert.Nil(t, err)
+
+	role := Role{Name: "operators", Admin: true, Datacenters: []string{"us-east-1", "us-west-1"}}
+	owners := map[string]*User{"alice": &User{Username: "alice", Role: role}}
+	s.lookup = func(username string) (*User, error) {
+		if user, ok := owners[username]; ok {
+			return user, nil
+		}
+		return nil, errors.New("not found")
+	}
+
+	token, err := s.Create(APIToken{Name: "ci", Datacenters: []string{"us-east-1", "us-west-1"}}, "alice", &role)
+	assert.Nil(t, err)
+
+	verified, err := s.Verify(token.Token)
+	assert.Nil(t, err)
+	assert.True(t, verified.Role.Admin)
+
+	// The token follows the current role of its owner
+	owners["alice"].Role = Role{Name: "viewers", Readonly: true, Subscriptions: []string{"linux"}, Datacenters: []string{"us-west-1", "eu-west-1"}}
+	verified, err = s.Verify(token.Token)
+	assert.Nil(t, err)
+	assert.Equal(t, "viewers", verified.Role.Name)
+	assert.False(t, verified.Role.Admin)
+	assert.True(t, verified.Role.Readonly)
+	assert.Equal(t, []string{"linux"}, verified.Role.Subscriptions)
+	assert.Equal(t, []string{"us-west-1"}, verified.Role.Datacenters)
+
+	// No datacenter in common
+	owners["alice"].Role = Role{Name: "europe", Datacenters: []string{"eu-west-1"}}
+	_, err = s.Verify(token.Token)
+	assert.NotNil(t, err)
+
+	// No role assigned
+	owners["alice"].Role = Role{}
+	_, err = s.Verify(token.Token)
+	assert.NotNil(t, err)
+
+	// Unknown owner
+	delete(owners, "alice")
+	_, err = s.Verify(token.Token)
+	assert.NotNil(t, err)
+}
+
+func TestAPITokensRevoke(t *testing.T) {
+	dir, err := ioutil.TempDir("", "uchiwa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := NewAPITokens(filepath.Join(dir, "tokens.json"))
+	assert.Nil(t, err)
+
+	role := &Role{Name: "operators"}
+	for _, username := range []string{"alice", "alice", "bob"} {
+		_, err = s.Create(APIToken{Name: "ci"}, username, role)
+		assert.Nil(t, err)
+	}
+
+	assert.Nil(t, s.Revoke("alice"))
+	assert.Equal(t, 0, len(s.List("alice", false)))
+	assert.Equal(t, 1, len(s.List("bob", false)))
+
+	// The revocation is persisted
+	s, err = NewAPITokens(filepath.Join(dir, "tokens.json"))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(s.List("alice", true)))
+}
+
+func TestIntersectRole(t *testing.T) {
+	role, err := intersectRole(Role{Admin: true, AccessToken: "static"}, false, []string{"us-east-1"})
+	assert.Nil(t, err)
+	assert.True(t, role.Admin)
+	assert.Empty(t, role.AccessToken)
+	assert.Equal(t, []string{"us-east-1"}, role.Datacenters)
+
+	role, err = intersectRole(Role{Admin: true, Datacenters: []string{"us-east-1"}}, true, nil)
+	assert.Nil(t, err)
+	assert.False(t, role.Admin)
+	assert.Equal(t, []string{"us-east-1"}, role.Datacenters)
+
+	_, err = intersectRole(Role{Datacenters: []string{"us-east-1"}}, false, []string{"eu-west-1"})
+	assert.NotNil(t, err)
+}
+
+func TestScopeRole(t *testing.T) {
+	role, err := scopeRole(Role{Admin: true}, false, []string{"us-east-1"})
+	assert.Nil(t, err)
+	assert.True(t, role.Admin)
+	assert.Equal(t, []string{"us-east-1"}, role.Datacenters)
+
+	// A read-only token can't administrate
+	role, err = scopeRole(Role{Admin: true}, true, nil)
+	assert.Nil(t, err)
+	assert.False(t, role.Admin)
+	assert.True(t, role.Readonly)
+}
+
+func tokensRequest(method, url string, body interface{}, claims map[string]interface{}) *http.Request {
+	var b bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&b).Encode(body)
+	}
+
+	r, _ := http.NewRequest(method, url, &b)
+	if claims != nil {
+		token := jwt.New(jwt.GetSigningMethod("RS256"))
+		for k, v := range claims {
+			token.Claims[k] = v
+		}
+		context.Set(r, JWTToken, token)
+	}
+	return r
+}
+
+func TestAPITokensHandlers(t *testing.T) {
+	audit.Log = audit.LogMock
+	s, _ := NewAPITokens("")
+	a := New(structs.Auth{})
+	a.EnableAPITokens(s)
+	defer func() { apiTokens = nil }()
+
+	alice := map[string]interface{}{"Username": "alice", "Role": Role{Name: "operators"}}
+
+	w := httptest.NewRecorder()
+	tokensHandler(w, tokensRequest("POST", "/tokens", APIToken{Name: "ci", Readonly: true}, alice))
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var created APIToken
+	assert.Nil(t, json.NewDecoder(w.Body).Decode(&created))
+	assert.NotEmpty(t, created.Token)
+
+	// The API token authenticates requests with a scoped role
+	r, _ := http.NewRequest("GET", "/events", nil)
+	r.Header.Set("Authorization", "token "+created.Token)
+	token, err := verifyAccessToken(r)
+	assert.Nil(t, err)
+	assert.Equal(t, "alice", token.Claims["Username"])
+	role, _ := GetRoleFromToken(token)
+	assert.True(t, role.Readonly)
+
+	// API tokens can't mint other tokens
+	w = httptest.NewRecorder()
+	tokensHandler(w, tokensRequest("POST", "/tokens", APIToken{Name: "ci"}, token.Claims))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = httptest.NewRecorder()
+	tokensHandler(w, tokensRequest("GET", "/tokens", nil, alice))
+	assert.Equal(t, http.StatusOK, w.Code)
+	var list []APIToken
+	assert.Nil(t, json.NewDecoder(w.Body).Decode(&list))
+	assert.Equal(t, 1, len(list))
+	assert.Empty(t, list[0].Token)
+
+	w = httptest.NewRecorder()
+	tokenHandler(w, tokensRequest("DELETE", "/tokens/"+created.ID, nil, alice))
+	assert.Equal(t, http.StatusAccepted, w.Code)
+
+	w = httptest.NewRecorder()
+	tokenHandler(w, tokensRequest("DELETE", "/tokens/"+created.ID, nil, alice))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	_, err = verifyAccessToken(r)
+	assert.NotNil(t, err)
+
+	// Unauthenticated requests
+	w = httptest.NewRecorder()
+	tokensHandler(w, tokensRequest("GET", "/tokens", nil, nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
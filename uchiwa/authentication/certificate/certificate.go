@@ -0,0 +1,84 @@
+package certificate
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/sensu/uchiwa/uchiwa/authentication"
+	"github.com/sensu/uchiwa/uchiwa/config"
+)
+
+// Certificate represents the authentication driver based on the TLS client
+// certificates verified against the client CA bundle
+type Certificate struct {
+	Config config.Certificate
+}
+
+// New returns a client certificate authentication driver for the provided
+// configuration
+func New(c config.Certificate) *Certificate {
+	return &Certificate{Config: c}
+}
+
+// Register sets the client certificate driver as the authentication driver
+func (c *Certificate) Register(a *authentication.Config) {
+	a.Advanced(c.Login, "certificate")
+	a.Delegate(c.Authenticate)
+}
+
+// Login rejects any authentication based on a username and a password since
+// the users are authenticated with their certificate
+func (c *Certificate) Login(u, p string) (*authentication.User, error) {
+	return nil, errors.New("the certificate driver does not support password authentication")
+}
+
+// Authenticate returns the user identified by the verified client certificate
+// of the request, or nil if no certificate was provided
+func (c *Certificate) Authenticate(r *http.Request) (*authentication.User, error) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil, nil
+	}
+
+	cert := r.TLS.VerifiedChains[0][0]
+	identities := Identities(cert)
+
+	username := cert.Subject.CommonName
+	if username == "" && len(identities) != 0 {
+		username = identities[0]
+	}
+
+	role, err := authentication.GetRoleFromGroups(identities)
+	if err != nil {
+		return nil, fmt.Errorf("the certificate '%s' is not allowed: %s", username, err)
+	}
+
+	return &authentication.User{FullName: username, Role: *role, Username: username}, nil
+}
+
+// Identities returns the identities of the certificate, which can be used as
+// the members of a role: CN=<common name>, OU=<organizational unit>,
+// DNS:<name>, email:<address>, IP:<address> and URI:<uri>
+func Identities(cert *x509.Certificate) []string {
+	var identities []string
+	if cert.Subject.CommonName != "" {
+		identities = append(identities, "CN="+cert.Subject.CommonName)
+	}
+	for _, ou := range cert.Subject.OrganizationalUnit {
+		identities = append(identities, "OU="+ou)
+	}
+	for _, name := range cert.DNSNames {
+		identities = append(identities, "DNS:"+name)
+	}
+	for _, address := range cert.EmailAddresses {
+		identities = append(identities, "email:"+address)
+	}
+	for _, ip := range cert.IPAddresses {
+		identities = append(identities, "IP:"+ip.String())
+	}
+	for _, uri := range cert.URIs {
+		identities = append(identities, "URI:"+uri.String())
+	}
+	return identities
+}
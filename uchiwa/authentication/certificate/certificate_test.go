@@ -0,0 +1,146 @@
+package certificate
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/sensu/uchiwa/uchiwa/authentication"
+	"github.com/sensu/uchiwa/uchiwa/config"
+	"github.com/sensu/uchiwa/uchiwa/structs"
+	"github.com/stretchr/testify/assert"
+)
+
+// newCertificate returns a certificate signed by the parent, or self-signed
+// if no parent is provided
+func newCertificate(t *testing.T, template *x509.Certificate, parent *tls.Certificate) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+
+	signer, signerKey := template, interface{}(key)
+	if parent != nil {
+		signer, signerKey = parent.Leaf, parent.PrivateKey
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+func TestIdentities(t *testing.T) {
+	uri, _ := url.Parse("spiffe://example.com/automation")
+	cert := &x509.Certificate{
+		Subject:        pkix.Name{CommonName: "automation", OrganizationalUnit: []string{"ops", "sre"}},
+		DNSNames:       []string{"bot.example.com"},
+		EmailAddresses: []string{"bot@example.com"},
+		URIs:           []*url.URL{uri},
+	}
+
+	assert.Equal(t, []string{
+		"CN=automation",
+		"OU=ops",
+		"OU=sre",
+		"DNS:bot.example.com",
+		"email:bot@example.com",
+		"URI:spiffe://example.com/automation",
+	}, Identities(cert))
+}
+
+func TestAuthenticate(t *testing.T) {
+	authentication.SetRoles([]authentication.Role{
+		{Name: "automation", Members: []string{"OU=ops"}},
+		{Name: "monitoring", Members: []string{"DNS:monitoring.example.com"}, Readonly: true},
+	})
+	defer authentication.SetRoles(nil)
+
+	ca := newCertificate(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "ca"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil)
+	clientTemplate := func(cn string, ou []string, dns []string) *x509.Certificate {
+		return &x509.Certificate{
+			Subject:     pkix.Name{CommonName: cn, OrganizationalUnit: ou},
+			DNSNames:    dns,
+			ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+			KeyUsage:    x509.KeyUsageDigitalSignature,
+		}
+	}
+
+	c := New(config.Certificate{})
+	a := authentication.New(structs.Auth{})
+	c.Register(&a)
+	assert.Equal(t, "certificate", a.DriverName)
+
+	handler := a.Authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := authentication.GetJWTFromContext(r)
+		role, _ := authentication.GetRoleFromToken(token)
+		w.Write([]byte(token.Claims["Username"].(string) + " " + role.Name))
+	}))
+
+	server := httptest.NewUnstartedServer(handler)
+	server.TLS = &tls.Config{ClientCAs: x509.NewCertPool(), ClientAuth: tls.VerifyClientCertIfGiven}
+	server.TLS.ClientCAs.AddCert(ca.Leaf)
+	server.StartTLS()
+	defer server.Close()
+
+	get := func(cert *tls.Certificate) (int, string) {
+		transport := server.Client().Transport.(*http.Transport).Clone()
+		if cert != nil {
+			transport.TLSClientConfig.Certificates = []tls.Certificate{*cert}
+		}
+		client := &http.Client{Transport: transport}
+
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		body := make([]byte, 512)
+		n, _ := resp.Body.Read(body)
+		return resp.StatusCode, string(body[:n])
+	}
+
+	cert := newCertificate(t, clientTemplate("deploy", []string{"ops"}, nil), &ca)
+	code, body := get(&cert)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "deploy automation", body)
+
+	cert = newCertificate(t, clientTemplate("", nil, []string{"monitoring.example.com"}), &ca)
+	code, body = get(&cert)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "DNS:monitoring.example.com monitoring", body)
+
+	// No matching role
+	cert = newCertificate(t, clientTemplate("intruder", []string{"qa"}, nil), &ca)
+	code, _ = get(&cert)
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	// Without any certificate, a JWT or an access token is required
+	code, _ = get(nil)
+	assert.Equal(t, http.StatusUnauthorized, code)
+}
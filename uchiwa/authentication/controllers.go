@@ -2,8 +2,14 @@ package authentication
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
 
+	"github.com/dgrijalva/jwt-go"
 	"github.com/gorilla/context"
 	"github.com/sensu/uchiwa/uchiwa/audit"
 	"github.com/sensu/uchiwa/uchiwa/helpers"
@@ -14,7 +20,8 @@ import (
 // New function initalizes and returns a Config struct
 func New(auth structs.Auth) Config {
 	a := Config{
-		Auth: auth,
+		Auth:     auth,
+		throttle: newThrottle(auth),
 	}
 	return a
 }
@@ -55,11 +62,42 @@ func restrictedHandler(next http.Handler) http.Handler {
 	})
 }
 
+// delegatedHandler authenticates the request with the provided driver and
+// falls back to the restrictedHandler if the driver does not handle it
+func delegatedHandler(driver requestFn, next http.Handler) http.Handler {
+	restricted := restrictedHandler(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, err := driver(r)
+		if err != nil {
+			logger.Info(err)
+			http.Error(w, "Request unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		if user == nil {
+			restricted.ServeHTTP(w, r)
+			return
+		}
+
+		token := jwt.New(jwt.GetSigningMethod("RS256"))
+		token.Claims["Role"] = user.Role
+		token.Claims["Username"] = user.Username
+
+		setJWTInContext(r, token)
+		next.ServeHTTP(w, r)
+		context.Clear(r)
+		return
+	})
+}
+
 // Authenticate calls the proper handler based on whether authentication is enabled or not
 func (a *Config) Authenticate(next http.Handler) http.Handler {
 	if a.DriverName == "none" {
 		return publicHandler(next)
 	}
+	if a.RequestFn != nil {
+		return delegatedHandler(a.RequestFn, next)
+	}
 	return restrictedHandler(next)
 }
 
@@ -91,30 +129,44 @@ func (a *Config) Login() http.Handler {
 				return
 			}
 
-			user, err := a.login(u, p)
-			if err != nil {
-				logger.Info(err)
-
-				// Output to audit log
-				log := structs.AuditLog{Action: "loginfailure", Level: "default", Output: err.Error()}
-				log.RemoteAddr = helpers.GetIP(r)
-				audit.Log(log)
+			if a.throttle != nil {
+				ip := a.throttle.clientIP(r)
+				if wait := a.throttle.allow(u, ip); wait > 0 {
+					logger.Infof("Authentication throttled for the user '%s' from %s", u, ip)
+					w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+					http.Error(w, "Too many failed login attempts", http.StatusTooManyRequests)
+					return
+				}
+			}
 
+			user, err := a.authenticate(u, p)
+			if err != nil {
+				a.loginFailure(r, u, err)
 				http.Error(w, "", http.StatusUnauthorized)
 				return
 			}
 
-			// Obfuscate user attributes
-			user.Password = ""
+			// Users enrolled in two-factor authentication, or whose role requires
+			// it, must provide a code before receiving a token
+			if a.twoFactor != nil {
+				id, enrolment, err := a.twoFactor.challenge(user)
+				if err != nil {
+					logger.Warningf("Could not create the two-factor challenge for the user '%s': %s", u, err)
+					http.Error(w, "", http.StatusInternalServerError)
+					return
+				}
 
-			j, err := json.Marshal(user)
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
+				if id != "" {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusAccepted)
+					if err := json.NewEncoder(w).Encode(twoFactorChallenge{Challenge: id, Enrolment: enrolment}); err != nil {
+						logger.Warningf("Cannot encode response data: %v", err)
+					}
+					return
+				}
 			}
 
-			w.Header().Set("Content-Type", "application/json")
-			w.Write(j)
+			a.loginSuccess(w, r, user)
 			return
 		}
 
@@ -122,3 +174,377 @@ func (a *Config) Login() http.Handler {
 		return
 	})
 }
+
+// LoginTwoFactor completes the login of the users who must provide a second
+// factor, using the challenge returned by the /login endpoint
+func (a *Config) LoginTwoFactor() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.Error(w, "", http.StatusBadRequest)
+			return
+		}
+
+		var data struct {
+			Challenge string `json:"challenge"`
+			Code      string `json:"code"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+			http.Error(w, "Could not decode body", http.StatusBadRequest)
+			return
+		}
+
+		user, err := a.twoFactor.verify(data.Challenge, data.Code)
+		if err != nil {
+			username := ""
+			if user != nil {
+				username = user.Username
+			}
+			a.loginFailure(r, username, fmt.Errorf("Two-factor authentication failed: %s", err))
+			http.Error(w, "", http.StatusUnauthorized)
+			return
+		}
+
+		a.loginSuccess(w, r, user)
+	})
+}
+
+// loginFailure logs the failed login attempt and locks out the user or the
+// IP address after too many failures
+func (a *Config) loginFailure(r *http.Request, username string, err error) {
+	logger.Info(err)
+
+	// Output to audit log
+	log := structs.AuditLog{Action: "loginfailure", Level: "default", Output: err.Error(), User: username}
+	log.RemoteAddr = helpers.GetIP(r)
+	audit.Log(log)
+
+	if a.throttle == nil {
+		return
+	}
+
+	ip := a.throttle.clientIP(r)
+	if a.throttle.fail(username, ip) {
+		logger.Warningf("Too many failed login attempts for the user '%s' or from %s, locking out", username, ip)
+
+		log := structs.AuditLog{Action: "loginlockout", Level: "default", Output: "Too many failed login attempts", User: username}
+		log.RemoteAddr = helpers.GetIP(r)
+		audit.Log(log)
+	}
+}
+
+// loginSuccess issues a token for the authenticated user and writes the user
+// to the response
+func (a *Config) loginSuccess(w http.ResponseWriter, r *http.Request, user *User) {
+	if err := issueToken(user); err != nil {
+		logger.Warning(err)
+		http.Error(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	if a.throttle != nil {
+		a.throttle.succeed(user.Username)
+	}
+
+	// Output to audit log
+	log := structs.AuditLog{Action: "loginsuccess", Level: "verbose", User: user.Username}
+	log.RemoteAddr = helpers.GetIP(r)
+	audit.Log(log)
+
+	// Obfuscate user attributes
+	user.Password = ""
+
+	j, err := json.Marshal(user)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(j)
+}
+
+// Logout revokes the JWT provided in the request
+func (a *Config) Logout() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.Error(w, "", http.StatusBadRequest)
+			return
+		}
+
+		token, err := verifyJWT(r)
+		if err != nil {
+			http.Error(w, "Request unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		revokeToken(token)
+
+		// Output to audit log
+		username, _ := getUsernameFromToken(token)
+		log := structs.AuditLog{Action: "logout", Level: "verbose", User: username}
+		log.RemoteAddr = helpers.GetIP(r)
+		audit.Log(log)
+
+		w.WriteHeader(http.StatusNoContent)
+		return
+	})
+}
+
+// Refresh issues a new JWT in exchange of the valid JWT provided in the
+// request, which is revoked
+func (a *Config) Refresh() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.Error(w, "", http.StatusBadRequest)
+			return
+		}
+
+		token, err := verifyJWT(r)
+		if err != nil {
+			http.Error(w, "Request unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		tokenString, err := refreshToken(token)
+		if err != nil {
+			logger.Info(err)
+			http.Error(w, "Request unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]string{"token": tokenString}); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		return
+	})
+}
+
+// EnableAPITokens enables the API tokens backed by the provided store and
+// registers the /tokens endpoints. The role of the tokens follows the current
+// role of their owner if the driver provides a lookup of its users
+func (a *Config) EnableAPITokens(s *APITokens) {
+	s.lookup = a.UserFn
+	apiTokens = s
+	a.HandlePrivate("/tokens", http.HandlerFunc(tokensHandler))
+	a.HandlePrivate("/tokens/", http.HandlerFunc(tokenHandler))
+}
+
+// getCaller returns the username and the role of the user making the request.
+// API tokens can't be used to manage API tokens
+func getCaller(r *http.Request) (string, *Role, error) {
+	token := GetJWTFromContext(r)
+	if token == nil {
+		return "", nil, errors.New("authentication is required")
+	}
+	if _, ok := token.Claims["APIToken"]; ok {
+		return "", nil, errors.New("API tokens can't be used to manage API tokens")
+	}
+
+	username, err := getUsernameFromToken(token)
+	if err != nil {
+		return "", nil, err
+	}
+	role, err := GetRoleFromToken(token)
+	if err != nil {
+		return "", nil, err
+	}
+	return username, role, nil
+}
+
+// tokenHandler serves the /tokens/:id endpoint
+func tokenHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "DELETE" {
+		http.Error(w, "", http.StatusBadRequest)
+		return
+	}
+
+	username, role, err := getCaller(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Request forbidden: %s", err), http.StatusForbidden)
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, "/tokens/")
+	if err := apiTokens.Delete(id, username, role.Admin); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	// Output to audit log
+	log := structs.AuditLog{Action: "tokendelete", Level: "default", Output: id, URL: r.URL.String(), User: username}
+	log.RemoteAddr = helpers.GetIP(r)
+	audit.Log(log)
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// tokensHandler serves the /tokens endpoint
+func tokensHandler(w http.ResponseWriter, r *http.Request) {
+	username, role, err := getCaller(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Request forbidden: %s", err), http.StatusForbidden)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case "GET", "HEAD":
+		if err := json.NewEncoder(w).Encode(apiTokens.List(username, role.Admin)); err != nil {
+			http.Error(w, fmt.Sprintf("Cannot encode response data: %v", err), http.StatusInternalServerError)
+		}
+	case "POST":
+		var data APIToken
+		if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+			http.Error(w, "Could not decode body", http.StatusBadRequest)
+			return
+		}
+
+		token, err := apiTokens.Create(data, username, role)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Output to audit log
+		log := structs.AuditLog{Action: "tokencreate", Level: "default", Output: token.ID, URL: r.URL.String(), User: username}
+		log.RemoteAddr = helpers.GetIP(r)
+		audit.Log(log)
+
+		w.WriteHeader(http.StatusCreated)
+		if err := json.NewEncoder(w).Encode(token); err != nil {
+			http.Error(w, fmt.Sprintf("Cannot encode response data: %v", err), http.StatusInternalServerError)
+		}
+	default:
+		http.Error(w, "", http.StatusBadRequest)
+	}
+}
+
+// EnableTwoFactor enables the two-factor authentication of the local users,
+// backed by the provided store, and registers the /login/2fa and /twofactor
+// endpoints
+func (a *Config) EnableTwoFactor(s TwoFactorStore) {
+	a.twoFactor = newTwoFactor(s)
+	a.Handle("/login/2fa", a.LoginTwoFactor())
+	a.HandlePrivate("/twofactor", http.HandlerFunc(a.twoFactorHandler))
+	a.HandlePrivate("/twofactor/confirm", http.HandlerFunc(a.twoFactorConfirmHandler))
+}
+
+// decodeTwoFactorCode returns the code provided in the body of the request
+func decodeTwoFactorCode(r *http.Request) (string, error) {
+	var data struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		return "", err
+	}
+	return data.Code, nil
+}
+
+// twoFactorConfirmHandler serves the /twofactor/confirm endpoint
+func (a *Config) twoFactorConfirmHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "", http.StatusBadRequest)
+		return
+	}
+
+	username, _, err := getCaller(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Request forbidden: %s", err), http.StatusForbidden)
+		return
+	}
+
+	code, err := decodeTwoFactorCode(r)
+	if err != nil {
+		http.Error(w, "Could not decode body", http.StatusBadRequest)
+		return
+	}
+
+	a.twoFactor.mu.Lock()
+	err = a.twoFactor.confirm(username, code)
+	a.twoFactor.mu.Unlock()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Output to audit log
+	log := structs.AuditLog{Action: "twofactorenable", Level: "default", URL: r.URL.String(), User: username}
+	log.RemoteAddr = helpers.GetIP(r)
+	audit.Log(log)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// twoFactorHandler serves the /twofactor endpoint
+func (a *Config) twoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	username, role, err := getCaller(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Request forbidden: %s", err), http.StatusForbidden)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case "GET", "HEAD":
+		tf, err := a.twoFactor.store.GetTwoFactor(username)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		status := map[string]interface{}{"enabled": false, "recoverycodes": 0, "required": role.RequireTwoFactor}
+		if tf != nil && tf.Confirmed {
+			status["enabled"] = true
+			status["recoverycodes"] = len(tf.RecoveryCodes)
+		}
+
+		if err := json.NewEncoder(w).Encode(status); err != nil {
+			http.Error(w, fmt.Sprintf("Cannot encode response data: %v", err), http.StatusInternalServerError)
+		}
+	case "POST":
+		a.twoFactor.mu.Lock()
+		enrolment, err := a.twoFactor.enroll(username)
+		a.twoFactor.mu.Unlock()
+		if err == errTwoFactorEnabled {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		if err := json.NewEncoder(w).Encode(enrolment); err != nil {
+			http.Error(w, fmt.Sprintf("Cannot encode response data: %v", err), http.StatusInternalServerError)
+		}
+	case "DELETE":
+		if role.RequireTwoFactor {
+			http.Error(w, fmt.Sprintf("Request forbidden: the role '%s' requires two-factor authentication", role.Name), http.StatusForbidden)
+			return
+		}
+
+		code, err := decodeTwoFactorCode(r)
+		if err != nil {
+			http.Error(w, "Could not decode body", http.StatusBadRequest)
+			return
+		}
+
+		if err := a.twoFactor.disable(username, code); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Output to audit log
+		log := structs.AuditLog{Action: "twofactordisable", Level: "default", URL: r.URL.String(), User: username}
+		log.RemoteAddr = helpers.GetIP(r)
+		audit.Log(log)
+
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "", http.StatusBadRequest)
+	}
+}
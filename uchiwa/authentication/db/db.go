@@ -0,0 +1,146 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	// SQLite driver
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/sensu/uchiwa/uchiwa/authentication"
+	"github.com/sensu/uchiwa/uchiwa/config"
+	"github.com/sensu/uchiwa/uchiwa/logger"
+)
+
+// SupportedDriver is the only SQL driver supported by the SQL authentication driver
+const SupportedDriver = "sqlite3"
+
+var (
+	errConflict = errors.New("the resource already exists")
+	errNotFound = errors.New("the resource does not exist")
+)
+
+// Db represents the SQL authentication driver
+type Db struct {
+	Config config.Db
+	db     *sql.DB
+}
+
+// New opens the configured database, applies the pending migrations and
+// returns the SQL authentication driver
+func New(c config.Db) (*Db, error) {
+	if c.Driver != SupportedDriver {
+		return nil, fmt.Errorf("unsupported SQL driver '%s', only %s is supported", c.Driver, SupportedDriver)
+	}
+
+	db, err := sql.Open(c.Driver, c.Scheme)
+	if err != nil {
+		return nil, fmt.Errorf("could not open the database: %s", err)
+	}
+
+	// SQLite does not support concurrent writers
+	db.SetMaxOpenConns(1)
+
+	if err = db.Ping(); err != nil {
+		return nil, fmt.Errorf("could not connect to the database: %s", err)
+	}
+
+	d := &Db{Config: c, db: db}
+
+	if err = d.migrate(); err != nil {
+		return nil, err
+	}
+
+	if err = d.bootstrap(); err != nil {
+		return nil, err
+	}
+
+	if err = d.loadRoles(); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// Close closes the database
+func (d *Db) Close() error {
+	return d.db.Close()
+}
+
+// Register sets the SQL driver as the authentication driver, enables the
+// two-factor authentication of its users and registers the endpoints used to
+// manage the users and the roles
+func (d *Db) Register(a *authentication.Config) {
+	a.Advanced(d.Login, "sql")
+	a.Lookup(d.GetUserByName)
+	a.EnableTwoFactor(d)
+	a.HandlePrivate("/roles", adminHandler(http.HandlerFunc(d.rolesHandler)))
+	a.HandlePrivate("/roles/", adminHandler(http.HandlerFunc(d.roleHandler)))
+	a.HandlePrivate("/users", adminHandler(http.HandlerFunc(d.usersHandler)))
+	a.HandlePrivate("/users/", adminHandler(http.HandlerFunc(d.userHandler)))
+}
+
+// bootstrap creates an administrator role and user when the database is empty
+// so the dashboard can be accessed right after the first start
+func (d *Db) bootstrap() error {
+	roles, err := d.GetRoles()
+	if err != nil {
+		return err
+	}
+
+	if len(roles) == 0 {
+		logger.Info("Creating the initial 'admin' role")
+		if err = d.CreateRole(&authentication.Role{Name: "admin", Admin: true}); err != nil {
+			return err
+		}
+	}
+
+	users, err := d.GetUsers()
+	if err != nil {
+		return err
+	}
+
+	if len(users) == 0 {
+		password, err := authentication.RandomString(12)
+		if err != nil {
+			return err
+		}
+
+		path := d.Config.AdminPasswordFile
+		if err = writePassword(path, password); err != nil {
+			return err
+		}
+
+		user := &authentication.User{Username: "admin", FullName: "Administrator"}
+		if err = d.CreateUser(user, password, "admin"); err != nil {
+			os.Remove(path)
+			return err
+		}
+		logger.Warningf("Created the initial user 'admin' with the password written to %s, make sure to change it and to delete the file", path)
+	}
+
+	return nil
+}
+
+// writePassword writes the password of the initial admin user to a new file
+// only readable by its owner. An existing file is never overwritten since it
+// could be readable by others
+func writePassword(path, password string) error {
+	if path == "" {
+		return errors.New("the file receiving the password of the initial user 'admin' must be configured with adminpasswordfile")
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("could not create the file of the initial password: %s", err)
+	}
+
+	if _, err = fmt.Fprintln(f, password); err != nil {
+		f.Close()
+		os.Remove(path)
+		return fmt.Errorf("could not write the initial password: %s", err)
+	}
+	return f.Close()
+}
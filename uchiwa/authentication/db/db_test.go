@@ -0,0 +1,316 @@
+package db
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gorilla/context"
+	"github.com/sensu/uchiwa/uchiwa/audit"
+	"github.com/sensu/uchiwa/uchiwa/authentication"
+	"github.com/sensu/uchiwa/uchiwa/config"
+	"github.com/sensu/uchiwa/uchiwa/structs"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestDb(t *testing.T) *Db {
+	dir, err := ioutil.TempDir("", "uchiwa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	d, err := New(config.Db{AdminPasswordFile: filepath.Join(dir, "password"), Driver: "sqlite3", Scheme: ":memory:"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestNew(t *testing.T) {
+	// Unsupported driver
+	_, err := New(config.Db{Driver: "postgres", Scheme: "foo"})
+	assert.NotNil(t, err)
+
+	dir, err := ioutil.TempDir("", "uchiwa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "password")
+
+	// The password of the initial user must be written to a new file
+	_, err = New(config.Db{Driver: "sqlite3", Scheme: ":memory:"})
+	assert.NotNil(t, err)
+	ioutil.WriteFile(path, []byte("foo"), 0644)
+	_, err = New(config.Db{AdminPasswordFile: path, Driver: "sqlite3", Scheme: ":memory:"})
+	assert.NotNil(t, err)
+	os.Remove(path)
+
+	d, err := New(config.Db{AdminPasswordFile: path, Driver: "sqlite3", Scheme: ":memory:"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	info, err := os.Stat(path)
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	password, _ := ioutil.ReadFile(path)
+	_, err = d.Login("admin", strings.TrimSpace(string(password)))
+	assert.Nil(t, err)
+
+	// The initial role and user are created
+	roles, err := d.GetRoles()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(roles))
+	assert.True(t, roles[0].Admin)
+	assert.Equal(t, roles, authentication.GetRoles())
+
+	users, err := d.GetUsers()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(users))
+	assert.Equal(t, "admin", users[0].Username)
+
+	// Migrations are only applied once
+	assert.Nil(t, d.migrate())
+	var version int
+	d.db.QueryRow(`SELECT MAX(version) FROM schema_migrations`).Scan(&version)
+	assert.Equal(t, len(migrations), version)
+}
+
+func TestLogin(t *testing.T) {
+	d := newTestDb(t)
+	defer d.Close()
+
+	assert.Nil(t, d.CreateRole(&authentication.Role{Name: "viewer", Readonly: true, Datacenters: []string{"us-east-1"}}))
+
+	user := &authentication.User{Username: "alice", FullName: "Alice"}
+	assert.Nil(t, d.CreateUser(user, "secret", "viewer"))
+	assert.NotEqual(t, int64(0), user.ID)
+
+	// Duplicated user
+	assert.Equal(t, errConflict, d.CreateUser(&authentication.User{Username: "alice"}, "foo", ""))
+
+	// Successful login
+	u, err := d.Login("alice", "secret")
+	assert.Nil(t, err)
+	assert.Equal(t, "Alice", u.FullName)
+	assert.Equal(t, "viewer", u.Role.Name)
+	assert.Equal(t, []string{"us-east-1"}, u.Role.Datacenters)
+	assert.True(t, u.Readonly)
+
+	// The password is stored hashed with bcrypt
+	assert.True(t, strings.HasPrefix(u.PasswordHash, "$2a$"), u.PasswordHash)
+	assert.Empty(t, u.PasswordSalt)
+
+	// Wrong password
+	_, err = d.Login("alice", "wrong")
+	assert.NotNil(t, err)
+
+	// Unknown user
+	_, err = d.Login("bob", "secret")
+	assert.NotNil(t, err)
+
+	// Change the password and remove the role
+	user.FullName = "Alice Doe"
+	assert.Nil(t, d.UpdateUser(user, "newsecret", ""))
+	_, err = d.Login("alice", "secret")
+	assert.NotNil(t, err)
+	_, err = d.Login("alice", "newsecret")
+	assert.NotNil(t, err, "a user without any role can't log in")
+
+	// Assign back the role without changing the password
+	assert.Nil(t, d.UpdateUser(user, "", "viewer"))
+	u, err = d.Login("alice", "newsecret")
+	assert.Nil(t, err)
+	assert.Equal(t, "Alice Doe", u.FullName)
+
+	// A role assigned to a user can't be deleted
+	assert.NotNil(t, d.DeleteRole("viewer"))
+	assert.Nil(t, d.DeleteUser(user.ID))
+	assert.Nil(t, d.DeleteRole("viewer"))
+	assert.Equal(t, errNotFound, d.DeleteUser(user.ID))
+}
+
+func adminRequest(method, url string, body interface{}, admin bool) *http.Request {
+	var b bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&b).Encode(body)
+	}
+
+	r, _ := http.NewRequest(method, url, &b)
+	token := jwt.New(jwt.GetSigningMethod("RS256"))
+	token.Claims["Role"] = authentication.Role{Admin: admin}
+	token.Claims["Username"] = "admin"
+	context.Set(r, authentication.JWTToken, token)
+
+	return r
+}
+
+func TestHandlers(t *testing.T) {
+	d := newTestDb(t)
+	defer d.Close()
+
+	var logs []structs.AuditLog
+	audit.Log = func(log structs.AuditLog) error {
+		logs = append(logs, log)
+		return nil
+	}
+
+	users := adminHandler(http.HandlerFunc(d.usersHandler))
+	user := adminHandler(http.HandlerFunc(d.userHandler))
+	roles := adminHandler(http.HandlerFunc(d.rolesHandler))
+	role := adminHandler(http.HandlerFunc(d.roleHandler))
+
+	// Non-admin users are forbidden
+	w := httptest.NewRecorder()
+	users.ServeHTTP(w, adminRequest("GET", "/users", nil, false))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// Create a role
+	w = httptest.NewRecorder()
+	roles.ServeHTTP(w, adminRequest("POST", "/roles", authentication.Role{Name: "operator"}, true))
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	w = httptest.NewRecorder()
+	roles.ServeHTTP(w, adminRequest("POST", "/roles", authentication.Role{Name: "operator"}, true))
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = httptest.NewRecorder()
+	role.ServeHTTP(w, adminRequest("PUT", "/roles/operator", authentication.Role{Subscriptions: []string{"linux"}}, true))
+	assert.Equal(t, http.StatusOK, w.Code)
+	r, _ := d.GetRole("operator")
+	assert.Equal(t, []string{"linux"}, r.Subscriptions)
+
+	// Create a user
+	w = httptest.NewRecorder()
+	users.ServeHTTP(w, adminRequest("POST", "/users", userPayload{Username: "bob", Password: "secret", Role: "operator"}, true))
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var created authentication.User
+	assert.Nil(t, json.NewDecoder(w.Body).Decode(&created))
+	assert.Equal(t, "bob", created.Username)
+	assert.Equal(t, "operator", created.Role.Name)
+	assert.Empty(t, created.PasswordHash)
+
+	// Unknown role
+	w = httptest.NewRecorder()
+	users.ServeHTTP(w, adminRequest("POST", "/users", userPayload{Username: "carol", Password: "secret", Role: "foo"}, true))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// List the users
+	w = httptest.NewRecorder()
+	users.ServeHTTP(w, adminRequest("GET", "/users", nil, true))
+	assert.Equal(t, http.StatusOK, w.Code)
+	var list []authentication.User
+	assert.Nil(t, json.NewDecoder(w.Body).Decode(&list))
+	assert.Equal(t, 2, len(list))
+
+	// Update then delete the user
+	w = httptest.NewRecorder()
+	user.ServeHTTP(w, adminRequest("PUT", "/users/2", userPayload{FullName: "Bob", Role: "operator"}, true))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	user.ServeHTTP(w, adminRequest("DELETE", "/users/2", nil, true))
+	assert.Equal(t, http.StatusAccepted, w.Code)
+
+	w = httptest.NewRecorder()
+	user.ServeHTTP(w, adminRequest("GET", "/users/2", nil, true))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	role.ServeHTTP(w, adminRequest("DELETE", "/roles/operator", nil, true))
+	assert.Equal(t, http.StatusAccepted, w.Code)
+
+	// Only the successful changes are recorded in the audit log
+	var actions []string
+	for _, log := range logs {
+		assert.Equal(t, "admin", log.User)
+		actions = append(actions, log.Action+" "+log.Output)
+	}
+	assert.Equal(t, []string{"rolecreate operator", "roleupdate operator", "usercreate bob", "userupdate bob", "userdelete bob", "roledelete operator"}, actions)
+}
+
+func TestAPITokens(t *testing.T) {
+	d := newTestDb(t)
+	defer d.Close()
+
+	a := authentication.New(structs.Auth{})
+	d.Register(&a)
+	s, _ := authentication.NewAPITokens("")
+	a.EnableAPITokens(s)
+
+	assert.Nil(t, d.CreateRole(&authentication.Role{Name: "operator"}))
+	assert.Nil(t, d.CreateRole(&authentication.Role{Name: "viewer", Readonly: true}))
+	user := &authentication.User{Username: "alice"}
+	assert.Nil(t, d.CreateUser(user, "secret", "operator"))
+
+	operator, _ := d.GetRole("operator")
+	token, err := s.Create(authentication.APIToken{Name: "ci"}, "alice", operator)
+	assert.Nil(t, err)
+
+	// The token follows the current definition of the role
+	assert.Nil(t, d.UpdateRole("operator", &authentication.Role{Readonly: true}))
+	verified, err := s.Verify(token.Token)
+	assert.Nil(t, err)
+	assert.True(t, verified.Role.Readonly)
+
+	// The tokens are revoked once the role of the user changes
+	assert.Nil(t, d.UpdateUser(user, "", "operator"))
+	_, err = s.Verify(token.Token)
+	assert.Nil(t, err)
+	assert.Nil(t, d.UpdateUser(user, "", "viewer"))
+	_, err = s.Verify(token.Token)
+	assert.NotNil(t, err)
+
+	// The tokens are revoked once the user is deleted
+	viewer, _ := d.GetRole("viewer")
+	token, err = s.Create(authentication.APIToken{Name: "ci"}, "alice", viewer)
+	assert.Nil(t, err)
+	assert.Nil(t, d.DeleteUser(user.ID))
+	_, err = s.Verify(token.Token)
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, len(s.List("alice", false)))
+}
+
+func TestTwoFactor(t *testing.T) {
+	d := newTestDb(t)
+	defer d.Close()
+
+	user := &authentication.User{Username: "alice"}
+	assert.Nil(t, d.CreateUser(user, "secret", ""))
+
+	tf, err := d.GetTwoFactor("alice")
+	assert.Nil(t, err)
+	assert.Nil(t, tf)
+
+	expected := &authentication.TwoFactor{Confirmed: true, LastStep: 42, RecoveryCodes: []string{"foo", "bar"}, Secret: "baz"}
+	assert.Nil(t, d.SetTwoFactor("alice", expected))
+	tf, err = d.GetTwoFactor("alice")
+	assert.Nil(t, err)
+	assert.Equal(t, expected, tf)
+
+	// Unknown user
+	assert.Equal(t, errNotFound, d.SetTwoFactor("bob", expected))
+
+	// The enrolment is removed along with the user
+	assert.Nil(t, d.DeleteUser(user.ID))
+	assert.Nil(t, d.CreateUser(user, "secret", ""))
+	tf, err = d.GetTwoFactor("alice")
+	assert.Nil(t, err)
+	assert.Nil(t, tf)
+
+	assert.Nil(t, d.SetTwoFactor("alice", expected))
+	assert.Nil(t, d.SetTwoFactor("alice", nil))
+	tf, _ = d.GetTwoFactor("alice")
+	assert.Nil(t, tf)
+}
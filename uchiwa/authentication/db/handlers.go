@@ -0,0 +1,249 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/sensu/uchiwa/uchiwa/audit"
+	"github.com/sensu/uchiwa/uchiwa/authentication"
+	"github.com/sensu/uchiwa/uchiwa/helpers"
+	"github.com/sensu/uchiwa/uchiwa/structs"
+)
+
+// userPayload contains the attributes of a user that can be managed through
+// the /users endpoints
+type userPayload struct {
+	Email    string
+	FullName string
+	Password string
+	Role     string
+	Username string
+}
+
+// adminHandler only allows the requests made by users with the Admin role
+func adminHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := authentication.GetJWTFromContext(r)
+		if token == nil {
+			http.Error(w, "Request forbidden", http.StatusForbidden)
+			return
+		}
+
+		role, err := authentication.GetRoleFromToken(token)
+		if err != nil || !role.Admin {
+			http.Error(w, "Request forbidden", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// roleHandler serves the /roles/:name endpoint
+func (d *Db) roleHandler(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/roles/")
+	if name == "" || strings.Contains(name, "/") {
+		http.Error(w, "", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case "GET", "HEAD":
+		role, err := d.GetRole(name)
+		if err != nil {
+			httpError(w, err)
+			return
+		}
+		encode(w, role)
+	case "PUT":
+		var role authentication.Role
+		if err := json.NewDecoder(r.Body).Decode(&role); err != nil {
+			http.Error(w, "Could not decode body", http.StatusBadRequest)
+			return
+		}
+
+		if err := d.UpdateRole(name, &role); err != nil {
+			httpError(w, err)
+			return
+		}
+		auditLog(r, "roleupdate", name)
+		encode(w, role)
+	case "DELETE":
+		if err := d.DeleteRole(name); err != nil {
+			httpError(w, err)
+			return
+		}
+		auditLog(r, "roledelete", name)
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		http.Error(w, "", http.StatusBadRequest)
+	}
+}
+
+// rolesHandler serves the /roles endpoint
+func (d *Db) rolesHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET", "HEAD":
+		roles, err := d.GetRoles()
+		if err != nil {
+			httpError(w, err)
+			return
+		}
+		encode(w, roles)
+	case "POST":
+		var role authentication.Role
+		if err := json.NewDecoder(r.Body).Decode(&role); err != nil {
+			http.Error(w, "Could not decode body", http.StatusBadRequest)
+			return
+		}
+
+		if err := d.CreateRole(&role); err != nil {
+			httpError(w, err)
+			return
+		}
+		auditLog(r, "rolecreate", role.Name)
+		w.WriteHeader(http.StatusCreated)
+		encode(w, role)
+	default:
+		http.Error(w, "", http.StatusBadRequest)
+	}
+}
+
+// userHandler serves the /users/:id endpoint
+func (d *Db) userHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/users/"), 10, 64)
+	if err != nil {
+		http.Error(w, "", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case "GET", "HEAD":
+		user, err := d.GetUser(id)
+		if err != nil {
+			httpError(w, err)
+			return
+		}
+		encode(w, obfuscate(*user))
+	case "PUT":
+		var data userPayload
+		if err = json.NewDecoder(r.Body).Decode(&data); err != nil {
+			http.Error(w, "Could not decode body", http.StatusBadRequest)
+			return
+		}
+
+		user := &authentication.User{ID: id, Email: data.Email, FullName: data.FullName}
+		if err = d.UpdateUser(user, data.Password, data.Role); err != nil {
+			httpError(w, err)
+			return
+		}
+
+		user, err = d.GetUser(id)
+		if err != nil {
+			httpError(w, err)
+			return
+		}
+		auditLog(r, "userupdate", user.Username)
+		encode(w, obfuscate(*user))
+	case "DELETE":
+		user, err := d.GetUser(id)
+		if err != nil {
+			httpError(w, err)
+			return
+		}
+
+		if err = d.DeleteUser(id); err != nil {
+			httpError(w, err)
+			return
+		}
+		auditLog(r, "userdelete", user.Username)
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		http.Error(w, "", http.StatusBadRequest)
+	}
+}
+
+// usersHandler serves the /users endpoint
+func (d *Db) usersHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET", "HEAD":
+		users, err := d.GetUsers()
+		if err != nil {
+			httpError(w, err)
+			return
+		}
+
+		for i := range users {
+			users[i] = obfuscate(users[i])
+		}
+		encode(w, users)
+	case "POST":
+		var data userPayload
+		if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+			http.Error(w, "Could not decode body", http.StatusBadRequest)
+			return
+		}
+
+		user := &authentication.User{Email: data.Email, FullName: data.FullName, Username: data.Username}
+		if err := d.CreateUser(user, data.Password, data.Role); err != nil {
+			httpError(w, err)
+			return
+		}
+
+		auditLog(r, "usercreate", user.Username)
+
+		user, err := d.GetUser(user.ID)
+		if err != nil {
+			httpError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		encode(w, obfuscate(*user))
+	default:
+		http.Error(w, "", http.StatusBadRequest)
+	}
+}
+
+// auditLog records in the audit log the action performed by the authenticated
+// user on the provided user or role
+func auditLog(r *http.Request, action, target string) {
+	log := structs.AuditLog{Action: action, Level: audit.LevelDefault, Output: target, URL: r.URL.String()}
+	log.RemoteAddr = helpers.GetIP(r)
+
+	if token := authentication.GetJWTFromContext(r); token != nil {
+		log.User, _ = token.Claims["Username"].(string)
+	}
+
+	audit.Log(log)
+}
+
+func encode(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, fmt.Sprintf("Cannot encode response data: %v", err), http.StatusInternalServerError)
+	}
+}
+
+// httpError writes the HTTP status code corresponding to the provided error
+func httpError(w http.ResponseWriter, err error) {
+	switch err {
+	case errNotFound:
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errConflict:
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// obfuscate removes the sensitive attributes of a user
+func obfuscate(user authentication.User) authentication.User {
+	user.Password = ""
+	user.PasswordHash = ""
+	user.PasswordSalt = ""
+	user.Role.AccessToken = ""
+	return user
+}
@@ -0,0 +1,65 @@
+package db
+
+import "fmt"
+
+// migrations contains the schema migrations, in order. Never modify an
+// existing migration, append a new one instead
+var migrations = []string{
+	`CREATE TABLE roles (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL UNIQUE,
+		definition TEXT NOT NULL
+	)`,
+	`CREATE TABLE users (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		username TEXT NOT NULL UNIQUE,
+		fullname TEXT NOT NULL DEFAULT '',
+		email TEXT NOT NULL DEFAULT '',
+		password_hash TEXT NOT NULL,
+		password_salt TEXT NOT NULL,
+		role_id INTEGER
+	)`,
+	`CREATE TABLE two_factor (
+		user_id INTEGER PRIMARY KEY,
+		confirmed INTEGER NOT NULL DEFAULT 0,
+		last_step INTEGER NOT NULL DEFAULT 0,
+		recovery_codes TEXT NOT NULL DEFAULT '[]',
+		secret TEXT NOT NULL
+	)`,
+}
+
+// migrate applies the migrations that were not applied yet
+func (d *Db) migrate() error {
+	_, err := d.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY)`)
+	if err != nil {
+		return fmt.Errorf("could not create the schema_migrations table: %s", err)
+	}
+
+	var version int
+	if err = d.db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version); err != nil {
+		return fmt.Errorf("could not retrieve the schema version: %s", err)
+	}
+
+	for i := version; i < len(migrations); i++ {
+		tx, err := d.db.Begin()
+		if err != nil {
+			return err
+		}
+
+		if _, err = tx.Exec(migrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("could not apply the migration %d: %s", i+1, err)
+		}
+
+		if _, err = tx.Exec(`INSERT INTO schema_migrations (version) VALUES (?)`, i+1); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("could not record the migration %d: %s", i+1, err)
+		}
+
+		if err = tx.Commit(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
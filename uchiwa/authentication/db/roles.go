@@ -0,0 +1,136 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/sensu/uchiwa/uchiwa/authentication"
+)
+
+// CreateRole adds the provided role to the database
+func (d *Db) CreateRole(role *authentication.Role) error {
+	if role.Name == "" {
+		return errors.New("the role name can't be empty")
+	}
+
+	if _, err := d.GetRole(role.Name); err == nil {
+		return errConflict
+	}
+
+	definition, err := json.Marshal(role)
+	if err != nil {
+		return err
+	}
+
+	if _, err = d.db.Exec(`INSERT INTO roles (name, definition) VALUES (?, ?)`, role.Name, string(definition)); err != nil {
+		return fmt.Errorf("could not create the role '%s': %s", role.Name, err)
+	}
+
+	return d.loadRoles()
+}
+
+// DeleteRole removes the role with the provided name. A role assigned to at
+// least one user can't be deleted
+func (d *Db) DeleteRole(name string) error {
+	var id, users int64
+	err := d.db.QueryRow(`SELECT id FROM roles WHERE name = ?`, name).Scan(&id)
+	if err == sql.ErrNoRows {
+		return errNotFound
+	} else if err != nil {
+		return err
+	}
+
+	if err = d.db.QueryRow(`SELECT COUNT(*) FROM users WHERE role_id = ?`, id).Scan(&users); err != nil {
+		return err
+	}
+	if users != 0 {
+		return fmt.Errorf("the role '%s' is assigned to %d user(s)", name, users)
+	}
+
+	if _, err = d.db.Exec(`DELETE FROM roles WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("could not delete the role '%s': %s", name, err)
+	}
+
+	return d.loadRoles()
+}
+
+// GetRole returns the role with the provided name
+func (d *Db) GetRole(name string) (*authentication.Role, error) {
+	var definition string
+	err := d.db.QueryRow(`SELECT definition FROM roles WHERE name = ?`, name).Scan(&definition)
+	if err == sql.ErrNoRows {
+		return nil, errNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return decodeRole(definition)
+}
+
+// GetRoles returns every role
+func (d *Db) GetRoles() ([]authentication.Role, error) {
+	rows, err := d.db.Query(`SELECT definition FROM roles ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve the roles: %s", err)
+	}
+	defer rows.Close()
+
+	roles := []authentication.Role{}
+	for rows.Next() {
+		var definition string
+		if err = rows.Scan(&definition); err != nil {
+			return nil, err
+		}
+
+		role, err := decodeRole(definition)
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, *role)
+	}
+
+	return roles, rows.Err()
+}
+
+// UpdateRole replaces the definition of the role with the provided name
+func (d *Db) UpdateRole(name string, role *authentication.Role) error {
+	if _, err := d.GetRole(name); err != nil {
+		return err
+	}
+
+	// The name identifies the role and can't be changed
+	role.Name = name
+
+	definition, err := json.Marshal(role)
+	if err != nil {
+		return err
+	}
+
+	if _, err = d.db.Exec(`UPDATE roles SET definition = ? WHERE name = ?`, string(definition), name); err != nil {
+		return fmt.Errorf("could not update the role '%s': %s", name, err)
+	}
+
+	return d.loadRoles()
+}
+
+// loadRoles exposes the roles of the database to the authentication package,
+// which relies on them for the access tokens
+func (d *Db) loadRoles() error {
+	roles, err := d.GetRoles()
+	if err != nil {
+		return err
+	}
+
+	authentication.SetRoles(roles)
+	return nil
+}
+
+func decodeRole(definition string) (*authentication.Role, error) {
+	var role authentication.Role
+	if err := json.Unmarshal([]byte(definition), &role); err != nil {
+		return nil, fmt.Errorf("could not decode the role: %s", err)
+	}
+	return &role, nil
+}
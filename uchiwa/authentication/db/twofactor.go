@@ -0,0 +1,60 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/sensu/uchiwa/uchiwa/authentication"
+)
+
+// GetTwoFactor returns the TOTP enrolment of the user, or nil if the user is
+// not enrolled
+func (d *Db) GetTwoFactor(username string) (*authentication.TwoFactor, error) {
+	var tf authentication.TwoFactor
+	var codes string
+
+	err := d.db.QueryRow(`SELECT t.confirmed, t.last_step, t.recovery_codes, t.secret
+		FROM two_factor t JOIN users u ON u.id = t.user_id WHERE u.username = ?`, username).
+		Scan(&tf.Confirmed, &tf.LastStep, &codes, &tf.Secret)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("could not retrieve the two-factor enrolment of the user '%s': %s", username, err)
+	}
+
+	if err = json.Unmarshal([]byte(codes), &tf.RecoveryCodes); err != nil {
+		return nil, fmt.Errorf("could not decode the recovery codes of the user '%s': %s", username, err)
+	}
+
+	return &tf, nil
+}
+
+// SetTwoFactor replaces the TOTP enrolment of the user, or removes it if nil
+func (d *Db) SetTwoFactor(username string, tf *authentication.TwoFactor) error {
+	var id int64
+	err := d.db.QueryRow(`SELECT id FROM users WHERE username = ?`, username).Scan(&id)
+	if err == sql.ErrNoRows {
+		return errNotFound
+	} else if err != nil {
+		return err
+	}
+
+	if tf == nil {
+		_, err = d.db.Exec(`DELETE FROM two_factor WHERE user_id = ?`, id)
+		return err
+	}
+
+	codes, err := json.Marshal(tf.RecoveryCodes)
+	if err != nil {
+		return err
+	}
+
+	_, err = d.db.Exec(`INSERT OR REPLACE INTO two_factor (user_id, confirmed, last_step, recovery_codes, secret) VALUES (?, ?, ?, ?, ?)`,
+		id, tf.Confirmed, tf.LastStep, string(codes), tf.Secret)
+	if err != nil {
+		return fmt.Errorf("could not store the two-factor enrolment of the user '%s': %s", username, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,219 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/sensu/uchiwa/uchiwa/authentication"
+)
+
+const selectUsers = `SELECT u.id, u.username, u.fullname, u.email, u.password_hash, u.password_salt, COALESCE(r.definition, '')
+	FROM users u LEFT JOIN roles r ON r.id = u.role_id`
+
+// scanner is implemented by both *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(...interface{}) error
+}
+
+// Login represents the SQL authentication driver
+func (d *Db) Login(u, p string) (*authentication.User, error) {
+	user, err := scanUser(d.db.QueryRow(selectUsers+` WHERE u.username = ?`, u))
+	if err != nil || authentication.VerifyPassword(user.PasswordHash, p) != nil {
+		return nil, fmt.Errorf("invalid user '%s' or invalid password", u)
+	}
+
+	if user.Role.Name == "" {
+		return nil, fmt.Errorf("no role is assigned to the user '%s'", u)
+	}
+
+	return user, nil
+}
+
+// CreateUser adds the provided user to the database with the provided
+// password and role
+func (d *Db) CreateUser(user *authentication.User, password, role string) error {
+	if user.Username == "" || password == "" {
+		return errors.New("the username and the password can't be empty")
+	}
+
+	roleID, err := d.roleID(role)
+	if err != nil {
+		return err
+	}
+
+	var count int
+	if err = d.db.QueryRow(`SELECT COUNT(*) FROM users WHERE username = ?`, user.Username).Scan(&count); err != nil {
+		return err
+	}
+	if count != 0 {
+		return errConflict
+	}
+
+	hash, err := authentication.HashPassword(password, authentication.Bcrypt)
+	if err != nil {
+		return err
+	}
+
+	// The salt is part of the bcrypt hash, the password_salt column is kept
+	// empty
+	res, err := d.db.Exec(`INSERT INTO users (username, fullname, email, password_hash, password_salt, role_id) VALUES (?, ?, ?, ?, '', ?)`,
+		user.Username, user.FullName, user.Email, hash, roleID)
+	if err != nil {
+		return fmt.Errorf("could not create the user '%s': %s", user.Username, err)
+	}
+
+	user.ID, err = res.LastInsertId()
+	return err
+}
+
+// DeleteUser removes the user with the provided ID
+func (d *Db) DeleteUser(id int64) error {
+	user, err := d.GetUser(id)
+	if err != nil {
+		return err
+	}
+
+	res, err := d.db.Exec(`DELETE FROM users WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("could not delete the user %d: %s", id, err)
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errNotFound
+	}
+
+	if _, err = d.db.Exec(`DELETE FROM two_factor WHERE user_id = ?`, id); err != nil {
+		return fmt.Errorf("could not delete the two-factor enrolment of the user %d: %s", id, err)
+	}
+
+	if err = authentication.RevokeAPITokens(user.Username); err != nil {
+		return fmt.Errorf("could not revoke the API tokens of the user %d: %s", id, err)
+	}
+
+	return nil
+}
+
+// GetUser returns the user with the provided ID
+func (d *Db) GetUser(id int64) (*authentication.User, error) {
+	user, err := scanUser(d.db.QueryRow(selectUsers+` WHERE u.id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, errNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// GetUserByName returns the user with the provided username
+func (d *Db) GetUserByName(username string) (*authentication.User, error) {
+	user, err := scanUser(d.db.QueryRow(selectUsers+` WHERE u.username = ?`, username))
+	if err == sql.ErrNoRows {
+		return nil, errNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// GetUsers returns every user
+func (d *Db) GetUsers() ([]authentication.User, error) {
+	rows, err := d.db.Query(selectUsers + ` ORDER BY u.username`)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve the users: %s", err)
+	}
+	defer rows.Close()
+
+	users := []authentication.User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *user)
+	}
+
+	return users, rows.Err()
+}
+
+// UpdateUser updates the attributes and the role of the provided user. The
+// password is only updated when not empty. The API tokens of the user are
+// revoked when its role changes
+func (d *Db) UpdateUser(user *authentication.User, password, role string) error {
+	current, err := d.GetUser(user.ID)
+	if err != nil {
+		return err
+	}
+
+	roleID, err := d.roleID(role)
+	if err != nil {
+		return err
+	}
+
+	_, err = d.db.Exec(`UPDATE users SET fullname = ?, email = ?, role_id = ? WHERE id = ?`,
+		user.FullName, user.Email, roleID, user.ID)
+	if err != nil {
+		return fmt.Errorf("could not update the user %d: %s", user.ID, err)
+	}
+
+	if current.Role.Name != role {
+		if err = authentication.RevokeAPITokens(current.Username); err != nil {
+			return fmt.Errorf("could not revoke the API tokens of the user %d: %s", user.ID, err)
+		}
+	}
+
+	if password == "" {
+		return nil
+	}
+
+	hash, err := authentication.HashPassword(password, authentication.Bcrypt)
+	if err != nil {
+		return err
+	}
+
+	_, err = d.db.Exec(`UPDATE users SET password_hash = ?, password_salt = '' WHERE id = ?`, hash, user.ID)
+	if err != nil {
+		return fmt.Errorf("could not update the password of the user %d: %s", user.ID, err)
+	}
+
+	return nil
+}
+
+// roleID returns the ID of the role with the provided name, or nil if no role
+// name was provided
+func (d *Db) roleID(name string) (interface{}, error) {
+	if name == "" {
+		return nil, nil
+	}
+
+	var id int64
+	err := d.db.QueryRow(`SELECT id FROM roles WHERE name = ?`, name).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("the role '%s' does not exist", name)
+	}
+
+	return id, err
+}
+
+func scanUser(row scanner) (*authentication.User, error) {
+	var user authentication.User
+	var definition string
+
+	err := row.Scan(&user.ID, &user.Username, &user.FullName, &user.Email, &user.PasswordHash, &user.PasswordSalt, &definition)
+	if err != nil {
+		return nil, err
+	}
+
+	if definition != "" {
+		role, err := decodeRole(definition)
+		if err != nil {
+			return nil, err
+		}
+		user.Role = *role
+		user.Readonly = role.Readonly
+	}
+
+	return &user, nil
+}
@@ -1,17 +1,8 @@
 package authentication
 
 import (
-	"errors"
 	"fmt"
 	"strings"
-
-	"github.com/palourde/crypt"
-
-	// Supported schemas for hashed passwords
-	_ "github.com/palourde/crypt/apr1_crypt"
-	_ "github.com/palourde/crypt/md5_crypt"
-	_ "github.com/palourde/crypt/sha256_crypt"
-	_ "github.com/palourde/crypt/sha512_crypt"
 )
 
 // Advanced function allows a third party Identification driver
@@ -22,6 +13,20 @@ func (a *Config) Advanced(driver loginFn, driverName string) {
 	initToken(a.Auth)
 }
 
+// Delegate function allows a third party driver to authenticate every
+// request by itself, e.g. from the headers set by a reverse proxy. The driver
+// returns a nil user if the request must be authenticated with a JWT or an
+// access token instead
+func (a *Config) Delegate(driver requestFn) {
+	a.RequestFn = driver
+}
+
+// Lookup function allows a driver to provide the current attributes of its
+// users, e.g. to resolve the current role of the owner of an API token
+func (a *Config) Lookup(driver userFn) {
+	a.UserFn = driver
+}
+
 // None function sets the Config struct in order to disable authentication
 func (a *Config) None() {
 	a.DriverFn = none
@@ -32,6 +37,7 @@ func (a *Config) None() {
 func (a *Config) Simple(u []User) {
 	a.DriverFn = simple
 	a.DriverName = "simple"
+	a.UserFn = lookupSimple
 
 	users = u
 
@@ -50,15 +56,16 @@ func simple(u, p string) (*User, error) {
 			continue
 		}
 
+		// Prefer the hashed password so plaintext passwords never need to be stored
+		if user.PasswordHash != "" {
+			return &user, VerifyPassword(user.PasswordHash, p)
+		}
+
 		if strings.HasPrefix(user.Password, "{crypt}") {
 			password := user.Password
 			password = strings.Replace(password, "{crypt}", "", 1)
-			crypter, err := crypt.NewFromHash(password)
-			if err != nil {
-				return &user, errors.New("Invalid hashed password")
-			}
 
-			return &user, crypter.Verify(password, []byte(p))
+			return &user, VerifyPassword(password, p)
 		}
 
 		if p == user.Password {
@@ -67,3 +74,13 @@ func simple(u, p string) (*User, error) {
 	}
 	return &User{}, fmt.Errorf("invalid user '%s' or invalid password", u)
 }
+
+// lookupSimple returns the user of the simple driver with the provided username
+func lookupSimple(u string) (*User, error) {
+	for _, user := range users {
+		if u == user.Username {
+			return &user, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid user '%s'", u)
+}
@@ -57,3 +57,36 @@ func TestAuthSimplePlain(t *testing.T) {
 	assert.NotNil(t, err)
 
 }
+
+func TestAuthSimplePasswordHash(t *testing.T) {
+	bcryptHash, _ := HashPassword("testbcrypt", Bcrypt)
+	argon2Hash, _ := HashPassword("testargon2", Argon2id)
+	users = []User{
+		{Username: "bcrypt", PasswordHash: bcryptHash},
+		{Username: "argon2", PasswordHash: argon2Hash},
+		{Username: "crypt", Password: "{crypt}" + bcryptHash},
+		{Username: "sha512", PasswordHash: "$6$KYg/Ceo.HQW6R7D0$4d3vWTQRhoyC27nNusOo/fnYh6wEmOm1YoQsTz4K9mYlmoUGL.LWO2ez1g5QqZFamWgo.VSeOEHLwe0EeKAC3/"},
+	}
+
+	user, err := simple("bcrypt", "testbcrypt")
+	assert.Nil(t, err)
+	assert.Equal(t, "bcrypt", user.Username)
+
+	_, err = simple("bcrypt", "testwrong")
+	assert.NotNil(t, err)
+
+	user, err = simple("argon2", "testargon2")
+	assert.Nil(t, err)
+	assert.Equal(t, "argon2", user.Username)
+
+	_, err = simple("argon2", "testwrong")
+	assert.NotNil(t, err)
+
+	// bcrypt hash with the legacy {crypt} prefix
+	_, err = simple("crypt", "testbcrypt")
+	assert.Nil(t, err)
+
+	// Legacy crypt hash as the password hash
+	_, err = simple("sha512", "testsha512")
+	assert.Nil(t, err)
+}
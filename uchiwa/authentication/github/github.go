@@ -0,0 +1,254 @@
+package github
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sensu/uchiwa/uchiwa/authentication"
+	"github.com/sensu/uchiwa/uchiwa/config"
+)
+
+const (
+	// CallbackPath is the path of the OAuth callback handler
+	CallbackPath = "/login/github/callback"
+	// LoginPath is the path of the handler that initiates the OAuth flow
+	LoginPath = "/login/github"
+
+	publicServer = "https://github.com"
+	publicAPI    = "https://api.github.com"
+)
+
+// Github represents the GitHub OAuth authentication driver
+type Github struct {
+	Config config.Github
+	Client *http.Client
+}
+
+type githubOrg struct {
+	Login string `json:"login"`
+}
+
+type githubTeam struct {
+	Slug         string    `json:"slug"`
+	Organization githubOrg `json:"organization"`
+}
+
+type githubUser struct {
+	Email string `json:"email"`
+	Login string `json:"login"`
+	Name  string `json:"name"`
+}
+
+// New returns a GitHub authentication driver for the provided configuration
+func New(c config.Github) *Github {
+	c.Server = strings.TrimSuffix(c.Server, "/")
+	return &Github{Config: c, Client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Register sets the GitHub driver as the authentication driver and registers
+// the OAuth handlers
+func (g *Github) Register(a *authentication.Config) {
+	a.Advanced(g.Login, "github")
+	a.Handle(LoginPath, g.LoginHandler())
+	a.Handle(CallbackPath, g.CallbackHandler())
+}
+
+// Login rejects any authentication based on a username and a password since
+// the users must authenticate against GitHub
+func (g *Github) Login(u, p string) (*authentication.User, error) {
+	return nil, errors.New("the GitHub driver does not support password authentication")
+}
+
+// LoginHandler redirects the user to the GitHub authorization page
+func (g *Github) LoginHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		state, err := authentication.SetOAuthState(w, r)
+		if err != nil {
+			authentication.LoginFailure(w, r, err)
+			return
+		}
+
+		params := url.Values{}
+		params.Set("client_id", g.Config.ClientID)
+		params.Set("redirect_uri", authentication.CallbackURL(r, CallbackPath))
+		params.Set("scope", "read:org")
+		params.Set("state", state)
+
+		http.Redirect(w, r, fmt.Sprintf("%s/login/oauth/authorize?%s", g.Config.Server, params.Encode()), http.StatusFound)
+	})
+}
+
+// CallbackHandler exchanges the authorization code for an access token,
+// determines the role of the user from its organizations and teams and then
+// issues a JWT
+func (g *Github) CallbackHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := authentication.VerifyOAuthState(w, r); err != nil {
+			authentication.LoginFailure(w, r, fmt.Errorf("Authentication failed: %s", err))
+			return
+		}
+
+		user, err := g.authenticate(r, r.URL.Query().Get("code"))
+		if err != nil {
+			authentication.LoginFailure(w, r, fmt.Errorf("Authentication failed: %s", err))
+			return
+		}
+
+		authentication.LoginRedirect(w, r, user)
+	})
+}
+
+// apiURL returns the URL of the GitHub API, which differs between github.com
+// and GitHub Enterprise
+func (g *Github) apiURL() string {
+	if g.Config.Server == publicServer {
+		return publicAPI
+	}
+	return fmt.Sprintf("%s/api/v3", g.Config.Server)
+}
+
+// authenticate exchanges the provided code and returns the corresponding user
+func (g *Github) authenticate(r *http.Request, code string) (*authentication.User, error) {
+	if code == "" {
+		return nil, errors.New("missing OAuth code")
+	}
+
+	params := url.Values{}
+	params.Set("client_id", g.Config.ClientID)
+	params.Set("client_secret", g.Config.ClientSecret)
+	params.Set("code", code)
+	params.Set("redirect_uri", authentication.CallbackURL(r, CallbackPath))
+
+	m, err := authentication.ExchangeOAuthCode(g.Client, fmt.Sprintf("%s/login/oauth/access_token", g.Config.Server), params)
+	if err != nil {
+		return nil, err
+	}
+
+	token, ok := m["access_token"].(string)
+	if !ok || token == "" {
+		return nil, errors.New("GitHub did not return any access token")
+	}
+
+	var gu githubUser
+	if _, err = g.get(token, "user", &gu); err != nil {
+		return nil, err
+	}
+
+	groups, err := g.groups(token)
+	if err != nil {
+		return nil, err
+	}
+
+	role, err := authentication.GetRoleFromGroups(groups)
+	if err != nil {
+		return nil, fmt.Errorf("could not find a role for the user '%s': %s", gu.Login, err)
+	}
+
+	user := &authentication.User{
+		Email:    gu.Email,
+		FullName: gu.Name,
+		Readonly: role.Readonly,
+		Role:     *role,
+		Username: gu.Login,
+	}
+	if user.FullName == "" {
+		user.FullName = gu.Login
+	}
+
+	return user, nil
+}
+
+// groups returns the organizations and the teams, e.g. org/team, of the user.
+// Role members can either be an organization or a team
+func (g *Github) groups(token string) ([]string, error) {
+	var groups []string
+
+	endpoint := "user/orgs?per_page=100"
+	for endpoint != "" {
+		var orgs []githubOrg
+		res, err := g.get(token, endpoint, &orgs)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, org := range orgs {
+			groups = append(groups, org.Login)
+		}
+
+		if endpoint, err = g.nextPage(res); err != nil {
+			return nil, err
+		}
+	}
+
+	endpoint = "user/teams?per_page=100"
+	for endpoint != "" {
+		var teams []githubTeam
+		res, err := g.get(token, endpoint, &teams)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, team := range teams {
+			groups = append(groups, fmt.Sprintf("%s/%s", team.Organization.Login, team.Slug))
+		}
+
+		if endpoint, err = g.nextPage(res); err != nil {
+			return nil, err
+		}
+	}
+
+	return groups, nil
+}
+
+// nextPage returns the endpoint of the next page, according to the Link header
+// of the response, or an empty string on the last page
+func (g *Github) nextPage(res *http.Response) (string, error) {
+	for _, link := range strings.Split(res.Header.Get("Link"), ",") {
+		parts := strings.Split(link, ";")
+		for _, param := range parts[1:] {
+			if strings.TrimSpace(param) != `rel="next"` {
+				continue
+			}
+
+			// The access token is only sent to the GitHub API
+			next := strings.Trim(strings.TrimSpace(parts[0]), "<>")
+			if !strings.HasPrefix(next, g.apiURL()+"/") {
+				return "", fmt.Errorf("unexpected next page '%s'", next)
+			}
+			return strings.TrimPrefix(next, g.apiURL()+"/"), nil
+		}
+	}
+	return "", nil
+}
+
+// get performs an authenticated GET request against the GitHub API and decodes
+// the response into v
+func (g *Github) get(token, endpoint string, v interface{}) (*http.Response, error) {
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/%s", g.apiURL(), endpoint), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	req.Header.Set("Authorization", fmt.Sprintf("token %s", token))
+
+	res, err := g.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("GET %s returned: %s", endpoint, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 400 {
+		return nil, fmt.Errorf("GET %s returned: %s", endpoint, res.Status)
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(v); err != nil {
+		return nil, fmt.Errorf("could not decode the response of %s: %s", endpoint, err)
+	}
+
+	return res, nil
+}
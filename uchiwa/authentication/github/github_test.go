@@ -0,0 +1,187 @@
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/sensu/uchiwa/uchiwa/audit"
+	"github.com/sensu/uchiwa/uchiwa/authentication"
+	"github.com/sensu/uchiwa/uchiwa/config"
+	"github.com/sensu/uchiwa/uchiwa/structs"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeGithub returns an in-process OAuth provider mimicking GitHub Enterprise
+func fakeGithub(t *testing.T, teams string) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login/oauth/authorize", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "foo", r.URL.Query().Get("client_id"))
+		redirect := r.URL.Query().Get("redirect_uri") + "?code=secretcode&state=" + r.URL.Query().Get("state")
+		http.Redirect(w, r, redirect, http.StatusFound)
+	})
+	mux.HandleFunc("/login/oauth/access_token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("code") != "secretcode" || r.Form.Get("client_secret") != "bar" {
+			w.Write([]byte(`{"error":"bad_verification_code"}`))
+			return
+		}
+		w.Write([]byte(`{"access_token":"accesstoken","token_type":"bearer"}`))
+	})
+	api := func(body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "token accesstoken" {
+				http.Error(w, "", http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(body))
+		}
+	}
+	mux.HandleFunc("/api/v3/user", api(`{"login":"alice","name":"Alice","email":"alice@example.com"}`))
+	mux.HandleFunc("/api/v3/user/orgs", api(`[{"login":"acme"}]`))
+	mux.HandleFunc("/api/v3/user/teams", api(teams))
+
+	return httptest.NewServer(mux)
+}
+
+// newUchiwa returns an in-process Uchiwa serving the GitHub handlers
+func newUchiwa(provider string) *httptest.Server {
+	auth := authentication.New(structs.Auth{})
+	New(config.Github{ClientID: "foo", ClientSecret: "bar", Server: provider + "/"}).Register(&auth)
+
+	mux := http.NewServeMux()
+	for pattern, handler := range auth.Handlers {
+		mux.Handle(pattern, handler)
+	}
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {})
+
+	return httptest.NewServer(mux)
+}
+
+func login(t *testing.T, server string) *authentication.User {
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{Jar: jar}
+
+	res, err := client.Get(server + LoginPath)
+	assert.Nil(t, err)
+	res.Body.Close()
+
+	u, _ := url.Parse(server)
+	for _, cookie := range jar.Cookies(u) {
+		if cookie.Name == "uchiwa_auth" {
+			value, _ := url.QueryUnescape(cookie.Value)
+			var user authentication.User
+			assert.Nil(t, json.NewDecoder(strings.NewReader(value)).Decode(&user))
+			return &user
+		}
+	}
+	return nil
+}
+
+func TestOAuthFlow(t *testing.T) {
+	audit.Log = audit.LogMock
+	authentication.SetRoles([]authentication.Role{
+		{Name: "operators", Members: []string{"acme/ops"}},
+		{Name: "developers", Members: []string{"acme"}, Readonly: true},
+	})
+	defer authentication.SetRoles(nil)
+
+	// Member of a team
+	provider := fakeGithub(t, `[{"slug":"ops","organization":{"login":"acme"}}]`)
+	defer provider.Close()
+	server := newUchiwa(provider.URL)
+	defer server.Close()
+
+	user := login(t, server.URL)
+	if assert.NotNil(t, user) {
+		assert.Equal(t, "alice", user.Username)
+		assert.Equal(t, "Alice", user.FullName)
+		assert.Equal(t, "operators", user.Role.Name)
+		assert.NotEmpty(t, user.Token)
+	}
+
+	// Member of the organization only
+	provider2 := fakeGithub(t, `[]`)
+	defer provider2.Close()
+	server2 := newUchiwa(provider2.URL)
+	defer server2.Close()
+
+	user = login(t, server2.URL)
+	if assert.NotNil(t, user) {
+		assert.Equal(t, "developers", user.Role.Name)
+		assert.True(t, user.Readonly)
+	}
+
+	// Not a member of any role
+	authentication.SetRoles(authentication.GetRoles()[:1])
+	user = login(t, server2.URL)
+	assert.Nil(t, user)
+}
+
+func TestCallbackHandlerState(t *testing.T) {
+	audit.Log = audit.LogMock
+	g := New(config.Github{Server: "https://github.com"})
+
+	// Missing state cookie
+	r, _ := http.NewRequest("GET", CallbackPath+"?code=foo&state=bar", nil)
+	w := httptest.NewRecorder()
+	g.CallbackHandler().ServeHTTP(w, r)
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "/#/login", w.Header().Get("Location"))
+}
+
+func TestAPIURL(t *testing.T) {
+	g := New(config.Github{Server: "https://github.com"})
+	assert.Equal(t, "https://api.github.com", g.apiURL())
+
+	g = New(config.Github{Server: "https://github.example.com/"})
+	assert.Equal(t, "https://github.example.com/api/v3", g.apiURL())
+}
+
+func TestGroupsPagination(t *testing.T) {
+	var server *httptest.Server
+	page := func(body, next string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "100", r.URL.Query().Get("per_page"))
+			if next != "" {
+				w.Header().Set("Link", fmt.Sprintf(`<%s/api/v3/%s>; rel="next", <%s/api/v3/%s>; rel="last"`, server.URL, next, server.URL, next))
+			}
+			w.Write([]byte(body))
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/user/orgs", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "2" {
+			page(`[{"login":"initech"}]`, "")(w, r)
+			return
+		}
+		page(`[{"login":"acme"}]`, "user/orgs?per_page=100&page=2")(w, r)
+	})
+	mux.HandleFunc("/api/v3/user/teams", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("page") {
+		case "3":
+			page(`[{"slug":"ops","organization":{"login":"initech"}}]`, "")(w, r)
+		case "2":
+			page(`[]`, "user/teams?per_page=100&page=3")(w, r)
+		default:
+			page(`[{"slug":"dev","organization":{"login":"acme"}}]`, "user/teams?per_page=100&page=2")(w, r)
+		}
+	})
+	server = httptest.NewServer(mux)
+	defer server.Close()
+
+	g := New(config.Github{Server: server.URL})
+	groups, err := g.groups("accesstoken")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"acme", "initech", "acme/dev", "initech/ops"}, groups)
+
+	// The access token is not sent outside of the GitHub API
+	_, err = g.nextPage(&http.Response{Header: http.Header{"Link": {`<https://example.com/user/orgs?page=2>; rel="next"`}}})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,206 @@
+package gitlab
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sensu/uchiwa/uchiwa/authentication"
+	"github.com/sensu/uchiwa/uchiwa/config"
+)
+
+const (
+	// CallbackPath is the path of the OAuth callback handler
+	CallbackPath = "/login/gitlab/callback"
+	// LoginPath is the path of the handler that initiates the OAuth flow
+	LoginPath = "/login/gitlab"
+)
+
+// Gitlab represents the GitLab OAuth authentication driver
+type Gitlab struct {
+	Config config.Gitlab
+	Client *http.Client
+}
+
+type gitlabGroup struct {
+	FullPath string `json:"full_path"`
+}
+
+type gitlabUser struct {
+	Email    string `json:"email"`
+	Name     string `json:"name"`
+	Username string `json:"username"`
+}
+
+// New returns a GitLab authentication driver for the provided configuration
+func New(c config.Gitlab) *Gitlab {
+	c.Server = strings.TrimSuffix(c.Server, "/")
+	return &Gitlab{Config: c, Client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Register sets the GitLab driver as the authentication driver and registers
+// the OAuth handlers
+func (g *Gitlab) Register(a *authentication.Config) {
+	a.Advanced(g.Login, "gitlab")
+	a.Handle(LoginPath, g.LoginHandler())
+	a.Handle(CallbackPath, g.CallbackHandler())
+}
+
+// Login rejects any authentication based on a username and a password since
+// the users must authenticate against GitLab
+func (g *Gitlab) Login(u, p string) (*authentication.User, error) {
+	return nil, errors.New("the GitLab driver does not support password authentication")
+}
+
+// LoginHandler redirects the user to the GitLab authorization page
+func (g *Gitlab) LoginHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		state, err := authentication.SetOAuthState(w, r)
+		if err != nil {
+			authentication.LoginFailure(w, r, err)
+			return
+		}
+
+		params := url.Values{}
+		params.Set("client_id", g.Config.ApplicationID)
+		params.Set("redirect_uri", g.redirectURL(r))
+		params.Set("response_type", "code")
+		params.Set("scope", "read_api")
+		params.Set("state", state)
+
+		http.Redirect(w, r, fmt.Sprintf("%s/oauth/authorize?%s", g.Config.Server, params.Encode()), http.StatusFound)
+	})
+}
+
+// CallbackHandler exchanges the authorization code for an access token,
+// determines the role of the user from its groups and then issues a JWT
+func (g *Gitlab) CallbackHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := authentication.VerifyOAuthState(w, r); err != nil {
+			authentication.LoginFailure(w, r, fmt.Errorf("Authentication failed: %s", err))
+			return
+		}
+
+		user, err := g.authenticate(r, r.URL.Query().Get("code"))
+		if err != nil {
+			authentication.LoginFailure(w, r, fmt.Errorf("Authentication failed: %s", err))
+			return
+		}
+
+		authentication.LoginRedirect(w, r, user)
+	})
+}
+
+// authenticate exchanges the provided code and returns the corresponding user
+func (g *Gitlab) authenticate(r *http.Request, code string) (*authentication.User, error) {
+	if code == "" {
+		return nil, errors.New("missing OAuth code")
+	}
+
+	params := url.Values{}
+	params.Set("client_id", g.Config.ApplicationID)
+	params.Set("client_secret", g.Config.Secret)
+	params.Set("code", code)
+	params.Set("grant_type", "authorization_code")
+	params.Set("redirect_uri", g.redirectURL(r))
+
+	m, err := authentication.ExchangeOAuthCode(g.Client, fmt.Sprintf("%s/oauth/token", g.Config.Server), params)
+	if err != nil {
+		return nil, err
+	}
+
+	token, ok := m["access_token"].(string)
+	if !ok || token == "" {
+		return nil, errors.New("GitLab did not return any access token")
+	}
+
+	var gu gitlabUser
+	if _, err = g.get(token, "user", &gu); err != nil {
+		return nil, err
+	}
+
+	groups, err := g.groups(token)
+	if err != nil {
+		return nil, err
+	}
+
+	role, err := authentication.GetRoleFromGroups(groups)
+	if err != nil {
+		return nil, fmt.Errorf("could not find a role for the user '%s': %s", gu.Username, err)
+	}
+
+	user := &authentication.User{
+		Email:    gu.Email,
+		FullName: gu.Name,
+		Readonly: role.Readonly,
+		Role:     *role,
+		Username: gu.Username,
+	}
+	if user.FullName == "" {
+		user.FullName = gu.Username
+	}
+
+	return user, nil
+}
+
+// groups returns the full path of every group the user is a member of,
+// e.g. acme/ops for a subgroup
+func (g *Gitlab) groups(token string) ([]string, error) {
+	var groups []string
+
+	page := "1"
+	for page != "" {
+		var list []gitlabGroup
+		res, err := g.get(token, fmt.Sprintf("groups?min_access_level=10&per_page=100&page=%s", page), &list)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, group := range list {
+			groups = append(groups, group.FullPath)
+		}
+
+		page = res.Header.Get("X-Next-Page")
+	}
+
+	return groups, nil
+}
+
+// get performs an authenticated GET request against the GitLab API and decodes
+// the response into v
+func (g *Gitlab) get(token, endpoint string, v interface{}) (*http.Response, error) {
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/api/v4/%s", g.Config.Server, endpoint), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+
+	res, err := g.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("GET %s returned: %s", endpoint, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 400 {
+		return nil, fmt.Errorf("GET %s returned: %s", endpoint, res.Status)
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(v); err != nil {
+		return nil, fmt.Errorf("could not decode the response of %s: %s", endpoint, err)
+	}
+
+	return res, nil
+}
+
+// redirectURL returns the configured redirect URL, or the URL of the callback
+// handler as reached by the user
+func (g *Gitlab) redirectURL(r *http.Request) string {
+	if g.Config.RedirectURL != "" {
+		return g.Config.RedirectURL
+	}
+	return authentication.CallbackURL(r, CallbackPath)
+}
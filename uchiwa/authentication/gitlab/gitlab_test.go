@@ -0,0 +1,129 @@
+package gitlab
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/sensu/uchiwa/uchiwa/audit"
+	"github.com/sensu/uchiwa/uchiwa/authentication"
+	"github.com/sensu/uchiwa/uchiwa/config"
+	"github.com/sensu/uchiwa/uchiwa/structs"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeGitlab returns an in-process OAuth provider mimicking a GitLab server
+func fakeGitlab(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/oauth/authorize", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "foo", r.URL.Query().Get("client_id"))
+		assert.Equal(t, "code", r.URL.Query().Get("response_type"))
+		redirect := r.URL.Query().Get("redirect_uri") + "?code=secretcode&state=" + r.URL.Query().Get("state")
+		http.Redirect(w, r, redirect, http.StatusFound)
+	})
+	mux.HandleFunc("/oauth/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("code") != "secretcode" || r.Form.Get("client_secret") != "bar" ||
+			r.Form.Get("grant_type") != "authorization_code" {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"access_token":"accesstoken","token_type":"bearer"}`))
+	})
+	mux.HandleFunc("/api/v4/user", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer accesstoken", r.Header.Get("Authorization"))
+		w.Write([]byte(`{"username":"alice","name":"Alice","email":"alice@example.com"}`))
+	})
+	mux.HandleFunc("/api/v4/groups", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer accesstoken", r.Header.Get("Authorization"))
+		if r.URL.Query().Get("page") == "1" {
+			w.Header().Set("X-Next-Page", "2")
+			w.Write([]byte(`[{"full_path":"acme"}]`))
+			return
+		}
+		w.Write([]byte(`[{"full_path":"acme/ops"}]`))
+	})
+
+	return httptest.NewServer(mux)
+}
+
+func TestOAuthFlow(t *testing.T) {
+	audit.Log = audit.LogMock
+	authentication.SetRoles([]authentication.Role{
+		{Name: "operators", Members: []string{"acme/ops"}},
+	})
+	defer authentication.SetRoles(nil)
+
+	provider := fakeGitlab(t)
+	defer provider.Close()
+
+	auth := authentication.New(structs.Auth{})
+	New(config.Gitlab{ApplicationID: "foo", Secret: "bar", Server: provider.URL}).Register(&auth)
+
+	mux := http.NewServeMux()
+	for pattern, handler := range auth.Handlers {
+		mux.Handle(pattern, handler)
+	}
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{Jar: jar}
+	res, err := client.Get(server.URL + LoginPath)
+	assert.Nil(t, err)
+	res.Body.Close()
+
+	var user *authentication.User
+	u, _ := url.Parse(server.URL)
+	for _, cookie := range jar.Cookies(u) {
+		if cookie.Name == "uchiwa_auth" {
+			value, _ := url.QueryUnescape(cookie.Value)
+			assert.Nil(t, json.NewDecoder(strings.NewReader(value)).Decode(&user))
+		}
+	}
+
+	if assert.NotNil(t, user) {
+		assert.Equal(t, "alice", user.Username)
+		assert.Equal(t, "Alice", user.FullName)
+		assert.Equal(t, "operators", user.Role.Name)
+		assert.NotEmpty(t, user.Token)
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	provider := fakeGitlab(t)
+	defer provider.Close()
+
+	authentication.SetRoles([]authentication.Role{{Name: "admins", Members: []string{"admins"}}})
+	defer authentication.SetRoles(nil)
+
+	g := New(config.Gitlab{ApplicationID: "foo", Secret: "bar", Server: provider.URL + "/"})
+	r, _ := http.NewRequest("GET", CallbackPath, nil)
+
+	// Missing code
+	_, err := g.authenticate(r, "")
+	assert.NotNil(t, err)
+
+	// Invalid code
+	_, err = g.authenticate(r, "foo")
+	assert.NotNil(t, err)
+
+	// Not a member of any role
+	_, err = g.authenticate(r, "secretcode")
+	assert.NotNil(t, err)
+}
+
+func TestRedirectURL(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://uchiwa.example.com/login/gitlab", nil)
+
+	g := New(config.Gitlab{})
+	assert.Equal(t, "http://uchiwa.example.com/login/gitlab/callback", g.redirectURL(r))
+
+	g = New(config.Gitlab{RedirectURL: "https://uchiwa.example.com/login/gitlab/callback"})
+	assert.Equal(t, "https://uchiwa.example.com/login/gitlab/callback", g.redirectURL(r))
+}
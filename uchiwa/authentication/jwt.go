@@ -6,6 +6,7 @@ import (
 	"errors"
 	"io/ioutil"
 	"net/http"
+	"time"
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/gorilla/context"
@@ -17,9 +18,18 @@ import (
 // JWTToken constant
 const JWTToken = "jwtToken"
 
+// Default lifetimes of the tokens. A token can be refreshed until its
+// refresh lifetime, counted from the original login, is exceeded
+const (
+	defaultRefreshLifetime = 7 * 24 * time.Hour
+	defaultTokenLifetime   = 24 * time.Hour
+)
+
 var (
-	privateKey *rsa.PrivateKey
-	publicKey  *rsa.PublicKey
+	privateKey      *rsa.PrivateKey
+	publicKey       *rsa.PublicKey
+	refreshLifetime = defaultRefreshLifetime
+	tokenLifetime   = defaultTokenLifetime
 )
 
 // GetJWTFromContext retrieves the JWT Token from the request
@@ -50,14 +60,65 @@ func GetToken(role *Role, username string) (string, error) {
 		return "", errors.New("Could not generate a token for the user. Invalid username")
 	}
 
-	t := jwt.New(jwt.GetSigningMethod("RS256"))
-	t.Claims["Role"] = role
-	t.Claims["Username"] = username
+	return signToken(role, username, time.Now())
+}
+
+// getUsernameFromToken returns the username contained in the token
+func getUsernameFromToken(token *jwt.Token) (string, error) {
+	username, ok := token.Claims["Username"].(string)
+	if !ok || username == "" {
+		return "", errors.New("Could not retrieve the username from the JWT")
+	}
+	return username, nil
+}
+
+// refreshToken issues a new token for the user of the provided token, as
+// long as the refresh lifetime is not exceeded, and revokes the old token
+func refreshToken(token *jwt.Token) (string, error) {
+	username, err := getUsernameFromToken(token)
+	if err != nil {
+		return "", err
+	}
+
+	origIat, ok := token.Claims["orig_iat"].(float64)
+	if !ok {
+		return "", errors.New("Could not retrieve the original issue time from the JWT")
+	}
+	login := time.Unix(int64(origIat), 0)
+	if time.Since(login) > refreshLifetime {
+		return "", errors.New("The refresh lifetime of the token is exceeded")
+	}
+
+	tokenString, err := signToken(token.Claims["Role"], username, login)
+	if err != nil {
+		return "", err
+	}
 
+	revokeToken(token)
+	return tokenString, nil
+}
+
+// signToken returns a signed token expiring after the token lifetime. The
+// login time is kept across refreshes in the orig_iat claim
+func signToken(role interface{}, username string, login time.Time) (string, error) {
 	if privateKey == nil {
 		return "", errors.New("Could not generate a token for the user. Invalid private key")
 	}
 
+	id, err := RandomString(16)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	t := jwt.New(jwt.GetSigningMethod("RS256"))
+	t.Claims["Role"] = role
+	t.Claims["Username"] = username
+	t.Claims["exp"] = now.Add(tokenLifetime).Unix()
+	t.Claims["iat"] = now.Unix()
+	t.Claims["jti"] = id
+	t.Claims["orig_iat"] = login.Unix()
+
 	tokenString, err := t.SignedString(privateKey)
 	return tokenString, err
 }
@@ -88,6 +149,15 @@ func generateToken() (*rsa.PrivateKey, *rsa.PublicKey) {
 // filesystem with the loadToken() function or by generating temporarily
 // ones with the generateToken() function
 func initToken(a structs.Auth) {
+	refreshLifetime = defaultRefreshLifetime
+	if a.RefreshLifetime > 0 {
+		refreshLifetime = time.Duration(a.RefreshLifetime) * time.Minute
+	}
+	tokenLifetime = defaultTokenLifetime
+	if a.TokenLifetime > 0 {
+		tokenLifetime = time.Duration(a.TokenLifetime) * time.Minute
+	}
+
 	var err error
 	privateKey, publicKey, err = loadToken(a)
 	if err != nil {
@@ -154,5 +224,17 @@ func verifyJWT(r *http.Request) (*jwt.Token, error) {
 		return nil, errors.New("")
 	}
 
+	// The expiration is validated while parsing the token, but only if the
+	// claim is present
+	if _, ok := token.Claims["exp"]; !ok {
+		logger.Debug("The JWT does not expire")
+		return nil, errors.New("")
+	}
+
+	if isTokenRevoked(token) {
+		logger.Debug("The JWT has been revoked")
+		return nil, errors.New("")
+	}
+
 	return token, nil
 }
@@ -0,0 +1,97 @@
+package authentication
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sensu/uchiwa/uchiwa/audit"
+	"github.com/sensu/uchiwa/uchiwa/structs"
+	"github.com/stretchr/testify/assert"
+)
+
+func tokenRequest(method, url, token string) *http.Request {
+	r, _ := http.NewRequest(method, url, nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	return r
+}
+
+func TestTokenLifecycle(t *testing.T) {
+	audit.Log = audit.LogMock
+	a := New(structs.Auth{TokenLifetime: 5, RefreshLifetime: 60})
+	a.Advanced(none, "test")
+	defer initToken(structs.Auth{})
+
+	assert.Equal(t, 5*time.Minute, tokenLifetime)
+	assert.Equal(t, time.Hour, refreshLifetime)
+
+	token, err := GetToken(&Role{Name: "admin"}, "alice")
+	assert.Nil(t, err)
+
+	jwt, err := verifyJWT(tokenRequest("GET", "/", token))
+	assert.Nil(t, err)
+	assert.Equal(t, "alice", jwt.Claims["Username"])
+	assert.NotEmpty(t, jwt.Claims["jti"])
+	assert.Equal(t, jwt.Claims["iat"], jwt.Claims["orig_iat"])
+
+	// Refresh the token
+	w := httptest.NewRecorder()
+	a.Refresh().ServeHTTP(w, tokenRequest("POST", "/login/refresh", token))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var body map[string]string
+	assert.Nil(t, json.NewDecoder(w.Body).Decode(&body))
+	refreshed := body["token"]
+	assert.NotEqual(t, token, refreshed)
+
+	// The previous token is revoked while the new one keeps the role & login time
+	_, err = verifyJWT(tokenRequest("GET", "/", token))
+	assert.NotNil(t, err)
+
+	jwtRefreshed, err := verifyJWT(tokenRequest("GET", "/", refreshed))
+	assert.Nil(t, err)
+	assert.Equal(t, jwt.Claims["orig_iat"], jwtRefreshed.Claims["orig_iat"])
+	role, err := GetRoleFromToken(jwtRefreshed)
+	assert.Nil(t, err)
+	assert.Equal(t, "admin", role.Name)
+
+	// A revoked token can't be refreshed
+	w = httptest.NewRecorder()
+	a.Refresh().ServeHTTP(w, tokenRequest("POST", "/login/refresh", token))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// Logout
+	w = httptest.NewRecorder()
+	a.Logout().ServeHTTP(w, tokenRequest("POST", "/logout", refreshed))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	_, err = verifyJWT(tokenRequest("GET", "/", refreshed))
+	assert.NotNil(t, err)
+
+	w = httptest.NewRecorder()
+	a.Logout().ServeHTTP(w, tokenRequest("GET", "/logout", refreshed))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestTokenExpiration(t *testing.T) {
+	initToken(structs.Auth{})
+	defer initToken(structs.Auth{})
+
+	// Expired token
+	tokenLifetime = -time.Minute
+	token, err := GetToken(&Role{}, "alice")
+	assert.Nil(t, err)
+	_, err = verifyJWT(tokenRequest("GET", "/", token))
+	assert.NotNil(t, err)
+
+	// Token beyond its refresh lifetime
+	tokenLifetime = time.Minute
+	refreshLifetime = -time.Minute
+	token, err = GetToken(&Role{}, "alice")
+	assert.Nil(t, err)
+	jwt, err := verifyJWT(tokenRequest("GET", "/", token))
+	assert.Nil(t, err)
+	_, err = refreshToken(jwt)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,213 @@
+package ldap
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"strings"
+
+	goldap "github.com/go-ldap/ldap/v3"
+	"github.com/sensu/uchiwa/uchiwa/authentication"
+	"github.com/sensu/uchiwa/uchiwa/config"
+	"github.com/sensu/uchiwa/uchiwa/logger"
+)
+
+// Supported values for the Security attribute of the LDAP configuration
+const (
+	SecurityNone     = "none"
+	SecurityStartTLS = "starttls"
+	SecurityTLS      = "tls"
+)
+
+// Dialect used to resolve nested groups with Active Directory
+const dialectActiveDirectory = "ad"
+
+// conn represents the subset of an LDAP connection used by the driver
+type conn interface {
+	Bind(string, string) error
+	Close()
+	Search(*goldap.SearchRequest) (*goldap.SearchResult, error)
+	StartTLS(*tls.Config) error
+}
+
+// Ldap represents the LDAP authentication driver
+type Ldap struct {
+	Config config.Ldap
+	dial   func(string, *tls.Config) (conn, error)
+}
+
+// New returns an LDAP authentication driver for the provided configuration
+func New(c config.Ldap) *Ldap {
+	return &Ldap{Config: c, dial: dial}
+}
+
+// dial opens a connection to the provided LDAP URL
+func dial(url string, tlsConfig *tls.Config) (conn, error) {
+	return goldap.DialURL(url, goldap.DialWithTLSConfig(tlsConfig))
+}
+
+// Login authenticates the user against the LDAP server and returns the user
+// along with the role mapped from its group membership
+func (l *Ldap) Login(u, p string) (*authentication.User, error) {
+	// An empty password would result in an unauthenticated bind
+	if u == "" || p == "" {
+		return nil, errors.New("user and password must not be empty")
+	}
+
+	c, err := l.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	if err = l.bindServiceAccount(c); err != nil {
+		return nil, err
+	}
+
+	entry, err := l.findUser(c, u)
+	if err != nil {
+		return nil, err
+	}
+
+	// Verify the password of the user
+	if err = c.Bind(entry.DN, p); err != nil {
+		return nil, fmt.Errorf("invalid user '%s' or invalid password", u)
+	}
+
+	// Search the groups with the service account since the user might not be
+	// allowed to do so
+	if err = l.bindServiceAccount(c); err != nil {
+		return nil, err
+	}
+
+	groups, err := l.findGroups(c, entry.DN)
+	if err != nil {
+		return nil, err
+	}
+
+	role, err := authentication.GetRoleFromGroups(groups)
+	if err != nil {
+		return nil, fmt.Errorf("could not find a role for the user '%s': %s", u, err)
+	}
+
+	user := &authentication.User{
+		Email:    entry.GetAttributeValue("mail"),
+		FullName: entry.GetAttributeValue("displayName"),
+		Readonly: role.Readonly,
+		Role:     *role,
+		Username: u,
+	}
+	if user.FullName == "" {
+		user.FullName = entry.GetAttributeValue("cn")
+	}
+
+	return user, nil
+}
+
+// bindServiceAccount binds with the configured bind user, if any
+func (l *Ldap) bindServiceAccount(c conn) error {
+	if l.Config.BindUser == "" {
+		return nil
+	}
+
+	if err := c.Bind(l.Config.BindUser, l.Config.BindPass); err != nil {
+		return fmt.Errorf("could not bind to the LDAP server with the bind user: %s", err)
+	}
+	return nil
+}
+
+// connect opens a connection to the LDAP server using the configured security
+func (l *Ldap) connect() (conn, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: l.Config.Insecure,
+		ServerName:         l.Config.Server,
+	}
+
+	scheme := "ldap"
+	security := strings.ToLower(l.Config.Security)
+	switch security {
+	case "", SecurityNone, SecurityStartTLS:
+	case SecurityTLS:
+		scheme = "ldaps"
+	default:
+		return nil, fmt.Errorf("unsupported LDAP security '%s'", l.Config.Security)
+	}
+
+	url := fmt.Sprintf("%s://%s:%d", scheme, l.Config.Server, l.Config.Port)
+	logger.Debugf("Connecting to the LDAP server %s", url)
+
+	c, err := l.dial(url, tlsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to the LDAP server %s: %s", url, err)
+	}
+
+	if security == SecurityStartTLS {
+		if err := c.StartTLS(tlsConfig); err != nil {
+			c.Close()
+			return nil, fmt.Errorf("could not start TLS with the LDAP server %s: %s", url, err)
+		}
+	}
+
+	return c, nil
+}
+
+// findGroups returns the name of the groups the provided DN is a member of
+func (l *Ldap) findGroups(c conn, dn string) ([]string, error) {
+	memberFilter := fmt.Sprintf("(%s=%s)", l.Config.GroupMemberAttribute, goldap.EscapeFilter(dn))
+	if l.Config.Dialect == dialectActiveDirectory {
+		// Use the LDAP_MATCHING_RULE_IN_CHAIN rule in order to resolve nested groups
+		memberFilter = fmt.Sprintf("(%s:1.2.840.113556.1.4.1941:=%s)", l.Config.GroupMemberAttribute, goldap.EscapeFilter(dn))
+	}
+
+	filter := fmt.Sprintf("(&(objectClass=%s)%s)", l.Config.GroupObjectClass, memberFilter)
+	if l.Config.Debug {
+		logger.Infof("Searching the LDAP groups in %s with the filter %s", l.Config.GroupBaseDN, filter)
+	}
+
+	req := goldap.NewSearchRequest(
+		l.Config.GroupBaseDN,
+		goldap.ScopeWholeSubtree, goldap.NeverDerefAliases, 0, 0, false,
+		filter,
+		[]string{"cn"},
+		nil,
+	)
+
+	res, err := c.Search(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not search the LDAP groups: %s", err)
+	}
+
+	groups := make([]string, 0, len(res.Entries))
+	for _, entry := range res.Entries {
+		groups = append(groups, entry.GetAttributeValue("cn"))
+	}
+
+	return groups, nil
+}
+
+// findUser returns the LDAP entry of the provided username
+func (l *Ldap) findUser(c conn, u string) (*goldap.Entry, error) {
+	filter := fmt.Sprintf("(&(objectClass=%s)(%s=%s))", l.Config.UserObjectClass, l.Config.UserAttribute, goldap.EscapeFilter(u))
+	if l.Config.Debug {
+		logger.Infof("Searching the LDAP user in %s with the filter %s", l.Config.UserBaseDN, filter)
+	}
+
+	req := goldap.NewSearchRequest(
+		l.Config.UserBaseDN,
+		goldap.ScopeWholeSubtree, goldap.NeverDerefAliases, 0, 0, false,
+		filter,
+		[]string{"dn", "cn", "displayName", "mail"},
+		nil,
+	)
+
+	res, err := c.Search(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not search the LDAP user '%s': %s", u, err)
+	}
+
+	if len(res.Entries) != 1 {
+		return nil, fmt.Errorf("invalid user '%s' or invalid password", u)
+	}
+
+	return res.Entries[0], nil
+}
@@ -0,0 +1,175 @@
+package ldap
+
+import (
+	"crypto/tls"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	goldap "github.com/go-ldap/ldap/v3"
+	"github.com/sensu/uchiwa/uchiwa/authentication"
+	"github.com/sensu/uchiwa/uchiwa/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeDirectory is an in-process stand-in for an LDAP server
+type fakeDirectory struct {
+	entries   []*goldap.Entry
+	passwords map[string]string
+	bound     string
+	startTLS  bool
+	url       string
+}
+
+var filterComponent = regexp.MustCompile(`\(([^()=:&|!]+)(?::[^=]*)?=([^()]*)\)`)
+
+func (f *fakeDirectory) Bind(dn, password string) error {
+	if p, ok := f.passwords[dn]; !ok || p != password {
+		return errors.New("invalid credentials")
+	}
+	f.bound = dn
+	return nil
+}
+
+func (f *fakeDirectory) Close() {}
+
+func (f *fakeDirectory) Search(req *goldap.SearchRequest) (*goldap.SearchResult, error) {
+	if f.bound == "" {
+		return nil, errors.New("operations error")
+	}
+
+	res := &goldap.SearchResult{}
+OUTER:
+	for _, entry := range f.entries {
+		if !strings.HasSuffix(entry.DN, req.BaseDN) {
+			continue
+		}
+		for _, m := range filterComponent.FindAllStringSubmatch(req.Filter, -1) {
+			value := strings.Replace(m[2], `\5c`, `\`, -1)
+			found := false
+			for _, v := range entry.GetAttributeValues(m[1]) {
+				if strings.EqualFold(v, value) {
+					found = true
+				}
+			}
+			if !found {
+				continue OUTER
+			}
+		}
+		res.Entries = append(res.Entries, entry)
+	}
+	return res, nil
+}
+
+func (f *fakeDirectory) StartTLS(*tls.Config) error {
+	f.startTLS = true
+	return nil
+}
+
+func newFakeDriver(security string) (*Ldap, *fakeDirectory) {
+	directory := &fakeDirectory{
+		entries: []*goldap.Entry{
+			goldap.NewEntry("uid=alice,ou=people,dc=example,dc=com", map[string][]string{
+				"objectClass": {"person"}, "uid": {"alice"}, "cn": {"Alice"}, "mail": {"alice@example.com"},
+			}),
+			goldap.NewEntry("uid=bob,ou=people,dc=example,dc=com", map[string][]string{
+				"objectClass": {"person"}, "uid": {"bob"}, "cn": {"Bob"},
+			}),
+			goldap.NewEntry("cn=admins,ou=groups,dc=example,dc=com", map[string][]string{
+				"objectClass": {"groupOfNames"}, "cn": {"admins"}, "member": {"uid=alice,ou=people,dc=example,dc=com"},
+			}),
+		},
+		passwords: map[string]string{
+			"cn=uchiwa,dc=example,dc=com":           "secret",
+			"uid=alice,ou=people,dc=example,dc=com": "alicepass",
+			"uid=bob,ou=people,dc=example,dc=com":   "bobpass",
+		},
+	}
+
+	l := New(config.Ldap{
+		Server:               "ldap.example.com",
+		Port:                 389,
+		BindUser:             "cn=uchiwa,dc=example,dc=com",
+		BindPass:             "secret",
+		GroupBaseDN:          "ou=groups,dc=example,dc=com",
+		GroupMemberAttribute: "member",
+		GroupObjectClass:     "groupOfNames",
+		Security:             security,
+		UserAttribute:        "uid",
+		UserBaseDN:           "ou=people,dc=example,dc=com",
+		UserObjectClass:      "person",
+	})
+	l.dial = func(url string, c *tls.Config) (conn, error) {
+		directory.url = url
+		return directory, nil
+	}
+
+	return l, directory
+}
+
+func TestLogin(t *testing.T) {
+	authentication.SetRoles([]authentication.Role{
+		{Name: "admin", Members: []string{"admins"}},
+	})
+	defer authentication.SetRoles(nil)
+
+	l, directory := newFakeDriver("none")
+
+	// Successful login
+	user, err := l.Login("alice", "alicepass")
+	assert.Nil(t, err)
+	assert.Equal(t, "alice", user.Username)
+	assert.Equal(t, "Alice", user.FullName)
+	assert.Equal(t, "alice@example.com", user.Email)
+	assert.Equal(t, "admin", user.Role.Name)
+	assert.Equal(t, "ldap://ldap.example.com:389", directory.url)
+	assert.False(t, directory.startTLS)
+
+	// Wrong password
+	_, err = l.Login("alice", "wrong")
+	assert.NotNil(t, err)
+
+	// Empty password
+	_, err = l.Login("alice", "")
+	assert.NotNil(t, err)
+
+	// Unknown user
+	_, err = l.Login("carol", "carolpass")
+	assert.NotNil(t, err)
+
+	// Valid user without any role
+	_, err = l.Login("bob", "bobpass")
+	assert.NotNil(t, err)
+
+	// Valid user with the fallback role
+	authentication.AddRoles(authentication.Role{Name: "guest", Fallback: true, Readonly: true})
+	user, err = l.Login("bob", "bobpass")
+	assert.Nil(t, err)
+	assert.Equal(t, "guest", user.Role.Name)
+	assert.True(t, user.Readonly)
+
+	// Wrong bind user
+	l.Config.BindPass = "wrong"
+	_, err = l.Login("alice", "alicepass")
+	assert.NotNil(t, err)
+}
+
+func TestConnect(t *testing.T) {
+	l, directory := newFakeDriver("starttls")
+	_, err := l.connect()
+	assert.Nil(t, err)
+	assert.Equal(t, "ldap://ldap.example.com:389", directory.url)
+	assert.True(t, directory.startTLS)
+
+	l, directory = newFakeDriver("tls")
+	l.Config.Port = 636
+	_, err = l.connect()
+	assert.Nil(t, err)
+	assert.Equal(t, "ldaps://ldap.example.com:636", directory.url)
+	assert.False(t, directory.startTLS)
+
+	l, _ = newFakeDriver("foo")
+	_, err = l.connect()
+	assert.NotNil(t, err)
+}
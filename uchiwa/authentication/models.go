@@ -1,32 +1,46 @@
 package authentication
 
-import "github.com/sensu/uchiwa/uchiwa/structs"
+import (
+	"net/http"
 
-var (
-	// Roles contains the roles for the active auth driver
-	Roles []Role
-	users []User
+	"github.com/sensu/uchiwa/uchiwa/structs"
 )
 
+var users []User
+
 type loginFn func(string, string) (*User, error)
 
+type requestFn func(*http.Request) (*User, error)
+
+type userFn func(string) (*User, error)
+
 // Config contains the authentication configuration
 type Config struct {
-	Auth       structs.Auth
-	DriverFn   loginFn
-	DriverName string
+	Auth            structs.Auth
+	DriverFn        loginFn
+	DriverName      string
+	Handlers        map[string]http.Handler
+	PrivateHandlers map[string]http.Handler
+	RequestFn       requestFn
+	UserFn          userFn
+
+	throttle  *throttle
+	twoFactor *twoFactor
 }
 
 // Role contains the attributes of a role
 type Role struct {
-	AccessToken   string
-	Datacenters   []string
-	Fallback      bool
-	Members       []string
-	Methods       Methods
-	Name          string
-	Readonly      bool
-	Subscriptions []string
+	AccessToken      string
+	Admin            bool
+	Audit            bool
+	Datacenters      []string
+	Fallback         bool
+	Members          []string
+	Methods          Methods
+	Name             string
+	Readonly         bool
+	RequireTwoFactor bool
+	Subscriptions    []string
 }
 
 // Methods contains the allowed endpoints for each HTTP method
@@ -0,0 +1,187 @@
+package authentication
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/sensu/uchiwa/uchiwa/audit"
+	"github.com/sensu/uchiwa/uchiwa/helpers"
+	"github.com/sensu/uchiwa/uchiwa/logger"
+	"github.com/sensu/uchiwa/uchiwa/structs"
+)
+
+const (
+	// oauthStateCookie contains the state parameter of a pending OAuth flow
+	oauthStateCookie = "uchiwa_oauth_state"
+	// userCookie contains the authenticated user, as consumed by the frontend
+	userCookie = "uchiwa_auth"
+)
+
+// Handle registers a public handler required by the authentication driver,
+// e.g. an OAuth callback
+func (a *Config) Handle(pattern string, handler http.Handler) {
+	if a.Handlers == nil {
+		a.Handlers = make(map[string]http.Handler)
+	}
+	a.Handlers[pattern] = handler
+}
+
+// HandlePrivate registers a handler of the authentication driver that is
+// only served to the authenticated users, once their request is authorized,
+// e.g. the management of the users
+func (a *Config) HandlePrivate(pattern string, handler http.Handler) {
+	if a.PrivateHandlers == nil {
+		a.PrivateHandlers = make(map[string]http.Handler)
+	}
+	a.PrivateHandlers[pattern] = handler
+}
+
+// CallbackURL returns the absolute URL of the provided path, based on the
+// host used by the user to reach Uchiwa
+func CallbackURL(r *http.Request, path string) string {
+	scheme := "http"
+	if r.TLS != nil || strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https") {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s%s", scheme, r.Host, path)
+}
+
+// ExchangeOAuthCode sends the provided parameters to the token endpoint of an
+// OAuth provider and returns the decoded response
+func ExchangeOAuthCode(client *http.Client, tokenURL string, params url.Values) (map[string]interface{}, error) {
+	req, err := http.NewRequest("POST", tokenURL, strings.NewReader(params.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not exchange the OAuth code: %s", err)
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("could not read the OAuth token response: %s", err)
+	}
+
+	if res.StatusCode >= 400 {
+		return nil, fmt.Errorf("could not exchange the OAuth code: %s", res.Status)
+	}
+
+	m, err := helpers.GetMapFromBytes(body)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse the OAuth token response: %s", err)
+	}
+
+	if e, ok := m["error"].(string); ok && e != "" {
+		return nil, fmt.Errorf("the OAuth provider returned an error: %s", e)
+	}
+
+	return m, nil
+}
+
+// LoginFailure logs a failed login to the audit log and redirects the user
+// to the login page
+func LoginFailure(w http.ResponseWriter, r *http.Request, err error) {
+	logger.Info(err)
+
+	log := structs.AuditLog{Action: "loginfailure", Level: "default", Output: err.Error()}
+	log.RemoteAddr = helpers.GetIP(r)
+	audit.Log(log)
+
+	http.Redirect(w, r, "/#/login", http.StatusFound)
+}
+
+// LoginRedirect issues a JWT for a user authenticated by a third party
+// provider, stores the user in a cookie and redirects the user to the dashboard
+func LoginRedirect(w http.ResponseWriter, r *http.Request, user *User) {
+	token, err := GetToken(&user.Role, user.Username)
+	if err != nil {
+		LoginFailure(w, r, fmt.Errorf("Authentication failed, could not create the token: %s", err))
+		return
+	}
+
+	log := structs.AuditLog{Action: "loginsuccess", Level: "verbose", User: user.Username}
+	log.RemoteAddr = helpers.GetIP(r)
+	audit.Log(log)
+
+	// Obfuscate user attributes
+	user.Password = ""
+	user.PasswordHash = ""
+	user.PasswordSalt = ""
+	user.Token = token
+
+	j, err := json.Marshal(user)
+	if err != nil {
+		LoginFailure(w, r, err)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:   userCookie,
+		Value:  url.QueryEscape(string(j)),
+		Path:   "/",
+		Secure: r.TLS != nil,
+	})
+
+	http.Redirect(w, r, "/", http.StatusFound)
+}
+
+// SetOAuthState generates a random state parameter for a new OAuth flow and
+// stores it in a short-lived cookie
+func SetOAuthState(w http.ResponseWriter, r *http.Request) (string, error) {
+	state, err := RandomString(32)
+	if err != nil {
+		return "", err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    state,
+		Path:     "/login",
+		MaxAge:   600,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+	})
+
+	return state, nil
+}
+
+// VerifyOAuthState verifies that the state parameter received by an OAuth
+// callback corresponds to the one stored by SetOAuthState
+func VerifyOAuthState(w http.ResponseWriter, r *http.Request) error {
+	cookie, err := r.Cookie(oauthStateCookie)
+	if err != nil || cookie.Value == "" {
+		return errors.New("missing OAuth state")
+	}
+
+	// The state can only be used once
+	http.SetCookie(w, &http.Cookie{Name: oauthStateCookie, Path: "/login", MaxAge: -1})
+
+	state := r.URL.Query().Get("state")
+	if subtle.ConstantTimeCompare([]byte(state), []byte(cookie.Value)) != 1 {
+		return errors.New("invalid OAuth state")
+	}
+
+	return nil
+}
+
+// RandomString returns a URL-safe string generated from n random bytes
+func RandomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("could not generate random bytes: %s", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,462 @@
+package oidc
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/sensu/uchiwa/uchiwa/authentication"
+	"github.com/sensu/uchiwa/uchiwa/config"
+)
+
+const (
+	// CallbackPath is the path of the OIDC callback handler
+	CallbackPath = "/login/oidc/callback"
+	// LoginPath is the path of the handler that initiates the OIDC flow
+	LoginPath = "/login/oidc"
+
+	// nonceCookie contains the nonce expected in the ID token
+	nonceCookie = "uchiwa_oidc_nonce"
+	// verifierCookie contains the PKCE code verifier of a pending flow
+	verifierCookie = "uchiwa_oidc_verifier"
+)
+
+// Oidc represents the OpenID Connect authentication driver
+type Oidc struct {
+	Config config.Oidc
+	Client *http.Client
+
+	keys     map[string]*rsa.PublicKey
+	mu       sync.Mutex
+	provider *provider
+}
+
+// provider contains the metadata of the OpenID provider, as returned by the
+// discovery endpoint
+type provider struct {
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	Issuer                string `json:"issuer"`
+	JwksURI               string `json:"jwks_uri"`
+	TokenEndpoint         string `json:"token_endpoint"`
+}
+
+// jwk represents a JSON Web Key
+type jwk struct {
+	E   string `json:"e"`
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	N   string `json:"n"`
+	Use string `json:"use"`
+}
+
+// New returns an OIDC authentication driver for the provided configuration
+func New(c config.Oidc) *Oidc {
+	c.Issuer = strings.TrimSuffix(c.Issuer, "/")
+	return &Oidc{Config: c, Client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Register sets the OIDC driver as the authentication driver and registers
+// the OIDC handlers
+func (o *Oidc) Register(a *authentication.Config) {
+	a.Advanced(o.Login, "oidc")
+	a.Handle(LoginPath, o.LoginHandler())
+	a.Handle(CallbackPath, o.CallbackHandler())
+}
+
+// Login rejects any authentication based on a username and a password since
+// the users must authenticate against the OpenID provider
+func (o *Oidc) Login(u, p string) (*authentication.User, error) {
+	return nil, errors.New("the OIDC driver does not support password authentication")
+}
+
+// LoginHandler redirects the user to the authorization endpoint of the
+// OpenID provider
+func (o *Oidc) LoginHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, err := o.discover()
+		if err != nil {
+			authentication.LoginFailure(w, r, err)
+			return
+		}
+
+		state, err := authentication.SetOAuthState(w, r)
+		if err != nil {
+			authentication.LoginFailure(w, r, err)
+			return
+		}
+
+		verifier, err := authentication.RandomString(32)
+		if err != nil {
+			authentication.LoginFailure(w, r, err)
+			return
+		}
+		nonce, err := authentication.RandomString(16)
+		if err != nil {
+			authentication.LoginFailure(w, r, err)
+			return
+		}
+		setCookie(w, r, verifierCookie, verifier)
+		setCookie(w, r, nonceCookie, nonce)
+
+		challenge := sha256.Sum256([]byte(verifier))
+
+		params := url.Values{}
+		params.Set("client_id", o.Config.ClientID)
+		params.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+		params.Set("code_challenge_method", "S256")
+		params.Set("nonce", nonce)
+		params.Set("redirect_uri", o.redirectURL(r))
+		params.Set("response_type", "code")
+		params.Set("scope", strings.Join(o.scopes(), " "))
+		params.Set("state", state)
+
+		separator := "?"
+		if strings.Contains(p.AuthorizationEndpoint, "?") {
+			separator = "&"
+		}
+
+		http.Redirect(w, r, p.AuthorizationEndpoint+separator+params.Encode(), http.StatusFound)
+	})
+}
+
+// CallbackHandler exchanges the authorization code for an ID token,
+// determines the role of the user from its claims and then issues a JWT
+func (o *Oidc) CallbackHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := authentication.VerifyOAuthState(w, r); err != nil {
+			authentication.LoginFailure(w, r, fmt.Errorf("Authentication failed: %s", err))
+			return
+		}
+
+		if e := r.URL.Query().Get("error"); e != "" {
+			authentication.LoginFailure(w, r, fmt.Errorf("Authentication failed: the OpenID provider returned an error: %s", e))
+			return
+		}
+
+		verifier := getCookie(w, r, verifierCookie)
+		nonce := getCookie(w, r, nonceCookie)
+		if verifier == "" || nonce == "" {
+			authentication.LoginFailure(w, r, errors.New("Authentication failed: missing PKCE verifier or nonce"))
+			return
+		}
+
+		user, err := o.authenticate(r, r.URL.Query().Get("code"), verifier, nonce)
+		if err != nil {
+			authentication.LoginFailure(w, r, fmt.Errorf("Authentication failed: %s", err))
+			return
+		}
+
+		authentication.LoginRedirect(w, r, user)
+	})
+}
+
+// authenticate exchanges the provided code and returns the user described by
+// the ID token
+func (o *Oidc) authenticate(r *http.Request, code, verifier, nonce string) (*authentication.User, error) {
+	if code == "" {
+		return nil, errors.New("missing OAuth code")
+	}
+
+	p, err := o.discover()
+	if err != nil {
+		return nil, err
+	}
+
+	params := url.Values{}
+	params.Set("client_id", o.Config.ClientID)
+	if o.Config.ClientSecret != "" {
+		params.Set("client_secret", o.Config.ClientSecret)
+	}
+	params.Set("code", code)
+	params.Set("code_verifier", verifier)
+	params.Set("grant_type", "authorization_code")
+	params.Set("redirect_uri", o.redirectURL(r))
+
+	m, err := authentication.ExchangeOAuthCode(o.Client, p.TokenEndpoint, params)
+	if err != nil {
+		return nil, err
+	}
+
+	idToken, ok := m["id_token"].(string)
+	if !ok || idToken == "" {
+		return nil, errors.New("the OpenID provider did not return any ID token")
+	}
+
+	claims, err := o.verifyIDToken(idToken, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	username := claimString(claims, o.Config.UsernameClaim)
+	if username == "" {
+		username = claimString(claims, "sub")
+	}
+	if username == "" {
+		return nil, errors.New("the ID token does not identify the user")
+	}
+
+	role, err := authentication.GetRoleFromGroups(claimStrings(claims, o.Config.GroupsClaim))
+	if err != nil {
+		return nil, fmt.Errorf("could not find a role for the user '%s': %s", username, err)
+	}
+
+	user := &authentication.User{
+		Email:    claimString(claims, "email"),
+		FullName: claimString(claims, "name"),
+		Readonly: role.Readonly,
+		Role:     *role,
+		Username: username,
+	}
+	if user.FullName == "" {
+		user.FullName = username
+	}
+
+	return user, nil
+}
+
+// verifyIDToken verifies the signature of the ID token against the keys of
+// the provider, along with its issuer, audience, expiration and nonce
+func (o *Oidc) verifyIDToken(idToken, nonce string) (map[string]interface{}, error) {
+	p, err := o.discover()
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := jwt.Parse(idToken, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		kid, _ := t.Header["kid"].(string)
+		return o.key(p, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid ID token: %s", err)
+	}
+
+	if iss, _ := token.Claims["iss"].(string); iss != p.Issuer {
+		return nil, fmt.Errorf("invalid ID token: unexpected issuer '%s'", iss)
+	}
+	if !containsAudience(token.Claims["aud"], o.Config.ClientID) {
+		return nil, errors.New("invalid ID token: the client is not part of the audience")
+	}
+	if _, ok := token.Claims["exp"].(float64); !ok {
+		return nil, errors.New("invalid ID token: missing expiration")
+	}
+	if n, _ := token.Claims["nonce"].(string); n != nonce {
+		return nil, errors.New("invalid ID token: invalid nonce")
+	}
+
+	return token.Claims, nil
+}
+
+// discover retrieves and caches the metadata of the OpenID provider
+func (o *Oidc) discover() (*provider, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.provider != nil {
+		return o.provider, nil
+	}
+
+	var p provider
+	if err := o.get(o.Config.Issuer+"/.well-known/openid-configuration", &p); err != nil {
+		return nil, fmt.Errorf("could not discover the OpenID provider: %s", err)
+	}
+
+	if strings.TrimSuffix(p.Issuer, "/") != o.Config.Issuer {
+		return nil, fmt.Errorf("the OpenID provider returned the issuer '%s' instead of '%s'", p.Issuer, o.Config.Issuer)
+	}
+	if p.AuthorizationEndpoint == "" || p.JwksURI == "" || p.TokenEndpoint == "" {
+		return nil, errors.New("the OpenID provider metadata is incomplete")
+	}
+
+	o.provider = &p
+	return o.provider, nil
+}
+
+// key returns the RSA public key identified by kid, refreshing the keys of the
+// provider once if the key is unknown, e.g. after a key rotation
+func (o *Oidc) key(p *provider, kid string) (*rsa.PublicKey, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if key := o.findKey(kid); key != nil {
+		return key, nil
+	}
+
+	keys, err := o.fetchKeys(p.JwksURI)
+	if err != nil {
+		return nil, err
+	}
+	o.keys = keys
+
+	if key := o.findKey(kid); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key '%s'", kid)
+}
+
+// findKey returns the cached key identified by kid. A token without any kid
+// can only be verified if the provider has a single key
+func (o *Oidc) findKey(kid string) *rsa.PublicKey {
+	if kid == "" && len(o.keys) == 1 {
+		for _, key := range o.keys {
+			return key
+		}
+	}
+	return o.keys[kid]
+}
+
+// fetchKeys retrieves the RSA signing keys of the JWKS
+func (o *Oidc) fetchKeys(jwksURI string) (map[string]*rsa.PublicKey, error) {
+	var jwks struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := o.get(jwksURI, &jwks); err != nil {
+		return nil, fmt.Errorf("could not retrieve the JWKS: %s", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range jwks.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			continue
+		}
+
+		keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+
+	return keys, nil
+}
+
+// get performs a GET request and decodes the JSON response into v
+func (o *Oidc) get(u string, v interface{}) error {
+	res, err := o.Client.Get(u)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 400 {
+		return fmt.Errorf("GET %s returned: %s", u, res.Status)
+	}
+
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+// redirectURL returns the configured redirect URL, or the URL of the callback
+// handler as reached by the user
+func (o *Oidc) redirectURL(r *http.Request) string {
+	if o.Config.RedirectURL != "" {
+		return o.Config.RedirectURL
+	}
+	return authentication.CallbackURL(r, CallbackPath)
+}
+
+// scopes returns the configured scopes, which always include openid
+func (o *Oidc) scopes() []string {
+	for _, scope := range o.Config.Scopes {
+		if scope == "openid" {
+			return o.Config.Scopes
+		}
+	}
+	return append([]string{"openid"}, o.Config.Scopes...)
+}
+
+// claim returns the value of the claim, which can be nested using dots,
+// e.g. realm_access.roles
+func claim(claims map[string]interface{}, name string) interface{} {
+	if name == "" {
+		return nil
+	}
+
+	var value interface{} = claims
+	for _, key := range strings.Split(name, ".") {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = m[key]
+	}
+	return value
+}
+
+// claimString returns the value of a string claim
+func claimString(claims map[string]interface{}, name string) string {
+	s, _ := claim(claims, name).(string)
+	return s
+}
+
+// claimStrings returns the values of a claim containing either a list of
+// strings or a single string
+func claimStrings(claims map[string]interface{}, name string) []string {
+	var values []string
+	switch v := claim(claims, name).(type) {
+	case string:
+		values = append(values, v)
+	case []interface{}:
+		for _, e := range v {
+			if s, ok := e.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+	return values
+}
+
+// containsAudience returns true if the aud claim, either a string or a list
+// of strings, contains the client ID
+func containsAudience(aud interface{}, clientID string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == clientID
+	case []interface{}:
+		for _, e := range v {
+			if e == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// getCookie returns the value of the cookie and removes it since it can only
+// be used once
+func getCookie(w http.ResponseWriter, r *http.Request, name string) string {
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return ""
+	}
+	http.SetCookie(w, &http.Cookie{Name: name, Path: "/login", MaxAge: -1})
+	return cookie.Value
+}
+
+// setCookie stores a value in a short-lived cookie for the duration of the flow
+func setCookie(w http.ResponseWriter, r *http.Request, name, value string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/login",
+		MaxAge:   600,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+	})
+}
@@ -0,0 +1,247 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/sensu/uchiwa/uchiwa/audit"
+	"github.com/sensu/uchiwa/uchiwa/authentication"
+	"github.com/sensu/uchiwa/uchiwa/config"
+	"github.com/sensu/uchiwa/uchiwa/structs"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeIssuer represents an in-process OpenID provider
+type fakeIssuer struct {
+	*httptest.Server
+
+	claims     map[string]interface{}
+	challenges map[string]string
+	key        *rsa.PrivateKey
+	nonces     map[string]string
+}
+
+func newFakeIssuer(t *testing.T) *fakeIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := &fakeIssuer{
+		challenges: make(map[string]string),
+		claims:     map[string]interface{}{"preferred_username": "alice", "name": "Alice", "groups": []string{"ops"}},
+		key:        key,
+		nonces:     make(map[string]string),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(provider{
+			AuthorizationEndpoint: f.URL + "/authorize",
+			Issuer:                f.URL,
+			JwksURI:               f.URL + "/jwks",
+			TokenEndpoint:         f.URL + "/token",
+		})
+	})
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		assert.Equal(t, "foo", q.Get("client_id"))
+		assert.Equal(t, "code", q.Get("response_type"))
+		assert.Equal(t, "S256", q.Get("code_challenge_method"))
+		assert.Contains(t, q.Get("scope"), "openid")
+
+		code := fmt.Sprintf("code%d", len(f.challenges))
+		f.challenges[code] = q.Get("code_challenge")
+		f.nonces[code] = q.Get("nonce")
+		http.Redirect(w, r, q.Get("redirect_uri")+"?code="+code+"&state="+q.Get("state"), http.StatusFound)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		code := r.Form.Get("code")
+		challenge := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+		if f.challenges[code] == "" || f.challenges[code] != base64.RawURLEncoding.EncodeToString(challenge[:]) ||
+			r.Form.Get("grant_type") != "authorization_code" {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		delete(f.challenges, code)
+
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "foo", "id_token": f.idToken(t, f.nonces[code])})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string][]jwk{"keys": {{
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(f.key.E)).Bytes()),
+			Kid: "key1",
+			Kty: "RSA",
+			N:   base64.RawURLEncoding.EncodeToString(f.key.N.Bytes()),
+			Use: "sig",
+		}}})
+	})
+
+	f.Server = httptest.NewServer(mux)
+	return f
+}
+
+// idToken returns an ID token signed by the issuer
+func (f *fakeIssuer) idToken(t *testing.T, nonce string) string {
+	token := jwt.New(jwt.GetSigningMethod("RS256"))
+	token.Header["kid"] = "key1"
+	token.Claims["iss"] = f.URL
+	token.Claims["aud"] = "foo"
+	token.Claims["sub"] = "1234"
+	token.Claims["exp"] = time.Now().Add(time.Minute).Unix()
+	token.Claims["nonce"] = nonce
+	for k, v := range f.claims {
+		token.Claims[k] = v
+	}
+
+	s, err := token.SignedString(f.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestOIDCFlow(t *testing.T) {
+	audit.Log = audit.LogMock
+	authentication.SetRoles([]authentication.Role{{Name: "operators", Members: []string{"ops"}}})
+	defer authentication.SetRoles(nil)
+
+	issuer := newFakeIssuer(t)
+	defer issuer.Close()
+
+	auth := authentication.New(structs.Auth{})
+	New(config.Oidc{ClientID: "foo", GroupsClaim: "groups", Issuer: issuer.URL + "/", UsernameClaim: "preferred_username"}).Register(&auth)
+	assert.Equal(t, "oidc", auth.DriverName)
+
+	mux := http.NewServeMux()
+	for pattern, handler := range auth.Handlers {
+		mux.Handle(pattern, handler)
+	}
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{Jar: jar}
+	res, err := client.Get(server.URL + LoginPath)
+	assert.Nil(t, err)
+	res.Body.Close()
+
+	var user *authentication.User
+	u, _ := url.Parse(server.URL)
+	for _, cookie := range jar.Cookies(u) {
+		if cookie.Name == "uchiwa_auth" {
+			value, _ := url.QueryUnescape(cookie.Value)
+			assert.Nil(t, json.NewDecoder(strings.NewReader(value)).Decode(&user))
+		}
+	}
+
+	if assert.NotNil(t, user) {
+		assert.Equal(t, "alice", user.Username)
+		assert.Equal(t, "Alice", user.FullName)
+		assert.Equal(t, "operators", user.Role.Name)
+		assert.NotEmpty(t, user.Token)
+	}
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	issuer := newFakeIssuer(t)
+	defer issuer.Close()
+
+	o := New(config.Oidc{ClientID: "foo", Issuer: issuer.URL})
+
+	claims, err := o.verifyIDToken(issuer.idToken(t, "nonce"), "nonce")
+	assert.Nil(t, err)
+	assert.Equal(t, "alice", claims["preferred_username"])
+
+	// Invalid nonce
+	_, err = o.verifyIDToken(issuer.idToken(t, "nonce"), "foo")
+	assert.NotNil(t, err)
+
+	// Another audience
+	issuer.claims["aud"] = []string{"bar"}
+	_, err = o.verifyIDToken(issuer.idToken(t, "nonce"), "nonce")
+	assert.NotNil(t, err)
+	issuer.claims["aud"] = []string{"bar", "foo"}
+	_, err = o.verifyIDToken(issuer.idToken(t, "nonce"), "nonce")
+	assert.Nil(t, err)
+	delete(issuer.claims, "aud")
+
+	// Another issuer
+	issuer.claims["iss"] = "https://example.com"
+	_, err = o.verifyIDToken(issuer.idToken(t, "nonce"), "nonce")
+	assert.NotNil(t, err)
+	delete(issuer.claims, "iss")
+
+	// Expired token
+	issuer.claims["exp"] = time.Now().Add(-time.Minute).Unix()
+	_, err = o.verifyIDToken(issuer.idToken(t, "nonce"), "nonce")
+	assert.NotNil(t, err)
+	delete(issuer.claims, "exp")
+
+	// Signed by an unknown key
+	key := issuer.key
+	issuer.key, _ = rsa.GenerateKey(rand.Reader, 2048)
+	token := issuer.idToken(t, "nonce")
+	issuer.key = key
+	_, err = o.verifyIDToken(token, "nonce")
+	assert.NotNil(t, err)
+
+	// HMAC signature using the public key as secret
+	hmac := jwt.New(jwt.GetSigningMethod("HS256"))
+	hmac.Claims["iss"] = issuer.URL
+	hmac.Claims["aud"] = "foo"
+	hmac.Claims["exp"] = time.Now().Add(time.Minute).Unix()
+	hmac.Claims["nonce"] = "nonce"
+	s, _ := hmac.SignedString(key.N.Bytes())
+	_, err = o.verifyIDToken(s, "nonce")
+	assert.NotNil(t, err)
+}
+
+func TestDiscover(t *testing.T) {
+	issuer := newFakeIssuer(t)
+	defer issuer.Close()
+
+	o := New(config.Oidc{Issuer: issuer.URL + "/foo"})
+	_, err := o.discover()
+	assert.NotNil(t, err)
+
+	o = New(config.Oidc{Issuer: issuer.URL})
+	p, err := o.discover()
+	assert.Nil(t, err)
+	assert.Equal(t, issuer.URL+"/token", p.TokenEndpoint)
+}
+
+func TestClaims(t *testing.T) {
+	claims := map[string]interface{}{
+		"groups":       []interface{}{"ops", "dev"},
+		"email":        "alice@example.com",
+		"realm_access": map[string]interface{}{"roles": []interface{}{"admin"}},
+	}
+
+	assert.Equal(t, []string{"ops", "dev"}, claimStrings(claims, "groups"))
+	assert.Equal(t, []string{"admin"}, claimStrings(claims, "realm_access.roles"))
+	assert.Equal(t, []string{"alice@example.com"}, claimStrings(claims, "email"))
+	assert.Nil(t, claimStrings(claims, "foo.bar"))
+	assert.Equal(t, "alice@example.com", claimString(claims, "email"))
+	assert.Equal(t, "", claimString(claims, ""))
+}
+
+func TestScopes(t *testing.T) {
+	assert.Equal(t, []string{"openid", "groups"}, New(config.Oidc{Scopes: []string{"groups"}}).scopes())
+	assert.Equal(t, []string{"profile", "openid"}, New(config.Oidc{Scopes: []string{"profile", "openid"}}).scopes())
+}
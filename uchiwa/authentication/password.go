@@ -0,0 +1,121 @@
+package authentication
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/palourde/crypt"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+
+	// Supported schemas for hashed passwords
+	_ "github.com/palourde/crypt/apr1_crypt"
+	_ "github.com/palourde/crypt/md5_crypt"
+	_ "github.com/palourde/crypt/sha256_crypt"
+	_ "github.com/palourde/crypt/sha512_crypt"
+)
+
+// Supported algorithms for hashing passwords
+const (
+	Argon2id = "argon2id"
+	Bcrypt   = "bcrypt"
+)
+
+// Default parameters used to generate argon2id hashes
+const (
+	argon2Memory  = 64 * 1024
+	argon2Threads = 4
+	argon2Time    = 1
+	argon2KeyLen  = 32
+	argon2SaltLen = 16
+)
+
+// Minimum lengths of the salt and the key of the argon2id hashes verified
+const (
+	argon2MinKeyLen  = 16
+	argon2MinSaltLen = 8
+)
+
+// HashPassword returns the hash of the password generated with the provided
+// algorithm, bcrypt by default
+func HashPassword(password, algorithm string) (string, error) {
+	if password == "" {
+		return "", errors.New("the password can't be empty")
+	}
+
+	switch algorithm {
+	case "", Bcrypt:
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		return string(hash), err
+	case Argon2id:
+		salt := make([]byte, argon2SaltLen)
+		if _, err := rand.Read(salt); err != nil {
+			return "", err
+		}
+		key := argon2.IDKey([]byte(password), salt, argon2Time, argon2Memory, argon2Threads, argon2KeyLen)
+		return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, argon2Memory, argon2Time, argon2Threads,
+			base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+	}
+
+	return "", fmt.Errorf("unsupported hashing algorithm '%s'", algorithm)
+}
+
+// VerifyPassword verifies the password against the provided hash, which can
+// either be a bcrypt, argon2id or crypt (MD5, APR1, SHA256 & SHA512) hash
+func VerifyPassword(hash, password string) error {
+	switch {
+	case strings.HasPrefix(hash, "$2a$"), strings.HasPrefix(hash, "$2b$"), strings.HasPrefix(hash, "$2y$"):
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	case strings.HasPrefix(hash, "$argon2id$"):
+		return verifyArgon2id(hash, password)
+	}
+
+	crypter, err := crypt.NewFromHash(hash)
+	if err != nil {
+		return errors.New("Invalid hashed password")
+	}
+	return crypter.Verify(hash, []byte(password))
+}
+
+// verifyArgon2id verifies the password against an argon2id hash encoded as
+// $argon2id$v=19$m=65536,t=1,p=4$salt$key
+func verifyArgon2id(hash, password string) error {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return errors.New("Invalid hashed password")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return errors.New("Invalid hashed password: unsupported argon2 version")
+	}
+
+	var memory, time uint32
+	var threads uint8
+	// The memory must be at least 8 KiB per thread
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &time, &threads); err != nil ||
+		time < 1 || threads < 1 || memory < 8*uint32(threads) {
+		return errors.New("Invalid hashed password: invalid argon2 parameters")
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil || len(salt) < argon2MinSaltLen {
+		return errors.New("Invalid hashed password: invalid argon2 salt")
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) < argon2MinKeyLen {
+		return errors.New("Invalid hashed password: invalid argon2 key")
+	}
+
+	computed := argon2.IDKey([]byte(password), salt, time, memory, threads, uint32(len(key)))
+	if subtle.ConstantTimeCompare(computed, key) != 1 {
+		return errors.New("invalid password")
+	}
+
+	return nil
+}
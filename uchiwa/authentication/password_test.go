@@ -0,0 +1,53 @@
+package authentication
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashPassword(t *testing.T) {
+	hash, err := HashPassword("foo", "")
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$2a$"))
+	assert.Nil(t, VerifyPassword(hash, "foo"))
+
+	hash, err = HashPassword("foo", Argon2id)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=65536,t=1,p=4$"))
+	assert.Nil(t, VerifyPassword(hash, "foo"))
+	assert.NotNil(t, VerifyPassword(hash, "bar"))
+
+	_, err = HashPassword("", Bcrypt)
+	assert.NotNil(t, err)
+
+	_, err = HashPassword("foo", "md5")
+	assert.NotNil(t, err)
+}
+
+func TestVerifyPassword(t *testing.T) {
+	// Invalid hashes
+	assert.NotNil(t, VerifyPassword("foo", "foo"))
+	assert.NotNil(t, VerifyPassword("$argon2id$v=19$m=65536,t=1,p=4$foo", "foo"))
+	assert.NotNil(t, VerifyPassword("$argon2id$v=16$m=65536,t=1,p=4$c2FsdA$a2V5", "foo"))
+
+	// Invalid argon2id parameters, which would either panic or match any
+	// password
+	for _, hash := range []string{
+		"$argon2id$v=19$m=65536,t=1,p=0$%[1]s$%[2]s",
+		"$argon2id$v=19$m=65536,t=0,p=4$%[1]s$%[2]s",
+		"$argon2id$v=19$m=16,t=1,p=4$%[1]s$%[2]s",
+		"$argon2id$v=19$m=65536,t=1,p=4$%[1]s$",
+		"$argon2id$v=19$m=65536,t=1,p=4$$%[2]s",
+		"$argon2id$v=19$m=65536,t=1,p=4$c2FsdA$%[2]s",
+		"$argon2id$v=19$m=65536,t=1,p=4$%[1]s$a2V5",
+	} {
+		hash = fmt.Sprintf(hash, "c2FsdHNhbHRzYWx0c2FsdA", "a2tra2tra2tra2tra2tra2tra2tra2tra2tra2tra2s")
+		assert.NotNil(t, VerifyPassword(hash, "foo"), hash)
+	}
+
+	// APR MD5 hash
+	assert.Nil(t, VerifyPassword("$apr1$YhYWYmA/$QE2UAxx9.tLWGZiLt9nPF.", "testapr"))
+}
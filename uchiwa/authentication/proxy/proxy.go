@@ -0,0 +1,94 @@
+package proxy
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/sensu/uchiwa/uchiwa/authentication"
+	"github.com/sensu/uchiwa/uchiwa/config"
+	"github.com/sensu/uchiwa/uchiwa/helpers"
+	"github.com/sensu/uchiwa/uchiwa/logger"
+)
+
+// Proxy represents the authentication driver trusting the user and groups
+// headers set by a reverse proxy
+type Proxy struct {
+	Config config.Proxy
+
+	trusted []*net.IPNet
+}
+
+// New returns a reverse proxy authentication driver for the provided
+// configuration
+func New(c config.Proxy) (*Proxy, error) {
+	if c.UserHeader == "" {
+		return nil, errors.New("the user header of the proxy driver can't be empty")
+	}
+
+	trusted, err := helpers.ParseNetworks(c.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Proxy{Config: c, trusted: trusted}, nil
+}
+
+// Register sets the proxy driver as the authentication driver
+func (p *Proxy) Register(a *authentication.Config) {
+	a.Advanced(p.Login, "proxy")
+	a.Delegate(p.Authenticate)
+}
+
+// Login rejects any authentication based on a username and a password since
+// the users are authenticated by the reverse proxy
+func (p *Proxy) Login(u, pass string) (*authentication.User, error) {
+	return nil, errors.New("the proxy driver does not support password authentication")
+}
+
+// Authenticate returns the user identified by the headers of the request if
+// the request comes from a trusted proxy, or nil otherwise
+func (p *Proxy) Authenticate(r *http.Request) (*authentication.User, error) {
+	username := r.Header.Get(p.Config.UserHeader)
+	if username == "" {
+		return nil, nil
+	}
+
+	ip := helpers.GetRemoteIP(r)
+	if !helpers.IsTrusted(net.ParseIP(ip), p.trusted) {
+		logger.Debugf("Ignoring the %s header sent by the untrusted address %s", p.Config.UserHeader, ip)
+		return nil, nil
+	}
+
+	groups := p.getGroups(r)
+	role, err := authentication.GetRoleFromGroups(groups)
+	if err != nil {
+		return nil, fmt.Errorf("the user '%s' is not allowed: %s", username, err)
+	}
+
+	return &authentication.User{FullName: username, Role: *role, Username: username}, nil
+}
+
+// getGroups returns the groups contained in the groups header
+func (p *Proxy) getGroups(r *http.Request) []string {
+	var groups []string
+	if p.Config.GroupsHeader == "" {
+		return groups
+	}
+
+	separator := p.Config.GroupsSeparator
+	if separator == "" {
+		separator = ","
+	}
+
+	for _, header := range r.Header[http.CanonicalHeaderKey(p.Config.GroupsHeader)] {
+		for _, group := range strings.Split(header, separator) {
+			if group = strings.TrimSpace(group); group != "" {
+				groups = append(groups, group)
+			}
+		}
+	}
+	return groups
+}
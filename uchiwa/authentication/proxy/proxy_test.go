@@ -0,0 +1,113 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sensu/uchiwa/uchiwa/authentication"
+	"github.com/sensu/uchiwa/uchiwa/config"
+	"github.com/sensu/uchiwa/uchiwa/structs"
+	"github.com/stretchr/testify/assert"
+)
+
+func newRequest(remoteAddr, user, groups string) *http.Request {
+	r, _ := http.NewRequest("GET", "/events", nil)
+	r.RemoteAddr = remoteAddr
+	if user != "" {
+		r.Header.Set("X-Remote-User", user)
+	}
+	if groups != "" {
+		r.Header.Set("X-Remote-Groups", groups)
+	}
+	return r
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(config.Proxy{UserHeader: "X-Remote-User", TrustedProxies: []string{"foo"}})
+	assert.NotNil(t, err)
+
+	_, err = New(config.Proxy{TrustedProxies: []string{"10.0.0.0/8"}})
+	assert.NotNil(t, err)
+
+	p, err := New(config.Proxy{UserHeader: "X-Remote-User", TrustedProxies: []string{"10.0.0.0/8", "192.168.1.1", "::1"}})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(p.trusted))
+}
+
+func TestAuthenticate(t *testing.T) {
+	authentication.SetRoles([]authentication.Role{
+		{Name: "operators", Members: []string{"ops"}},
+		{Name: "viewers", Members: []string{"dev"}, Readonly: true},
+	})
+	defer authentication.SetRoles(nil)
+
+	p, err := New(config.Proxy{
+		GroupsHeader:    "X-Remote-Groups",
+		GroupsSeparator: ",",
+		TrustedProxies:  []string{"10.0.0.0/8", "::1"},
+		UserHeader:      "X-Remote-User",
+	})
+	assert.Nil(t, err)
+
+	// Trusted proxy
+	user, err := p.Authenticate(newRequest("10.1.2.3:5000", "alice", "qa, dev"))
+	assert.Nil(t, err)
+	assert.Equal(t, "alice", user.Username)
+	assert.Equal(t, "viewers", user.Role.Name)
+
+	user, err = p.Authenticate(newRequest("[::1]:5000", "bob", "ops"))
+	assert.Nil(t, err)
+	assert.Equal(t, "operators", user.Role.Name)
+
+	// No matching role
+	_, err = p.Authenticate(newRequest("10.1.2.3:5000", "carol", "qa"))
+	assert.NotNil(t, err)
+
+	// Untrusted address, even with a forged X-Forwarded-For header
+	r := newRequest("192.168.1.1:5000", "alice", "ops")
+	r.Header.Set("X-Forwarded-For", "10.1.2.3")
+	user, err = p.Authenticate(r)
+	assert.Nil(t, err)
+	assert.Nil(t, user)
+
+	// No user header
+	user, err = p.Authenticate(newRequest("10.1.2.3:5000", "", "ops"))
+	assert.Nil(t, err)
+	assert.Nil(t, user)
+}
+
+func TestRegister(t *testing.T) {
+	authentication.SetRoles([]authentication.Role{{Name: "operators", Members: []string{"ops"}}})
+	defer authentication.SetRoles(nil)
+
+	p, _ := New(config.Proxy{GroupsHeader: "X-Remote-Groups", TrustedProxies: []string{"10.0.0.0/8"}, UserHeader: "X-Remote-User"})
+	a := authentication.New(structs.Auth{})
+	p.Register(&a)
+	assert.Equal(t, "proxy", a.DriverName)
+
+	_, err := a.DriverFn("alice", "secret")
+	assert.NotNil(t, err)
+
+	var role *authentication.Role
+	handler := a.Authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := authentication.GetJWTFromContext(r)
+		role, _ = authentication.GetRoleFromToken(token)
+		assert.Equal(t, "alice", token.Claims["Username"])
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, newRequest("10.1.2.3:5000", "alice", "ops"))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "operators", role.Name)
+
+	// Unknown group
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, newRequest("10.1.2.3:5000", "alice", "qa"))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// Untrusted requests must provide a JWT or an access token
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, newRequest("192.168.1.1:5000", "alice", "ops"))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
@@ -0,0 +1,65 @@
+package authentication
+
+import (
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// revocationList contains the identifiers of the revoked tokens along with
+// their expiration time, after which they can be forgotten
+type revocationList struct {
+	mu     sync.Mutex
+	tokens map[string]time.Time
+}
+
+var revokedTokens = &revocationList{tokens: make(map[string]time.Time)}
+
+// add adds the token identifier to the list and removes the expired ones
+func (l *revocationList) add(id string, expiration time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	for k, exp := range l.tokens {
+		if exp.Before(now) {
+			delete(l.tokens, k)
+		}
+	}
+
+	l.tokens[id] = expiration
+}
+
+// contains returns true if the token identifier is in the list
+func (l *revocationList) contains(id string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	_, ok := l.tokens[id]
+	return ok
+}
+
+// isTokenRevoked returns true if the token has been revoked
+func isTokenRevoked(token *jwt.Token) bool {
+	id, ok := token.Claims["jti"].(string)
+	if !ok {
+		return false
+	}
+	return revokedTokens.contains(id)
+}
+
+// revokeToken adds the token to the revocation list until it expires
+func revokeToken(token *jwt.Token) {
+	id, ok := token.Claims["jti"].(string)
+	if !ok {
+		return
+	}
+
+	expiration := time.Now().Add(tokenLifetime)
+	if exp, ok := token.Claims["exp"].(float64); ok {
+		expiration = time.Unix(int64(exp), 0)
+	}
+
+	revokedTokens.add(id, expiration)
+}
@@ -0,0 +1,67 @@
+package authentication
+
+import (
+	"errors"
+	"strings"
+	"sync"
+)
+
+// roles contains the roles for the active auth driver. They may be replaced
+// at runtime, e.g. by the sql driver, while the requests are authenticated
+var roles struct {
+	sync.RWMutex
+	list []Role
+}
+
+// AddRoles adds the provided roles to the roles of the active auth driver
+func AddRoles(r ...Role) {
+	roles.Lock()
+	defer roles.Unlock()
+
+	list := make([]Role, 0, len(roles.list)+len(r))
+	roles.list = append(append(list, roles.list...), r...)
+}
+
+// GetRoles returns the roles of the active auth driver. The returned slice
+// must not be modified
+func GetRoles() []Role {
+	roles.RLock()
+	defer roles.RUnlock()
+	return roles.list
+}
+
+// SetRoles replaces the roles of the active auth driver
+func SetRoles(r []Role) {
+	roles.Lock()
+	defer roles.Unlock()
+	roles.list = r
+}
+
+// GetRoleFromGroups returns the first role of the active auth driver that
+// lists one of the provided groups as a member. The fallback role, if any, is
+// returned when none of the groups matches
+func GetRoleFromGroups(groups []string) (*Role, error) {
+	var fallback *Role
+
+	list := GetRoles()
+	for i := range list {
+		role := list[i]
+		for _, member := range role.Members {
+			for _, group := range groups {
+				if strings.EqualFold(member, group) {
+					return &role, nil
+				}
+			}
+		}
+
+		if role.Fallback && fallback == nil {
+			fallback = &role
+		}
+	}
+
+	if fallback != nil {
+		return fallback, nil
+	}
+
+	return nil, errors.New("the user is not a member of any configured role")
+}
@@ -0,0 +1,31 @@
+package authentication
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetRoleFromGroups(t *testing.T) {
+	SetRoles([]Role{
+		{Name: "guest", Fallback: true},
+		{Name: "admin", Members: []string{"Admins"}},
+		{Name: "operator", Members: []string{"operators", "admins"}},
+	})
+	defer SetRoles(nil)
+
+	// First matching role, case insensitive
+	role, err := GetRoleFromGroups([]string{"foo", "admins"})
+	assert.Nil(t, err)
+	assert.Equal(t, "admin", role.Name)
+
+	// Fallback role
+	role, err = GetRoleFromGroups([]string{"foo"})
+	assert.Nil(t, err)
+	assert.Equal(t, "guest", role.Name)
+
+	// No fallback role
+	SetRoles(GetRoles()[1:])
+	_, err = GetRoleFromGroups([]string{"foo"})
+	assert.NotNil(t, err)
+}
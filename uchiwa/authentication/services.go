@@ -2,24 +2,33 @@ package authentication
 
 import "fmt"
 
-func (a *Config) login(user, pass string) (*User, error) {
-	// Authenticate the user with the authentication driver
+// authenticate verifies the credentials of the user with the authentication
+// driver, without issuing any token
+func (a *Config) authenticate(user, pass string) (*User, error) {
 	u, err := a.DriverFn(user, pass)
 	if err != nil {
 		return nil, fmt.Errorf("Authentication failed: %s", err)
 	}
 
+	// Fall back on the provided username if the driver did not set it
+	if u.Username == "" {
+		u.Username = user
+	}
+
 	// Obfuscate the user's salt & hash
 	u.PasswordHash = ""
 	u.PasswordSalt = ""
 
-	token, err := GetToken(&u.Role, user)
+	return u, nil
+}
+
+// issueToken adds a new token to the user struct
+func issueToken(u *User) error {
+	token, err := GetToken(&u.Role, u.Username)
 	if err != nil {
-		return nil, fmt.Errorf("Authentication failed, could not create the token: %s", err)
+		return fmt.Errorf("Authentication failed, could not create the token: %s", err)
 	}
 
-	// Add token to the user struct
 	u.Token = token
-
-	return u, nil
+	return nil
 }
@@ -0,0 +1,179 @@
+package authentication
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sensu/uchiwa/uchiwa/helpers"
+	"github.com/sensu/uchiwa/uchiwa/logger"
+	"github.com/sensu/uchiwa/uchiwa/structs"
+)
+
+// Default thresholds of the login throttling
+const (
+	defaultLoginAttempts      = 5
+	defaultLoginAttemptsPerIP = 20
+	defaultLoginBackoff       = time.Second
+	defaultLoginLockout       = 15 * time.Minute
+)
+
+// attempts contains the failed logins of a username or an IP address
+type attempts struct {
+	failures    int
+	last        time.Time
+	lockedUntil time.Time
+}
+
+// throttle limits the login attempts per username and per IP address. Every
+// failure delays the next attempt exponentially, and too many failures lock
+// the username or the IP address out temporarily
+type throttle struct {
+	backoff    time.Duration
+	lockout    time.Duration
+	maxPerIP   int
+	maxPerUser int
+	mu         sync.Mutex
+	now        func() time.Time
+	ips        map[string]*attempts
+	trusted    []*net.IPNet
+	usernames  map[string]*attempts
+}
+
+// newThrottle returns a throttle configured with the provided thresholds
+func newThrottle(a structs.Auth) *throttle {
+	t := &throttle{
+		backoff:    defaultLoginBackoff,
+		ips:        make(map[string]*attempts),
+		lockout:    defaultLoginLockout,
+		maxPerIP:   defaultLoginAttemptsPerIP,
+		maxPerUser: defaultLoginAttempts,
+		now:        time.Now,
+		usernames:  make(map[string]*attempts),
+	}
+	if a.LoginAttempts > 0 {
+		t.maxPerUser = a.LoginAttempts
+	}
+	if a.LoginAttemptsPerIP > 0 {
+		t.maxPerIP = a.LoginAttemptsPerIP
+	}
+	if a.LoginBackoff > 0 {
+		t.backoff = time.Duration(a.LoginBackoff) * time.Second
+	}
+	if a.LoginLockout > 0 {
+		t.lockout = time.Duration(a.LoginLockout) * time.Minute
+	}
+
+	// The trusted proxies are validated along with the configuration
+	trusted, err := helpers.ParseNetworks(a.TrustedProxies)
+	if err != nil {
+		logger.Warning(err)
+	}
+	t.trusted = trusted
+
+	return t
+}
+
+// clientIP returns the IP address of the client making the request. The
+// X-Forwarded-For header is only trusted when set by a trusted proxy, since
+// it could otherwise be forged in order to escape the throttling or to lock
+// out another user
+func (t *throttle) clientIP(r *http.Request) string {
+	return helpers.GetClientIP(r, t.trusted)
+}
+
+// allow returns how long the user must wait before attempting to log in with
+// the username from the IP address, or zero if the attempt is allowed
+func (t *throttle) allow(username, ip string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	wait := t.wait(t.usernames[username], now)
+	if w := t.wait(t.ips[ip], now); w > wait {
+		wait = w
+	}
+	return wait
+}
+
+// fail records a failed login and returns true if it locked out either the
+// username or the IP address
+func (t *throttle) fail(username, ip string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	t.prune(now)
+
+	lockedUser := t.record(t.usernames, username, t.maxPerUser, now)
+	lockedIP := t.record(t.ips, ip, t.maxPerIP, now)
+	return lockedUser || lockedIP
+}
+
+// succeed forgets the failed logins of the username. The failures of the IP
+// address are kept so a valid account can't be used to reset them
+func (t *throttle) succeed(username string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.usernames, username)
+}
+
+// record increments the failures of the key and locks it out once the
+// maximum is reached. Returns true if the key has just been locked out
+func (t *throttle) record(m map[string]*attempts, key string, max int, now time.Time) bool {
+	a, ok := m[key]
+	if !ok {
+		a = &attempts{}
+		m[key] = a
+	}
+
+	a.failures++
+	a.last = now
+
+	if a.failures >= max {
+		a.failures = 0
+		a.lockedUntil = now.Add(t.lockout)
+		return true
+	}
+	return false
+}
+
+// wait returns the remaining lockout or backoff of the attempts
+func (t *throttle) wait(a *attempts, now time.Time) time.Duration {
+	if a == nil {
+		return 0
+	}
+
+	if now.Before(a.lockedUntil) {
+		return a.lockedUntil.Sub(now)
+	}
+	if a.failures == 0 {
+		return 0
+	}
+
+	// The delay doubles with every failure, up to the lockout duration
+	delay := time.Duration(float64(t.backoff) * math.Pow(2, float64(a.failures-1)))
+	if delay > t.lockout || delay <= 0 {
+		delay = t.lockout
+	}
+
+	if next := a.last.Add(delay); now.Before(next) {
+		return next.Sub(now)
+	}
+	return 0
+}
+
+// prune forgets the attempts that are neither locked out nor recent enough
+// to matter
+func (t *throttle) prune(now time.Time) {
+	for _, m := range []map[string]*attempts{t.ips, t.usernames} {
+		for key, a := range m {
+			if now.After(a.lockedUntil) && now.Sub(a.last) > t.lockout {
+				delete(m, key)
+			}
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package authentication
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sensu/uchiwa/uchiwa/audit"
+	"github.com/sensu/uchiwa/uchiwa/structs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThrottle(t *testing.T) {
+	th := newThrottle(structs.Auth{LoginAttempts: 3, LoginAttemptsPerIP: 5, LoginBackoff: 1, LoginLockout: 10})
+	now := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	th.now = func() time.Time { return now }
+
+	assert.Equal(t, time.Duration(0), th.allow("alice", "10.0.0.1"))
+
+	// Exponential backoff
+	assert.False(t, th.fail("alice", "10.0.0.1"))
+	assert.Equal(t, time.Second, th.allow("alice", "10.0.0.1"))
+	assert.Equal(t, time.Second, th.allow("bob", "10.0.0.1"), "the IP address is throttled too")
+	assert.Equal(t, time.Second, th.allow("alice", "10.0.0.2"), "the username is throttled too")
+
+	now = now.Add(time.Second)
+	assert.Equal(t, time.Duration(0), th.allow("alice", "10.0.0.1"))
+	assert.False(t, th.fail("alice", "10.0.0.1"))
+	assert.Equal(t, 2*time.Second, th.allow("alice", "10.0.0.1"))
+
+	// Lockout of the username
+	now = now.Add(2 * time.Second)
+	assert.True(t, th.fail("alice", "10.0.0.1"))
+	assert.Equal(t, 10*time.Minute, th.allow("alice", "10.0.0.2"))
+
+	now = now.Add(10 * time.Minute)
+	assert.Equal(t, time.Duration(0), th.allow("alice", "10.0.0.2"))
+
+	// Lockout of the IP address across usernames
+	th.fail("bob", "10.0.0.1")
+	assert.True(t, th.fail("carol", "10.0.0.1"))
+	assert.Equal(t, 10*time.Minute, th.allow("dave", "10.0.0.1"))
+
+	// A successful login only resets the username
+	now = now.Add(time.Hour)
+	th.fail("alice", "10.0.0.3")
+	th.succeed("alice")
+	assert.Equal(t, time.Duration(0), th.allow("alice", "10.0.0.4"))
+	assert.Equal(t, time.Second, th.allow("alice", "10.0.0.3"))
+
+	// Old attempts are pruned
+	now = now.Add(time.Hour)
+	th.fail("erin", "10.0.0.5")
+	assert.Equal(t, 1, len(th.usernames))
+	assert.Equal(t, 1, len(th.ips))
+}
+
+func TestLoginThrottling(t *testing.T) {
+	var logs []structs.AuditLog
+	audit.Log = func(log structs.AuditLog) error {
+		logs = append(logs, log)
+		return nil
+	}
+	defer func() { audit.Log = audit.LogMock }()
+
+	a := New(structs.Auth{LoginAttempts: 2, LoginLockout: 1})
+	a.Simple([]User{{Username: "alice", Password: "secret"}})
+	now := time.Now()
+	a.throttle.now = func() time.Time { return now }
+
+	login := func(password string) *httptest.ResponseRecorder {
+		r, _ := http.NewRequest("POST", "/login", bytes.NewBufferString(`{"user":"alice","pass":"`+password+`"}`))
+		r.RemoteAddr = "10.0.0.1:5000"
+		w := httptest.NewRecorder()
+		a.Login().ServeHTTP(w, r)
+		return w
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, login("wrong").Code)
+	assert.Equal(t, http.StatusTooManyRequests, login("secret").Code)
+
+	now = now.Add(time.Second)
+	assert.Equal(t, http.StatusUnauthorized, login("wrong").Code)
+
+	w := login("secret")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+	assert.Equal(t, "loginlockout", logs[len(logs)-1].Action)
+
+	now = now.Add(time.Minute)
+	assert.Equal(t, http.StatusOK, login("secret").Code)
+}
+
+func TestLoginThrottlingForwardedFor(t *testing.T) {
+	a := New(structs.Auth{LoginAttempts: 10, LoginAttemptsPerIP: 2, LoginLockout: 1})
+	a.Simple([]User{{Username: "alice", Password: "secret"}})
+	now := time.Now()
+	a.throttle.now = func() time.Time { return now }
+
+	login := func(username, forwardedFor string) *httptest.ResponseRecorder {
+		r, _ := http.NewRequest("POST", "/login", bytes.NewBufferString(`{"user":"`+username+`","pass":"wrong"}`))
+		r.RemoteAddr = "10.0.0.1:5000"
+		r.Header.Set("X-Forwarded-For", forwardedFor)
+		w := httptest.NewRecorder()
+		a.Login().ServeHTTP(w, r)
+		return w
+	}
+
+	// Changing the X-Forwarded-For header does not reset the counter of the
+	// IP address
+	assert.Equal(t, http.StatusUnauthorized, login("bob", "192.168.0.1").Code)
+	now = now.Add(time.Second)
+	assert.Equal(t, http.StatusUnauthorized, login("carol", "192.168.0.2").Code)
+	assert.Equal(t, http.StatusTooManyRequests, login("dave", "192.168.0.3").Code)
+	assert.True(t, a.throttle.ips["10.0.0.1"].lockedUntil.After(now))
+	assert.Nil(t, a.throttle.ips["192.168.0.1"])
+}
+
+func TestLoginThrottlingTrustedProxy(t *testing.T) {
+	a := New(structs.Auth{LoginAttempts: 10, LoginAttemptsPerIP: 2, LoginLockout: 1, TrustedProxies: []string{"10.0.0.1"}})
+	a.Simple([]User{{Username: "alice", Password: "secret"}})
+	now := time.Now()
+	a.throttle.now = func() time.Time { return now }
+
+	login := func(username, password, forwardedFor string) int {
+		r, _ := http.NewRequest("POST", "/login", bytes.NewBufferString(`{"user":"`+username+`","pass":"`+password+`"}`))
+		r.RemoteAddr = "10.0.0.1:5000"
+		r.Header.Set("X-Forwarded-For", forwardedFor)
+		w := httptest.NewRecorder()
+		a.Login().ServeHTTP(w, r)
+		return w.Code
+	}
+
+	// The client locked out behind the proxy does not lock out the other
+	// clients of the proxy
+	assert.Equal(t, http.StatusUnauthorized, login("bob", "wrong", "192.168.0.1"))
+	now = now.Add(time.Second)
+	assert.Equal(t, http.StatusUnauthorized, login("carol", "wrong", "192.168.0.1"))
+	assert.Equal(t, http.StatusTooManyRequests, login("dave", "wrong", "192.168.0.1"))
+	assert.True(t, a.throttle.ips["192.168.0.1"].lockedUntil.After(now))
+	assert.Nil(t, a.throttle.ips["10.0.0.1"])
+
+	assert.Equal(t, http.StatusOK, login("alice", "secret", "192.168.0.2"))
+
+	// The addresses prepended by the client are ignored
+	assert.Equal(t, http.StatusTooManyRequests, login("alice", "secret", "192.168.0.2, 192.168.0.1"))
+}
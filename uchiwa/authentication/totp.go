@@ -0,0 +1,90 @@
+package authentication
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Parameters of the TOTP codes (RFC 6238), which are the defaults expected by
+// most authenticator applications
+const (
+	totpDigits = 6
+	totpIssuer = "Uchiwa"
+	totpPeriod = 30
+	totpSkew   = 1
+)
+
+// totpEncoding is the encoding of the TOTP secrets
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// newTOTPSecret returns a random base32-encoded 160-bit secret
+func newTOTPSecret() (string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(b), nil
+}
+
+// totpCode returns the code of the secret for the provided time step
+func totpCode(key []byte, step int64) string {
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+
+	// Dynamic truncation, see RFC 4226 section 5.3
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000)
+}
+
+// verifyTOTP verifies the code against the secret, allowing a clock skew of
+// one time step. Codes of time steps up to last were already used and are
+// rejected to prevent replays. Returns the time step of the code
+func verifyTOTP(secret, code string, now time.Time, last int64) (int64, bool) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return 0, false
+	}
+
+	code = strings.Replace(code, " ", "", -1)
+	if len(code) != totpDigits {
+		return 0, false
+	}
+
+	step := now.Unix() / totpPeriod
+	for i := step - totpSkew; i <= step+totpSkew; i++ {
+		if i <= last {
+			continue
+		}
+		if hmac.Equal([]byte(totpCode(key, i)), []byte(code)) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// totpURI returns the provisioning URI of the secret, usually displayed as a
+// QR code and scanned by the authenticator application
+func totpURI(username, secret string) string {
+	v := url.Values{}
+	v.Set("algorithm", "SHA1")
+	v.Set("digits", fmt.Sprintf("%d", totpDigits))
+	v.Set("issuer", totpIssuer)
+	v.Set("period", fmt.Sprintf("%d", totpPeriod))
+	v.Set("secret", secret)
+
+	label := url.PathEscape(totpIssuer + ":" + username)
+	return fmt.Sprintf("otpauth://totp/%s?%s", label, v.Encode())
+}
@@ -0,0 +1,369 @@
+package authentication
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Parameters of the second factor
+const (
+	challengeAttempts   = 5
+	challengeLifetime   = 5 * time.Minute
+	recoveryCodesNumber = 10
+)
+
+var errTwoFactorEnabled = errors.New("two-factor authentication is already enabled")
+
+// TwoFactor contains the TOTP enrolment of a user. Only the SHA-256 hashes of
+// the recovery codes are kept
+type TwoFactor struct {
+	Confirmed     bool     `json:"confirmed"`
+	LastStep      int64    `json:"laststep"`
+	RecoveryCodes []string `json:"recoverycodes"`
+	Secret        string   `json:"secret"`
+}
+
+// TwoFactorEnrolment contains what the user needs to configure the
+// authenticator application. The recovery codes can't be retrieved afterwards
+type TwoFactorEnrolment struct {
+	RecoveryCodes []string `json:"recoverycodes"`
+	Secret        string   `json:"secret"`
+	URI           string   `json:"uri"`
+}
+
+// twoFactorChallenge is returned by the /login endpoint when a second factor
+// is required
+type twoFactorChallenge struct {
+	Challenge string              `json:"challenge"`
+	Enrolment *TwoFactorEnrolment `json:"enrolment,omitempty"`
+}
+
+// TwoFactorStore persists the TOTP enrolments of the local users
+type TwoFactorStore interface {
+	// GetTwoFactor returns the enrolment of the user, or nil if the user is
+	// not enrolled
+	GetTwoFactor(username string) (*TwoFactor, error)
+	// SetTwoFactor replaces the enrolment of the user, or removes it if nil
+	SetTwoFactor(username string, tf *TwoFactor) error
+}
+
+// TwoFactorFile stores the TOTP enrolments in memory and, if a path is
+// provided, in a JSON file
+type TwoFactorFile struct {
+	Path string
+
+	mu         sync.Mutex
+	enrolments map[string]*TwoFactor
+}
+
+// NewTwoFactorFile returns a TOTP enrolments store, loading the enrolments
+// previously stored in the file if it exists
+func NewTwoFactorFile(path string) (*TwoFactorFile, error) {
+	s := &TwoFactorFile{Path: path, enrolments: make(map[string]*TwoFactor)}
+	if path == "" {
+		return s, nil
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("could not read the two-factor file: %s", err)
+	}
+
+	if err := json.Unmarshal(b, &s.enrolments); err != nil {
+		return nil, fmt.Errorf("could not parse the two-factor file: %s", err)
+	}
+
+	return s, nil
+}
+
+// GetTwoFactor returns the enrolment of the user, or nil if the user is not
+// enrolled
+func (s *TwoFactorFile) GetTwoFactor(username string) (*TwoFactor, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tf, ok := s.enrolments[username]
+	if !ok {
+		return nil, nil
+	}
+
+	t := *tf
+	t.RecoveryCodes = append([]string{}, tf.RecoveryCodes...)
+	return &t, nil
+}
+
+// SetTwoFactor replaces the enrolment of the user, or removes it if nil
+func (s *TwoFactorFile) SetTwoFactor(username string, tf *TwoFactor) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous, ok := s.enrolments[username]
+	if tf == nil {
+		delete(s.enrolments, username)
+	} else {
+		t := *tf
+		s.enrolments[username] = &t
+	}
+
+	if err := s.persist(); err != nil {
+		if ok {
+			s.enrolments[username] = previous
+		} else {
+			delete(s.enrolments, username)
+		}
+		return err
+	}
+	return nil
+}
+
+// persist writes the enrolments to the file, if any. The caller must hold the
+// lock
+func (s *TwoFactorFile) persist() error {
+	if s.Path == "" {
+		return nil
+	}
+
+	b, err := json.Marshal(s.enrolments)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so the file is never left truncated
+	tmp := s.Path + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0600); err != nil {
+		return fmt.Errorf("could not write the two-factor file: %s", err)
+	}
+	if err := os.Rename(tmp, s.Path); err != nil {
+		return fmt.Errorf("could not write the two-factor file: %s", err)
+	}
+	return nil
+}
+
+// challenge represents a login waiting for the second factor
+type challenge struct {
+	attempts int
+	enroll   bool
+	expires  time.Time
+	user     *User
+}
+
+// twoFactor verifies the second factor of the local users
+type twoFactor struct {
+	mu         sync.Mutex
+	challenges map[string]*challenge
+	now        func() time.Time
+	store      TwoFactorStore
+}
+
+func newTwoFactor(store TwoFactorStore) *twoFactor {
+	return &twoFactor{
+		challenges: make(map[string]*challenge),
+		now:        time.Now,
+		store:      store,
+	}
+}
+
+// challenge returns the ID of a challenge if the user must provide a second
+// factor, along with an enrolment if the role requires the second factor but
+// the user is not enrolled yet. An empty ID means that the password is enough
+func (t *twoFactor) challenge(user *User) (string, *TwoFactorEnrolment, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tf, err := t.store.GetTwoFactor(user.Username)
+	if err != nil {
+		return "", nil, err
+	}
+
+	enrolled := tf != nil && tf.Confirmed
+	if !enrolled && !user.Role.RequireTwoFactor {
+		return "", nil, nil
+	}
+
+	var enrolment *TwoFactorEnrolment
+	if !enrolled {
+		if enrolment, err = t.enroll(user.Username); err != nil {
+			return "", nil, err
+		}
+	}
+
+	id, err := RandomString(32)
+	if err != nil {
+		return "", nil, err
+	}
+
+	t.prune()
+	t.challenges[id] = &challenge{
+		enroll:  !enrolled,
+		expires: t.now().Add(challengeLifetime),
+		user:    user,
+	}
+	return id, enrolment, nil
+}
+
+// verify verifies the code provided for the challenge and returns the user
+// once the second factor is verified. The user is also returned alongside the
+// error if the challenge exists, so the failure can be attributed
+func (t *twoFactor) verify(id, code string) (*User, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	c, ok := t.challenges[id]
+	if !ok || t.now().After(c.expires) {
+		delete(t.challenges, id)
+		return nil, errors.New("invalid or expired two-factor challenge")
+	}
+
+	c.attempts++
+	if c.attempts >= challengeAttempts {
+		delete(t.challenges, id)
+	}
+
+	if c.enroll {
+		// The recovery codes can't be used to complete an enrolment
+		if err := t.confirm(c.user.Username, code); err != nil {
+			return c.user, err
+		}
+	} else if err := t.check(c.user.Username, code); err != nil {
+		return c.user, err
+	}
+
+	delete(t.challenges, id)
+	return c.user, nil
+}
+
+// enroll generates a new secret and new recovery codes for the user. The
+// enrolment must be confirmed with a valid code before being enforced. The
+// caller must hold the lock
+func (t *twoFactor) enroll(username string) (*TwoFactorEnrolment, error) {
+	tf, err := t.store.GetTwoFactor(username)
+	if err != nil {
+		return nil, err
+	}
+	if tf != nil && tf.Confirmed {
+		return nil, errTwoFactorEnabled
+	}
+
+	secret, err := newTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := t.store.SetTwoFactor(username, &TwoFactor{RecoveryCodes: hashes, Secret: secret}); err != nil {
+		return nil, err
+	}
+
+	return &TwoFactorEnrolment{RecoveryCodes: codes, Secret: secret, URI: totpURI(username, secret)}, nil
+}
+
+// confirm enables the pending enrolment of the user if the code is valid. The
+// caller must hold the lock
+func (t *twoFactor) confirm(username, code string) error {
+	tf, err := t.store.GetTwoFactor(username)
+	if err != nil {
+		return err
+	}
+	if tf == nil {
+		return errors.New("two-factor authentication is not enrolled")
+	}
+	if tf.Confirmed {
+		return errTwoFactorEnabled
+	}
+
+	step, ok := verifyTOTP(tf.Secret, code, t.now(), tf.LastStep)
+	if !ok {
+		return errors.New("invalid two-factor code")
+	}
+
+	tf.Confirmed = true
+	tf.LastStep = step
+	return t.store.SetTwoFactor(username, tf)
+}
+
+// check verifies the code, or the recovery code, of a confirmed enrolment.
+// Each code can only be used once. The caller must hold the lock
+func (t *twoFactor) check(username, code string) error {
+	tf, err := t.store.GetTwoFactor(username)
+	if err != nil {
+		return err
+	}
+	if tf == nil || !tf.Confirmed {
+		return errors.New("two-factor authentication is not enabled")
+	}
+
+	if step, ok := verifyTOTP(tf.Secret, code, t.now(), tf.LastStep); ok {
+		tf.LastStep = step
+		return t.store.SetTwoFactor(username, tf)
+	}
+
+	hash := hashSecret(normalizeRecoveryCode(code))
+	for i, h := range tf.RecoveryCodes {
+		if subtle.ConstantTimeCompare([]byte(h), []byte(hash)) == 1 {
+			tf.RecoveryCodes = append(tf.RecoveryCodes[:i], tf.RecoveryCodes[i+1:]...)
+			return t.store.SetTwoFactor(username, tf)
+		}
+	}
+
+	return errors.New("invalid two-factor code")
+}
+
+// disable removes the enrolment of the user once the code is verified
+func (t *twoFactor) disable(username, code string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err := t.check(username, code); err != nil {
+		return err
+	}
+	return t.store.SetTwoFactor(username, nil)
+}
+
+// prune removes the expired challenges. The caller must hold the lock
+func (t *twoFactor) prune() {
+	now := t.now()
+	for id, c := range t.challenges {
+		if now.After(c.expires) {
+			delete(t.challenges, id)
+		}
+	}
+}
+
+// newRecoveryCodes returns new recovery codes along with their hashes
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodesNumber)
+	hashes := make([]string, recoveryCodesNumber)
+
+	for i := range codes {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		code := strings.ToLower(base32.StdEncoding.EncodeToString(b))
+		codes[i] = code[:4] + "-" + code[4:]
+		hashes[i] = hashSecret(code)
+	}
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode removes the separators and the case of a recovery code
+func normalizeRecoveryCode(code string) string {
+	code = strings.Replace(code, "-", "", -1)
+	code = strings.Replace(code, " ", "", -1)
+	return strings.ToLower(code)
+}
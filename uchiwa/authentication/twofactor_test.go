@@ -0,0 +1,244 @@
+package authentication
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sensu/uchiwa/uchiwa/audit"
+	"github.com/sensu/uchiwa/uchiwa/structs"
+	"github.com/stretchr/testify/assert"
+)
+
+// codeAt returns the TOTP code of the secret at the provided time
+func codeAt(secret string, t time.Time) string {
+	key, _ := totpEncoding.DecodeString(secret)
+	return totpCode(key, t.Unix()/totpPeriod)
+}
+
+func TestVerifyTOTP(t *testing.T) {
+	// Test vector of RFC 6238, truncated to 6 digits
+	secret := totpEncoding.EncodeToString([]byte("12345678901234567890"))
+	assert.Equal(t, "287082", codeAt(secret, time.Unix(59, 0)))
+
+	now := time.Unix(1111111109, 0)
+	code := codeAt(secret, now)
+
+	step, ok := verifyTOTP(secret, code, now, 0)
+	assert.True(t, ok)
+	assert.Equal(t, now.Unix()/totpPeriod, step)
+
+	// Clock skew of one time step
+	_, ok = verifyTOTP(secret, code, now.Add(totpPeriod*time.Second), 0)
+	assert.True(t, ok)
+	_, ok = verifyTOTP(secret, code, now.Add(2*totpPeriod*time.Second), 0)
+	assert.False(t, ok)
+
+	// Replayed code
+	_, ok = verifyTOTP(secret, code, now, step)
+	assert.False(t, ok)
+
+	_, ok = verifyTOTP(secret, "000000", now, 0)
+	assert.False(t, ok)
+	_, ok = verifyTOTP("invalid secret", code, now, 0)
+	assert.False(t, ok)
+
+	uri := totpURI("alice", secret)
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/Uchiwa:alice?"))
+	assert.Contains(t, uri, "secret="+secret)
+}
+
+func TestTwoFactorFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "twofactor")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "twofactor.json")
+
+	s, err := NewTwoFactorFile(path)
+	assert.Nil(t, err)
+
+	tf, err := s.GetTwoFactor("alice")
+	assert.Nil(t, err)
+	assert.Nil(t, tf)
+
+	assert.Nil(t, s.SetTwoFactor("alice", &TwoFactor{Confirmed: true, RecoveryCodes: []string{"foo"}, Secret: "bar"}))
+
+	// The enrolments are reloaded from the file
+	s, err = NewTwoFactorFile(path)
+	assert.Nil(t, err)
+	tf, err = s.GetTwoFactor("alice")
+	assert.Nil(t, err)
+	assert.Equal(t, &TwoFactor{Confirmed: true, RecoveryCodes: []string{"foo"}, Secret: "bar"}, tf)
+
+	assert.Nil(t, s.SetTwoFactor("alice", nil))
+	tf, _ = s.GetTwoFactor("alice")
+	assert.Nil(t, tf)
+}
+
+func TestLoginTwoFactor(t *testing.T) {
+	audit.Log = audit.LogMock
+
+	a := New(structs.Auth{})
+	a.Simple([]User{
+		{Username: "alice", Password: "secret", Role: Role{Name: "operators", RequireTwoFactor: true}},
+		{Username: "bob", Password: "secret"},
+	})
+	s, _ := NewTwoFactorFile("")
+	a.EnableTwoFactor(s)
+	now := time.Now()
+	a.twoFactor.now = func() time.Time { return now }
+
+	// The throttling of the failed attempts is covered by TestLoginThrottling
+	a.throttle = nil
+
+	login := func(user string) *httptest.ResponseRecorder {
+		r, _ := http.NewRequest("POST", "/login", bytes.NewBufferString(`{"user":"`+user+`","pass":"secret"}`))
+		w := httptest.NewRecorder()
+		a.Login().ServeHTTP(w, r)
+		return w
+	}
+	verify := func(challenge, code string) *httptest.ResponseRecorder {
+		b, _ := json.Marshal(map[string]string{"challenge": challenge, "code": code})
+		r, _ := http.NewRequest("POST", "/login/2fa", bytes.NewBuffer(b))
+		w := httptest.NewRecorder()
+		a.LoginTwoFactor().ServeHTTP(w, r)
+		return w
+	}
+
+	// Users that are not enrolled and whose role does not require a second
+	// factor only need their password
+	w := login("bob")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var user User
+	assert.Nil(t, json.NewDecoder(w.Body).Decode(&user))
+	assert.NotEmpty(t, user.Token)
+
+	// The role of alice requires a second factor, which must be enrolled first
+	w = login("alice")
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	var c twoFactorChallenge
+	assert.Nil(t, json.NewDecoder(w.Body).Decode(&c))
+	assert.NotEmpty(t, c.Challenge)
+	assert.NotNil(t, c.Enrolment)
+	assert.Equal(t, recoveryCodesNumber, len(c.Enrolment.RecoveryCodes))
+	secret := c.Enrolment.Secret
+
+	// The recovery codes can't complete the enrolment
+	assert.Equal(t, http.StatusUnauthorized, verify(c.Challenge, c.Enrolment.RecoveryCodes[0]).Code)
+
+	w = verify(c.Challenge, codeAt(secret, now))
+	assert.Equal(t, http.StatusOK, w.Code)
+	user = User{}
+	assert.Nil(t, json.NewDecoder(w.Body).Decode(&user))
+	assert.Equal(t, "alice", user.Username)
+	assert.NotEmpty(t, user.Token)
+
+	// The challenge can only be used once
+	assert.Equal(t, http.StatusUnauthorized, verify(c.Challenge, codeAt(secret, now)).Code)
+
+	// Once enrolled, a code is required without any new enrolment
+	w = login("alice")
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	c2 := twoFactorChallenge{}
+	assert.Nil(t, json.NewDecoder(w.Body).Decode(&c2))
+	assert.Nil(t, c2.Enrolment)
+
+	// The code was already used
+	assert.Equal(t, http.StatusUnauthorized, verify(c2.Challenge, codeAt(secret, now)).Code)
+
+	// A recovery code can only be used once
+	recovery := strings.ToUpper(c.Enrolment.RecoveryCodes[0])
+	assert.Equal(t, http.StatusOK, verify(c2.Challenge, recovery).Code)
+
+	w = login("alice")
+	c3 := twoFactorChallenge{}
+	assert.Nil(t, json.NewDecoder(w.Body).Decode(&c3))
+	assert.Equal(t, http.StatusUnauthorized, verify(c3.Challenge, recovery).Code)
+
+	// The challenge is dropped after too many attempts
+	for i := 1; i < challengeAttempts; i++ {
+		verify(c3.Challenge, "000000")
+	}
+	now = now.Add(totpPeriod * time.Second)
+	assert.Equal(t, http.StatusUnauthorized, verify(c3.Challenge, codeAt(secret, now)).Code)
+
+	// The challenge expires
+	w = login("alice")
+	c4 := twoFactorChallenge{}
+	assert.Nil(t, json.NewDecoder(w.Body).Decode(&c4))
+	now = now.Add(challengeLifetime + time.Second)
+	assert.Equal(t, http.StatusUnauthorized, verify(c4.Challenge, codeAt(secret, now)).Code)
+}
+
+func TestTwoFactorHandlers(t *testing.T) {
+	audit.Log = audit.LogMock
+
+	a := New(structs.Auth{})
+	s, _ := NewTwoFactorFile("")
+	a.EnableTwoFactor(s)
+	now := time.Now()
+	a.twoFactor.now = func() time.Time { return now }
+
+	bob := map[string]interface{}{"Username": "bob", "Role": Role{Name: "operators"}}
+	status := func() map[string]interface{} {
+		w := httptest.NewRecorder()
+		a.twoFactorHandler(w, tokensRequest("GET", "/twofactor", nil, bob))
+		var status map[string]interface{}
+		json.NewDecoder(w.Body).Decode(&status)
+		return status
+	}
+
+	assert.Equal(t, false, status()["enabled"])
+
+	w := httptest.NewRecorder()
+	a.twoFactorHandler(w, tokensRequest("POST", "/twofactor", nil, bob))
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var enrolment TwoFactorEnrolment
+	assert.Nil(t, json.NewDecoder(w.Body).Decode(&enrolment))
+	assert.True(t, strings.HasPrefix(enrolment.URI, "otpauth://totp/Uchiwa:bob?"))
+
+	// The enrolment is only enabled once confirmed
+	assert.Equal(t, false, status()["enabled"])
+
+	w = httptest.NewRecorder()
+	a.twoFactorConfirmHandler(w, tokensRequest("POST", "/twofactor/confirm", map[string]string{"code": "000000"}, bob))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	a.twoFactorConfirmHandler(w, tokensRequest("POST", "/twofactor/confirm", map[string]string{"code": codeAt(enrolment.Secret, now)}, bob))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, true, status()["enabled"])
+	assert.Equal(t, float64(recoveryCodesNumber), status()["recoverycodes"])
+
+	w = httptest.NewRecorder()
+	a.twoFactorHandler(w, tokensRequest("POST", "/twofactor", nil, bob))
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// Disabling requires a valid code, and is forbidden by roles requiring a
+	// second factor
+	required := map[string]interface{}{"Username": "bob", "Role": Role{Name: "operators", RequireTwoFactor: true}}
+	w = httptest.NewRecorder()
+	a.twoFactorHandler(w, tokensRequest("DELETE", "/twofactor", map[string]string{"code": enrolment.RecoveryCodes[0]}, required))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = httptest.NewRecorder()
+	a.twoFactorHandler(w, tokensRequest("DELETE", "/twofactor", map[string]string{"code": "000000"}, bob))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	a.twoFactorHandler(w, tokensRequest("DELETE", "/twofactor", map[string]string{"code": enrolment.RecoveryCodes[0]}, bob))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, false, status()["enabled"])
+
+	// Unauthenticated requests
+	w = httptest.NewRecorder()
+	a.twoFactorHandler(w, tokensRequest("GET", "/twofactor", nil, nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
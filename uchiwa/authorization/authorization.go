@@ -1,7 +1,9 @@
 package authorization
 
 import (
+	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/sensu/uchiwa/uchiwa/authentication"
 	"github.com/sensu/uchiwa/uchiwa/logger"
@@ -22,10 +24,18 @@ func (u *Uchiwa) Handler(next http.Handler) http.Handler {
 		readonly := isReadOnly(r)
 		authorized := isAuthorized(readonly, r.Method)
 		if !authorized {
-			http.Error(w, "Request forbidden", http.StatusForbidden)
+			http.Error(w, "Request forbidden: the role has a read-only access", http.StatusForbidden)
 			return
 		}
 
+		if role := getRole(r); role != nil {
+			if err := isEndpointAllowed(role, r.Method, r.URL.Path); err != nil {
+				logger.Debugf("Request forbidden: %s", err)
+				http.Error(w, fmt.Sprintf("Request forbidden: %s", err), http.StatusForbidden)
+				return
+			}
+		}
+
 		next.ServeHTTP(w, r)
 	})
 }
@@ -37,6 +47,52 @@ func isAuthorized(isReadOnly bool, method string) bool {
 	return true
 }
 
+// isEndpointAllowed verifies that the endpoint is part of the allow-list of
+// the role for the HTTP method. An empty allow-list does not restrict the
+// method, while an entry allows the endpoint itself and its sub-resources,
+// e.g. /events/ allows DELETE /events/:client/:check
+func isEndpointAllowed(role *authentication.Role, method, path string) error {
+	var endpoints []string
+	switch method {
+	case http.MethodDelete:
+		endpoints = role.Methods.Delete
+	case http.MethodGet:
+		endpoints = role.Methods.Get
+	case http.MethodHead:
+		endpoints = role.Methods.Head
+	case http.MethodPost:
+		endpoints = role.Methods.Post
+	}
+
+	if len(endpoints) == 0 {
+		return nil
+	}
+
+	for _, endpoint := range endpoints {
+		if endpoint == "*" || path == endpoint || strings.HasPrefix(path, strings.TrimSuffix(endpoint, "/")+"/") {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("the role '%s' is not allowed to %s %s", role.Name, method, path)
+}
+
+// getRole returns the role of the authenticated user, or nil if
+// authentication is not enabled
+func getRole(r *http.Request) *authentication.Role {
+	token := authentication.GetJWTFromContext(r)
+	if token == nil {
+		return nil
+	}
+
+	role, err := authentication.GetRoleFromToken(token)
+	if err != nil {
+		return nil
+	}
+
+	return role
+}
+
 // hasReadOnly verifies if the user only has read-only access.
 // Returns true if the user only have read-only access
 func isReadOnly(r *http.Request) bool {
@@ -50,7 +106,7 @@ func isReadOnly(r *http.Request) bool {
 
 	role, err := authentication.GetRoleFromToken(token)
 	if err != nil {
-		logger.Debug("Invalid token: %s", err)
+		logger.Debugf("Invalid token: %s", err)
 		return true
 	}
 
@@ -99,3 +99,61 @@ func TestIsReadOnly(t *testing.T) {
 	readonly = isReadOnly(r)
 	assert.False(t, readonly)
 }
+
+func TestHandlerMethods(t *testing.T) {
+	role := authentication.Role{
+		Name:    "oncall",
+		Methods: authentication.Methods{Delete: []string{"/events/"}, Post: []string{"*"}},
+	}
+
+	handler := u.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	r, _ := http.NewRequest("DELETE", "/events/foo/bar", nil)
+	setJWTInContext(r, generateToken(role))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	r, _ = http.NewRequest("DELETE", "/clients/foo", nil)
+	setJWTInContext(r, generateToken(role))
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "the role 'oncall' is not allowed to DELETE /clients/foo")
+
+	// The read-only attribute takes precedence over the allow-lists
+	role.Readonly = true
+	r, _ = http.NewRequest("POST", "/silenced", nil)
+	setJWTInContext(r, generateToken(role))
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "read-only")
+}
+
+func TestIsEndpointAllowed(t *testing.T) {
+	role := &authentication.Role{
+		Methods: authentication.Methods{
+			Delete: []string{"/events/", "/stashes"},
+			Get:    []string{"/checks"},
+			Head:   []string{"*"},
+		},
+	}
+
+	// No allow-list for the method
+	assert.Nil(t, isEndpointAllowed(role, "POST", "/silenced"))
+
+	// Sub-resources
+	assert.Nil(t, isEndpointAllowed(role, "DELETE", "/events/foo/bar"))
+	assert.Nil(t, isEndpointAllowed(role, "DELETE", "/stashes/foo"))
+	assert.NotNil(t, isEndpointAllowed(role, "DELETE", "/events"))
+	assert.NotNil(t, isEndpointAllowed(role, "DELETE", "/clients/foo"))
+
+	// Exact match
+	assert.Nil(t, isEndpointAllowed(role, "GET", "/checks"))
+	assert.NotNil(t, isEndpointAllowed(role, "GET", "/checksfoo"))
+	assert.NotNil(t, isEndpointAllowed(role, "GET", "/clients"))
+
+	// Wildcard
+	assert.Nil(t, isEndpointAllowed(role, "HEAD", "/clients"))
+}
@@ -1,21 +1,23 @@
 package uchiwa
 
 import (
+	"context"
 	"fmt"
 
+	"github.com/dgrijalva/jwt-go"
 	"github.com/sensu/uchiwa/uchiwa/logger"
 	"github.com/sensu/uchiwa/uchiwa/structs"
 )
 
 // IssueCheckExecution sends a POST request to the /stashes endpoint in order to create a stash
-func (u *Uchiwa) IssueCheckExecution(data structs.CheckExecution) error {
+func (u *Uchiwa) IssueCheckExecution(ctx context.Context, data structs.CheckExecution) error {
 	api, err := getAPI(u.Datacenters, data.Dc)
 	if err != nil {
 		logger.Warning(err)
 		return err
 	}
 
-	_, err = api.IssueCheckExecution(data)
+	_, err = api.IssueCheckExecution(ctx, data)
 	if err != nil {
 		logger.Warning(err)
 		return err
@@ -24,16 +26,11 @@ func (u *Uchiwa) IssueCheckExecution(data structs.CheckExecution) error {
 	return nil
 }
 
-func (u *Uchiwa) findCheck(name string) ([]interface{}, error) {
-	var checks []interface{}
-	for _, c := range u.Data.Checks {
-		m, ok := c.(map[string]interface{})
-		if !ok {
-			logger.Warningf("Could not assert this check to an interface %+v", c)
-			continue
-		}
-		if m["name"] == name {
-			checks = append(checks, m)
+func (u *Uchiwa) findCheck(name string) ([]*structs.Check, error) {
+	var checks []*structs.Check
+	for _, c := range u.getData().Checks {
+		if c.Name == name {
+			checks = append(checks, c)
 		}
 	}
 
@@ -43,3 +40,31 @@ func (u *Uchiwa) findCheck(name string) ([]interface{}, error) {
 
 	return checks, nil
 }
+
+// isCheckRequestAllowed verifies that the check and the requested subscribers
+// are visible to the user, according to the filters. An unknown check is
+// treated as a check without subscribers, which is only visible to the roles
+// without restriction on the subscriptions
+func (u *Uchiwa) isCheckRequestAllowed(data structs.CheckExecution, token *jwt.Token) bool {
+	unknown := &structs.Check{Name: data.Check, Dc: data.Dc}
+	check := findModel(data.Check, data.Dc, u.getData().Checks)
+	if check == nil {
+		check = unknown
+	}
+	if len(Filters.Checks([]*structs.Check{check}, token)) == 0 {
+		return false
+	}
+
+	// Without subscribers, the check is executed on every subscriber of the
+	// check, including those that may not be visible
+	if len(data.Subscribers) == 0 {
+		return len(Filters.Checks([]*structs.Check{unknown}, token)) == 1
+	}
+
+	subscribers := make([]structs.Subscription, len(data.Subscribers))
+	for i, subscriber := range data.Subscribers {
+		subscribers[i] = structs.Subscription{Dc: data.Dc, Name: subscriber}
+	}
+
+	return len(Filters.Subscriptions(&subscribers, token)) == len(subscribers)
+}
@@ -3,20 +3,20 @@ package uchiwa
 import (
 	"testing"
 
+	"github.com/dgrijalva/jwt-go"
+	"github.com/sensu/uchiwa/uchiwa/authentication"
+	"github.com/sensu/uchiwa/uchiwa/filters"
 	"github.com/sensu/uchiwa/uchiwa/structs"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestFindCheck(t *testing.T) {
-	u := Uchiwa{
-		Data: &structs.Data{},
-	}
-
-	u.Data.Checks = []interface{}{
-		map[string]interface{}{"name": "foo", "dc": "us-east-1"},
-		map[string]interface{}{"name": "bar", "dc": "us-east-1"},
-		map[string]interface{}{"name": "foo", "dc": "us-west-1"},
-	}
+	u := Uchiwa{}
+	u.setData(&structs.Data{Checks: []*structs.Check{
+		{Name: "foo", Dc: "us-east-1"},
+		{Name: "bar", Dc: "us-east-1"},
+		{Name: "foo", Dc: "us-west-1"},
+	}})
 
 	checks, err := u.findCheck("foo")
 	assert.Nil(t, err)
@@ -29,3 +29,30 @@ func TestFindCheck(t *testing.T) {
 	_, err = u.findCheck("qux")
 	assert.NotNil(t, err)
 }
+
+func TestIsCheckRequestAllowed(t *testing.T) {
+	Filters = &filters.Uchiwa{}
+	u := Uchiwa{}
+	u.setData(&structs.Data{Checks: []*structs.Check{
+		{Name: "foo", Dc: "us-east-1", Subscribers: []string{"linux"}},
+		{Name: "bar", Dc: "us-east-1", Subscribers: []string{"windows"}},
+	}})
+
+	token := jwt.New(jwt.GetSigningMethod("RS256"))
+	token.Claims["Role"] = authentication.Role{Subscriptions: []string{"linux"}}
+
+	// Authentication disabled
+	assert.True(t, u.isCheckRequestAllowed(structs.CheckExecution{Check: "bar", Dc: "us-east-1"}, nil))
+
+	// Unknown check, without restriction on the subscriptions
+	datacenters := jwt.New(jwt.GetSigningMethod("RS256"))
+	datacenters.Claims["Role"] = authentication.Role{Datacenters: []string{"us-east-1"}}
+	assert.True(t, u.isCheckRequestAllowed(structs.CheckExecution{Check: "qux", Dc: "us-east-1"}, datacenters))
+	assert.True(t, u.isCheckRequestAllowed(structs.CheckExecution{Check: "foo", Dc: "us-east-1", Subscribers: []string{"linux"}}, token))
+	assert.False(t, u.isCheckRequestAllowed(structs.CheckExecution{Check: "bar", Dc: "us-east-1"}, token))
+	assert.False(t, u.isCheckRequestAllowed(structs.CheckExecution{Check: "foo", Dc: "us-east-1", Subscribers: []string{"linux", "windows"}}, token))
+
+	// Unknown checks and executions on every subscriber of the check
+	assert.False(t, u.isCheckRequestAllowed(structs.CheckExecution{Check: "foo", Dc: "us-east-1"}, token))
+	assert.False(t, u.isCheckRequestAllowed(structs.CheckExecution{Check: "qux", Dc: "us-east-1", Subscribers: []string{"linux"}}, token))
+}
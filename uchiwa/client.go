@@ -1,44 +1,40 @@
 package uchiwa
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/sensu/uchiwa/uchiwa/helpers"
 	"github.com/sensu/uchiwa/uchiwa/logger"
+	"github.com/sensu/uchiwa/uchiwa/structs"
 )
 
-func (u *Uchiwa) buildClientHistory(client, dc string, history []interface{}) []interface{} {
+func (u *Uchiwa) buildClientHistory(client, dc string, history []*structs.ClientHistory) []*structs.ClientHistory {
+	silenced := u.getData().Silenced
 	for _, h := range history {
-		m, ok := h.(map[string]interface{})
-		if !ok {
-			logger.Warningf("Could not assert this client history to an interface: %+v", h)
-			continue
-		}
+		h.Client = client
+		h.Dc = dc
 
-		m["client"] = client
-		m["dc"] = dc
-
-		check, ok := m["last_result"].(map[string]interface{})
-		if !ok {
-			logger.Warningf("Could not assert this check to a struct: %+v", m["last_result"])
+		if h.LastResult == nil {
+			logger.Warningf("The client history has no last result: %+v", h)
 			continue
 		}
 
-		m["silenced"], m["silenced_by"] = helpers.IsCheckSilenced(check, client, dc, u.Data.Silenced)
+		h.Silenced, h.SilencedBy = helpers.IsCheckSilenced(h.LastResult, client, dc, silenced)
 	}
 
 	return history
 }
 
 // DeleteClient send a DELETE request to the /clients/*client* endpoint in order to delete a client
-func (u *Uchiwa) DeleteClient(dc, name string) error {
+func (u *Uchiwa) DeleteClient(ctx context.Context, dc, name string) error {
 	api, err := getAPI(u.Datacenters, dc)
 	if err != nil {
 		logger.Warning(err)
 		return err
 	}
 
-	err = api.DeleteClient(name)
+	err = api.DeleteClient(ctx, name)
 	if err != nil {
 		logger.Warning(err)
 		return err
@@ -47,16 +43,11 @@ func (u *Uchiwa) DeleteClient(dc, name string) error {
 	return nil
 }
 
-func (u *Uchiwa) findClient(name string) ([]interface{}, error) {
-	var clients []interface{}
-	for _, c := range u.Data.Clients {
-		m, ok := c.(map[string]interface{})
-		if !ok {
-			logger.Warningf("Could not assert this client to an interface %+v", c)
-			continue
-		}
-		if m["name"] == name {
-			clients = append(clients, m)
+func (u *Uchiwa) findClient(name string) ([]*structs.Client, error) {
+	var clients []*structs.Client
+	for _, c := range u.getData().Clients {
+		if c.Name == name {
+			clients = append(clients, c)
 		}
 	}
 
@@ -67,91 +58,62 @@ func (u *Uchiwa) findClient(name string) ([]interface{}, error) {
 	return clients, nil
 }
 
-func (u *Uchiwa) findOutput(id *string, h map[string]interface{}, dc *string) string {
-	if h["last_status"] == 0 {
-		return ""
-	}
-
-	for _, e := range u.Data.Events {
-		// does the dc match?
-		m, ok := e.(map[string]interface{})
-		if !ok {
-			logger.Warningf("Could not assert this event to an interface %+v", e)
-			continue
-		}
-		if m["dc"] != *dc {
-			continue
+// clientSubscriptions returns the subscriptions of the client in the
+// datacenter, or nil if the client is unknown
+func (u *Uchiwa) clientSubscriptions(name, dc string) []string {
+	for _, c := range u.getData().Clients {
+		if c.Name == name && c.Dc == dc {
+			return c.Subscriptions
 		}
-
-		// does the client match?
-		c, ok := m["client"].(map[string]interface{})
-		if !ok {
-			logger.Warningf("Could not assert this client to an interface: %+v", c)
-			continue
-		}
-
-		if c["name"] != *id {
-			continue
-		}
-
-		// does the check match?
-		k := m["check"].(map[string]interface{})
-		if !ok {
-			logger.Warningf("Could not assert this check to an interface: %+v", k)
-			continue
-		}
-		if k["name"] != h["check"] {
-			continue
-		}
-		return k["output"].(string)
 	}
-
-	return ""
+	return nil
 }
 
 // GetClient retrieves a specific client
-func (u *Uchiwa) GetClient(dc, name string) (map[string]interface{}, error) {
+func (u *Uchiwa) GetClient(ctx context.Context, dc, name string) (*structs.Client, error) {
 	api, err := getAPI(u.Datacenters, dc)
 	if err != nil {
 		logger.Warning(err)
 		return nil, err
 	}
 
-	client, err := api.GetClient(name)
+	client, err := api.GetClient(ctx, name)
 	if err != nil {
 		logger.Warning(err)
 		return nil, err
 	}
 
-	// lock results
-	u.Mu.Lock()
-	defer u.Mu.Unlock()
-
-	client["_id"] = fmt.Sprintf("%s/%s", dc, name)
-	client["dc"] = dc
-	client["silenced"] = helpers.IsClientSilenced(name, dc, u.Data.Silenced)
+	snapshot := u.getData()
+	client.UID = fmt.Sprintf("%s/%s", dc, name)
+	client.Dc = dc
+	client.Silenced = helpers.IsClientSilenced(name, dc, snapshot.Silenced)
+
+	// The status and the output are determined from the events by the daemon
+	for _, c := range snapshot.Clients {
+		if c.Dc == dc && c.Name == name {
+			client.Output = c.Output
+			client.Status = c.Status
+			break
+		}
+	}
 
 	return client, nil
 }
 
 // GetClientHistory retrieves a specific client history
-func (u *Uchiwa) GetClientHistory(dc, name string) ([]interface{}, error) {
+func (u *Uchiwa) GetClientHistory(ctx context.Context, dc, name string) ([]*structs.ClientHistory, error) {
 	api, err := getAPI(u.Datacenters, dc)
 	if err != nil {
 		logger.Warning(err)
 		return nil, err
 	}
 
-	h, err := api.GetClientHistory(name)
+	h, err := api.GetClientHistory(ctx, name)
 	if err != nil {
 		logger.Warning(err)
 		return nil, err
 	}
 
-	// lock results
-	u.Mu.Lock()
-	defer u.Mu.Unlock()
-
 	history := u.buildClientHistory(name, dc, h)
 
 	return history, nil
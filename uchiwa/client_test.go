@@ -8,43 +8,34 @@ import (
 )
 
 func TestBuildClientHistory(t *testing.T) {
-	var client, dc string
-	var status float64
-	const Critical float64 = 2.0
-	const Warning float64 = 1.0
-	const Success float64 = 0.0
-	var history = []interface{}{}
-	var expectedHistory = []interface{}{}
-
-	u := Uchiwa{
-		Data: &structs.Data{},
+	u := Uchiwa{}
+	u.setData(&structs.Data{Silenced: []*structs.Silence{
+		{Dc: "us-east-1", ID: "*:cpu"},
+		{Dc: "us-west-1", ID: "*:disk"},
+	}})
+
+	history := []*structs.ClientHistory{
+		{Check: "cpu", LastResult: &structs.Check{Name: "cpu", Status: 2}, LastStatus: 2},
+		{Check: "disk", LastResult: &structs.Check{Name: "disk"}},
+		{Check: "ram"},
 	}
-	u.Data.Events = []interface{}{
-		map[string]interface{}{"action": "create", "check": map[string]interface{}{"name": "cpu", "command": "cpu.rb", "status": Critical}, "client": map[string]interface{}{"name": "foo"}, "dc": "us-east-1", "occurrences": 7},
-		map[string]interface{}{"check": map[string]interface{}{"name": "cpu", "command": "cpu.rb", "status": Warning}, "client": map[string]interface{}{"name": "bar"}, "dc": "us-east-1", "occurrences": 5},
-		map[string]interface{}{"check": "cpu", "client": "qux", "dc": "us-west-1", "occurrences": 10, "output": "CRITICAL", "status": Critical},
+	expectedHistory := []*structs.ClientHistory{
+		{Check: "cpu", Client: "qux", Dc: "us-east-1", LastResult: &structs.Check{Name: "cpu", Status: 2}, LastStatus: 2, Silenced: true, SilencedBy: []string{"*:cpu"}},
+		{Check: "disk", Client: "qux", Dc: "us-east-1", LastResult: &structs.Check{Name: "disk"}},
+		{Check: "ram", Client: "qux", Dc: "us-east-1"},
 	}
 
-	client = "qux"
-	dc = "us-east-1"
-	status = Success
-	history = []interface{}{map[string]interface{}{"check": "cpu", "last_result": map[string]interface{}{"command": "cpu.rb", "status": status}, "last_status": status}}
-	expectedHistory = []interface{}{map[string]interface{}{"check": "cpu", "client": "qux", "dc": "us-east-1", "last_result": map[string]interface{}{"command": "cpu.rb", "status": status}, "last_status": status, "silenced": false, "silenced_by": []string(nil)}}
-	result := u.buildClientHistory(client, dc, history)
+	result := u.buildClientHistory("qux", "us-east-1", history)
 	assert.Equal(t, expectedHistory, result)
-
 }
 
 func TestFindClient(t *testing.T) {
-	u := Uchiwa{
-		Data: &structs.Data{},
-	}
-
-	u.Data.Clients = []interface{}{
-		map[string]interface{}{"name": "foo", "dc": "us-east-1"},
-		map[string]interface{}{"name": "bar", "dc": "us-east-1"},
-		map[string]interface{}{"name": "foo", "dc": "us-west-1"},
-	}
+	u := Uchiwa{}
+	u.setData(&structs.Data{Clients: []*structs.Client{
+		{Name: "foo", Dc: "us-east-1"},
+		{Name: "bar", Dc: "us-east-1"},
+		{Name: "foo", Dc: "us-west-1"},
+	}})
 
 	clients, err := u.findClient("foo")
 	assert.Nil(t, err)
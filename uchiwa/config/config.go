@@ -2,23 +2,29 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math/rand"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/palourde/mergo"
 	"github.com/sensu/uchiwa/uchiwa/authentication"
+	"github.com/sensu/uchiwa/uchiwa/helpers"
 	"github.com/sensu/uchiwa/uchiwa/logger"
+	"github.com/sensu/uchiwa/uchiwa/sensu"
 )
 
 var (
 	defaultGlobalConfig = GlobalConfig{
-		Host:     "0.0.0.0",
-		Port:     3000,
-		LogLevel: "info",
-		Refresh:  10,
+		Host:        "0.0.0.0",
+		Port:        3000,
+		LogLevel:    "info",
+		Refresh:     10,
+		Concurrency: 4,
+		StaleLimit:  300,
 		Ldap: Ldap{
 			Port:                 389,
 			Security:             "none",
@@ -27,9 +33,23 @@ var (
 			GroupMemberAttribute: "member",
 			GroupObjectClass:     "groupOfNames",
 		},
+		Oidc: Oidc{
+			GroupsClaim:   "groups",
+			Scopes:        []string{"openid", "profile", "email"},
+			UsernameClaim: "preferred_username",
+		},
+		Proxy: Proxy{
+			GroupsHeader:    "X-Remote-Groups",
+			GroupsSeparator: ",",
+			UserHeader:      "X-Remote-User",
+		},
 		Audit: Audit{
-			Level:   "default",
-			Logfile: "/var/log/sensu/sensu-enterprise-dashboard-audit.log",
+			Level:        "default",
+			Logfile:      "/var/log/sensu/sensu-enterprise-dashboard-audit.log",
+			MaxAge:       24,
+			MaxBackupAge: 90,
+			MaxBackups:   30,
+			MaxSize:      100,
 		},
 		UsersOptions: UsersOptions{
 			DateFormat:             "YYYY-MM-DD HH:mm:ss",
@@ -177,6 +197,29 @@ func initSensu(apis []SensuConfig) []SensuConfig {
 			logger.Fatalf("Sensu API %q Host is missing", api.Name)
 		}
 
+		// The bearer token and the basic auth share the Authorization header
+		if api.Token != "" && (api.User != "" || api.Pass != "") {
+			logger.Warningf("Sensu API %q has both a token and a user, only the token will be used", api.Name)
+		}
+
+		// Make sure the strategy is supported
+		if !sensu.IsStrategy(api.Strategy) {
+			logger.Fatalf("Sensu API %q has an unknown strategy %q", api.Name, api.Strategy)
+		}
+
+		// Index the retry policies by HTTP method in uppercase
+		if len(api.Retries) != 0 {
+			retries := make(map[string]sensu.RetryPolicy, len(api.Retries))
+			for method, policy := range api.Retries {
+				method = strings.ToUpper(method)
+				if _, ok := sensu.DefaultRetryPolicies[method]; !ok {
+					logger.Fatalf("Sensu API %q has a retry policy for the unsupported method %q", api.Name, method)
+				}
+				retries[method] = policy
+			}
+			apis[i].Retries = retries
+		}
+
 		// Determine the protocol to use
 		prot := "http"
 		if api.Ssl {
@@ -196,13 +239,19 @@ func initUchiwa(global GlobalConfig) GlobalConfig {
 		global.Auth.Driver = "github"
 
 		for i := range global.Github.Roles {
-			authentication.Roles = append(authentication.Roles, global.Github.Roles[i])
+			authentication.AddRoles(global.Github.Roles[i])
 		}
 	} else if global.Gitlab.Server != "" {
 		global.Auth.Driver = "gitlab"
 
 		for i := range global.Gitlab.Roles {
-			authentication.Roles = append(authentication.Roles, global.Gitlab.Roles[i])
+			authentication.AddRoles(global.Gitlab.Roles[i])
+		}
+	} else if global.Oidc.Issuer != "" {
+		global.Auth.Driver = "oidc"
+
+		for i := range global.Oidc.Roles {
+			authentication.AddRoles(global.Oidc.Roles[i])
 		}
 	} else if global.Ldap.Server != "" {
 		global.Auth.Driver = "ldap"
@@ -214,7 +263,22 @@ func initUchiwa(global GlobalConfig) GlobalConfig {
 		}
 
 		for i := range global.Ldap.Roles {
-			authentication.Roles = append(authentication.Roles, global.Ldap.Roles[i])
+			authentication.AddRoles(global.Ldap.Roles[i])
+		}
+	} else if len(global.Proxy.TrustedProxies) != 0 {
+		global.Auth.Driver = "proxy"
+
+		for i := range global.Proxy.Roles {
+			authentication.AddRoles(global.Proxy.Roles[i])
+		}
+	} else if global.SSL.ClientCAFile != "" && len(global.Certificate.Roles) != 0 {
+		global.Auth.Driver = "certificate"
+		if err := validateCertificate(global.SSL); err != nil {
+			logger.Fatal(err)
+		}
+
+		for i := range global.Certificate.Roles {
+			authentication.AddRoles(global.Certificate.Roles[i])
 		}
 	} else if global.Db.Driver != "" && global.Db.Scheme != "" {
 		global.Auth.Driver = "sql"
@@ -229,7 +293,7 @@ func initUchiwa(global GlobalConfig) GlobalConfig {
 			if global.Users[i].Readonly != false {
 				global.Users[i].Role.Readonly = global.Users[i].Readonly
 			}
-			authentication.Roles = append(authentication.Roles, global.Users[i].Role)
+			authentication.AddRoles(global.Users[i].Role)
 		}
 	} else if global.User != "" && global.Pass != "" {
 		logger.Debug("Loading single user from the config")
@@ -239,15 +303,35 @@ func initUchiwa(global GlobalConfig) GlobalConfig {
 		global.Users = append(global.Users, authentication.User{Username: global.User, Password: global.Pass, FullName: global.User})
 	}
 
+	// The login throttling relies on the trusted proxies to identify the
+	// clients
+	if _, err := helpers.ParseNetworks(global.Auth.TrustedProxies); err != nil {
+		logger.Fatal(err)
+	}
+
 	// Set the logger level
 	logger.SetLogLevel(global.LogLevel)
 
 	// Set the refresh rate for frontend
 	global.UsersOptions.Refresh = global.Refresh * 1000
 
+	// A datacenter can't be fetched for longer than the refresh interval
+	if global.Deadline <= 0 || global.Deadline > global.Refresh {
+		global.Deadline = global.Refresh
+	}
+
 	return global
 }
 
+// validateCertificate verifies that the dashboard is served over TLS, since
+// the certificate driver relies on the client certificates
+func validateCertificate(ssl SSL) error {
+	if ssl.CertFile == "" || ssl.KeyFile == "" {
+		return errors.New("The certificate authentication requires the certfile and keyfile of the ssl configuration")
+	}
+	return nil
+}
+
 // GetPublic generates the public configuration
 func (c *Config) GetPublic() *Config {
 	p := new(Config)
@@ -261,6 +345,7 @@ func (c *Config) GetPublic() *Config {
 	p.Uchiwa.Gitlab.ApplicationID = "*****"
 	p.Uchiwa.Gitlab.Secret = "*****"
 	p.Uchiwa.Ldap.BindPass = "*****"
+	p.Uchiwa.Oidc.ClientSecret = "*****"
 
 	for i := range p.Uchiwa.Github.Roles {
 		p.Uchiwa.Github.Roles[i].AccessToken = "*****"
@@ -274,12 +359,50 @@ func (c *Config) GetPublic() *Config {
 		p.Uchiwa.Ldap.Roles[i].AccessToken = "*****"
 	}
 
+	for i := range p.Uchiwa.Certificate.Roles {
+		p.Uchiwa.Certificate.Roles[i].AccessToken = "*****"
+	}
+
+	for i := range p.Uchiwa.Oidc.Roles {
+		p.Uchiwa.Oidc.Roles[i].AccessToken = "*****"
+	}
+
+	for i := range p.Uchiwa.Proxy.Roles {
+		p.Uchiwa.Proxy.Roles[i].AccessToken = "*****"
+	}
+
 	p.Sensu = make([]SensuConfig, len(c.Sensu))
 	for i := range c.Sensu {
 		p.Sensu[i] = c.Sensu[i]
 		p.Sensu[i].User = "*****"
 		p.Sensu[i].Pass = "*****"
+		p.Sensu[i].Token = "*****"
+		p.Sensu[i].Proxy = maskURL(c.Sensu[i].Proxy)
+
+		// The headers might contain credentials
+		if c.Sensu[i].Headers != nil {
+			p.Sensu[i].Headers = make(map[string]string, len(c.Sensu[i].Headers))
+			for name := range c.Sensu[i].Headers {
+				p.Sensu[i].Headers[name] = "*****"
+			}
+		}
 	}
 
 	return p
 }
+
+// maskURL masks the password of the URL, if any
+func maskURL(raw string) string {
+	if raw == "" {
+		return raw
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "*****"
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), "*****")
+	}
+	return u.String()
+}
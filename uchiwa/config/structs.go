@@ -2,6 +2,7 @@ package config
 
 import (
 	"github.com/sensu/uchiwa/uchiwa/authentication"
+	"github.com/sensu/uchiwa/uchiwa/sensu"
 	"github.com/sensu/uchiwa/uchiwa/structs"
 )
 
@@ -14,16 +15,26 @@ type Config struct {
 
 // SensuConfig struct contains conf about a Sensu API
 type SensuConfig struct {
-	Name     string
-	Host     string
-	Port     int
-	Ssl      bool
-	Insecure bool
-	URL      string
-	User     string
-	Path     string
-	Pass     string
-	Timeout  int
+	Name          string
+	Host          string
+	Port          int
+	Ssl           bool
+	Insecure      bool
+	CAFile        string // CA bundle trusted instead of the system CAs
+	CertFile      string // client certificate, for mutual TLS
+	KeyFile       string
+	ServerName    string // verified instead of the host
+	TLSMinVersion string // e.g. 1.2
+	URL           string
+	User          string
+	Path          string
+	Pass          string
+	Token         string            // bearer token, used instead of User and Pass
+	Headers       map[string]string // added to every request
+	Proxy         string            // e.g. http://proxy:3128 or socks5://proxy:1080
+	Timeout       int
+	Strategy      string                       // how the APIs of the datacenter are selected
+	Retries       map[string]sensu.RetryPolicy // by HTTP method
 }
 
 // GlobalConfig struct contains conf about Uchiwa
@@ -32,30 +43,46 @@ type GlobalConfig struct {
 	Port         int
 	LogLevel     string
 	Refresh      int
+	Concurrency  int
+	Deadline     int // in seconds
+	StaleLimit   int // in seconds, negative to disable
 	Pass         string
 	User         string
 	Users        []authentication.User
 	Audit        Audit
 	Auth         structs.Auth
+	Certificate  Certificate
 	Db           Db
 	Enterprise   bool
 	Github       Github
 	Gitlab       Gitlab
 	Ldap         Ldap
+	Oidc         Oidc
+	Proxy        Proxy
 	SSL          SSL
 	UsersOptions UsersOptions
 }
 
 // Audit struct contains the config of the Audit logger
 type Audit struct {
-	Level   string
-	Logfile string
+	Level        string
+	Logfile      string
+	MaxAge       int // in hours
+	MaxBackupAge int // in days
+	MaxBackups   int
+	MaxSize      int // in megabytes
+}
+
+// Certificate struct contains the client certificate driver configuration
+type Certificate struct {
+	Roles []authentication.Role
 }
 
 // Db struct contains the SQL driver configuration
 type Db struct {
-	Driver string
-	Scheme string
+	AdminPasswordFile string // receives the generated password of the initial admin user
+	Driver            string
+	Scheme            string
 }
 
 // Github struct contains the GitHub driver configuration
@@ -95,10 +122,33 @@ type Ldap struct {
 	UserObjectClass      string
 }
 
+// Oidc struct contains the OpenID Connect driver configuration
+type Oidc struct {
+	ClientID      string
+	ClientSecret  string
+	GroupsClaim   string
+	Issuer        string
+	RedirectURL   string
+	Roles         []authentication.Role
+	Scopes        []string
+	UsernameClaim string
+}
+
+// Proxy struct contains the reverse proxy driver configuration
+type Proxy struct {
+	GroupsHeader    string
+	GroupsSeparator string
+	Roles           []authentication.Role
+	TrustedProxies  []string
+	UserHeader      string
+}
+
 // SSL struct contains the path the SSL certificate and key
 type SSL struct {
-	CertFile string
-	KeyFile  string
+	CertFile     string
+	ClientAuth   string
+	ClientCAFile string
+	KeyFile      string
 }
 
 // UsersOptions struct contains various config tweaks
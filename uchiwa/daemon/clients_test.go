@@ -3,81 +3,95 @@ package daemon
 import (
 	"testing"
 
+	"github.com/sensu/uchiwa/uchiwa/structs"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestFindClientEvents(t *testing.T) {
 
 	// no events
-	client := map[string]interface{}{"dc": "us-east-1", "name": "foo"}
-	events := []interface{}{nil}
-	expectedClient := map[string]interface{}{"dc": "us-east-1", "name": "foo", "status": 0}
-	result := findClientEvents(client, &events)
-	assert.Equal(t, expectedClient, result)
-
-	var statusFloat = 1.0
-	events = []interface{}{
-		map[string]interface{}{
-			"check":  map[string]interface{}{"output": "http_critical", "status": 2},
-			"client": map[string]interface{}{"name": "foo"},
-			"dc":     "us-east-1",
+	client := &structs.Client{Dc: "us-east-1", Name: "foo", Status: 2}
+	findClientEvents(client, nil)
+	assert.Equal(t, &structs.Client{Dc: "us-east-1", Name: "foo", Status: 0}, client)
+
+	events := []*structs.Event{
+		{
+			Check:  &structs.Check{Output: "http_critical", Status: 2},
+			Client: &structs.Client{Name: "foo"},
+			Dc:     "us-east-1",
 		},
-		map[string]interface{}{
-			"check":  map[string]interface{}{"output": "http_warning", "status": statusFloat},
-			"client": map[string]interface{}{"name": "bar"},
-			"dc":     "us-west-1",
+		{
+			Check:  &structs.Check{Output: "http_warning", Status: 1},
+			Client: &structs.Client{Name: "bar"},
+			Dc:     "us-west-1",
 		},
+		// events without check or client are ignored
+		{Client: &structs.Client{Name: "foo"}, Dc: "us-east-1"},
+		{Check: &structs.Check{Status: 2}, Dc: "us-east-1"},
 	}
 
-	// event where status is a float64
-	client = map[string]interface{}{"dc": "us-west-1", "name": "bar"}
-	expectedClient = map[string]interface{}{"dc": "us-west-1", "name": "bar", "output": "http_warning", "status": 1}
-	result = findClientEvents(client, &events)
-	assert.Equal(t, expectedClient, result)
+	client = &structs.Client{Dc: "us-west-1", Name: "bar"}
+	findClientEvents(client, events)
+	assert.Equal(t, &structs.Client{Dc: "us-west-1", Name: "bar", Output: "http_warning", Status: 1}, client)
 
-	// event where status is an int
-	client = map[string]interface{}{"dc": "us-east-1", "name": "foo"}
-	expectedClient = map[string]interface{}{"dc": "us-east-1", "name": "foo", "output": "http_critical", "status": 2}
-	result = findClientEvents(client, &events)
-	assert.Equal(t, expectedClient, result)
+	client = &structs.Client{Dc: "us-east-1", Name: "foo"}
+	findClientEvents(client, events)
+	assert.Equal(t, &structs.Client{Dc: "us-east-1", Name: "foo", Output: "http_critical", Status: 2}, client)
 
 	// client has no events
-	client = map[string]interface{}{"dc": "us-east-1", "name": "qux"}
-	expectedClient = map[string]interface{}{"dc": "us-east-1", "name": "qux", "status": 0}
-	result = findClientEvents(client, &events)
-	assert.Equal(t, expectedClient, result)
+	client = &structs.Client{Dc: "us-east-1", Name: "qux"}
+	findClientEvents(client, events)
+	assert.Equal(t, &structs.Client{Dc: "us-east-1", Name: "qux", Status: 0}, client)
 
 	// client has multiple events
-	newEvents := []interface{}{
-		map[string]interface{}{
-			"check":  map[string]interface{}{"output": "http_critical", "status": 2},
-			"client": map[string]interface{}{"name": "qux"},
-			"dc":     "us-east-1",
+	events = append(events,
+		&structs.Event{
+			Check:  &structs.Check{Output: "http_critical", Status: 2},
+			Client: &structs.Client{Name: "qux"},
+			Dc:     "us-east-1",
 		},
-		map[string]interface{}{
-			"check":  map[string]interface{}{"output": "http_warning", "status": 1},
-			"client": map[string]interface{}{"name": "qux"},
-			"dc":     "us-east-1",
+		&structs.Event{
+			Check:  &structs.Check{Output: "http_warning", Status: 1},
+			Client: &structs.Client{Name: "qux"},
+			Dc:     "us-east-1",
 		},
-	}
-	events = append(events, newEvents[0])
-	events = append(events, newEvents[1])
+	)
 
-	client = map[string]interface{}{"dc": "us-east-1", "name": "qux"}
-	expectedClient = map[string]interface{}{"dc": "us-east-1", "name": "qux", "output": "http_critical and 1 more...", "status": 2}
-	result = findClientEvents(client, &events)
-	assert.Equal(t, expectedClient, result)
+	client = &structs.Client{Dc: "us-east-1", Name: "qux"}
+	findClientEvents(client, events)
+	assert.Equal(t, &structs.Client{Dc: "us-east-1", Name: "qux", Output: "http_critical and 1 more...", Status: 2}, client)
 
 	// event with unknown status
-	newEvent := map[string]interface{}{
-		"check":  map[string]interface{}{"output": "http_unknown", "status": 3},
-		"client": map[string]interface{}{"name": "baz"},
-		"dc":     "us-west-1",
-	}
-	events = append(events, newEvent)
+	events = append(events, &structs.Event{
+		Check:  &structs.Check{Output: "http_unknown", Status: 3},
+		Client: &structs.Client{Name: "baz"},
+		Dc:     "us-west-1",
+	})
+
+	client = &structs.Client{Dc: "us-west-1", Name: "baz"}
+	findClientEvents(client, events)
+	assert.Equal(t, &structs.Client{Dc: "us-west-1", Name: "baz", Output: "http_unknown", Status: 3}, client)
+}
+
+func TestBuildEvents(t *testing.T) {
+	d := Daemon{Data: &structs.Data{
+		Events: []*structs.Event{
+			{Check: &structs.Check{Name: "cpu"}, Client: &structs.Client{Name: "foo"}, Dc: "us-east-1"},
+			{Check: &structs.Check{Name: "cpu"}, Dc: "us-east-1"},
+			{Attributes: structs.Attributes{"check": "cpu", "client": "foo"}, Dc: "us-east-1"},
+		},
+		Silenced: []*structs.Silence{{Dc: "us-east-1", ID: "client:foo:*"}},
+	}}
+
+	d.buildEvents()
+
+	event := d.Data.Events[0]
+	assert.Equal(t, "us-east-1/foo/cpu", event.UID)
+	assert.True(t, event.Client.Silenced)
+	assert.True(t, event.Silenced)
+	assert.Equal(t, []string{"client:foo:*"}, event.SilencedBy)
 
-	client = map[string]interface{}{"dc": "us-west-1", "name": "baz"}
-	expectedClient = map[string]interface{}{"dc": "us-west-1", "name": "baz", "output": "http_unknown", "status": 3}
-	result = findClientEvents(client, &events)
-	assert.Equal(t, expectedClient, result)
+	// The events without client are left untouched
+	assert.Equal(t, "", d.Data.Events[1].UID)
+	assert.Equal(t, "", d.Data.Events[2].UID)
 }
@@ -1,6 +1,9 @@
 package daemon
 
 import (
+	"context"
+	"fmt"
+	"sync"
 	"time"
 
 	"github.com/sensu/uchiwa/uchiwa/logger"
@@ -8,19 +11,23 @@ import (
 	"github.com/sensu/uchiwa/uchiwa/structs"
 )
 
-const datacenterErrorString = "Connection error. Is the Sensu API running?"
-
 // Daemon structure is used to manage the Uchiwa daemon
 type Daemon struct {
+	Concurrency int
 	Data        *structs.Data
 	Datacenters *[]sensu.Sensu
+	Deadline    time.Duration
 	Enterprise  bool
+	StaleLimit  time.Duration
+
+	// lastKnown contains the last data successfully fetched from each datacenter
+	lastKnown map[string]*datacenterResult
 }
 
 // SensuDatacenter represents the sensu.Sensu struct
 type SensuDatacenter interface {
 	GetName() string
-	Metric(string) (*structs.SERawMetric, error)
+	Metric(context.Context, string) (*structs.SERawMetric, error)
 }
 
 // Start method fetches and builds Sensu data from each datacenter every Refresh seconds
@@ -57,112 +64,245 @@ func (d *Daemon) Start(interval int, data chan *structs.Data) {
 func (d *Daemon) buildData() {
 	d.buildEvents()
 	d.buildClients()
-	setID(d.Data.Checks, "/")
-	setID(d.Data.Silenced, ":")
-	setID(d.Data.Stashes, "/")
+	d.setIDs()
 	d.BuildSubscriptions()
-	setID(d.Data.Aggregates, "/")
 	d.buildMetrics()
 	d.buildSEMetrics()
 }
 
-// getData retrieves all endpoints for every datacenter
+// fetchData retrieves all endpoints for every datacenter. The datacenters are
+// fetched concurrently, up to Concurrency at a time, and their results are
+// merged in the order of the datacenters
 func (d *Daemon) fetchData() {
-	d.Data.Health.Sensu = make(map[string]structs.SensuHealth, len(*d.Datacenters))
+	datacenters := *d.Datacenters
+	results := make([]*datacenterResult, len(datacenters))
 
-	for _, datacenter := range *d.Datacenters {
-		logger.Infof("Updating the datacenter %s", datacenter.Name)
+	concurrency := d.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	sem := make(chan struct{}, concurrency)
 
-		// set default health status
-		d.Data.Health.Sensu[datacenter.Name] = structs.SensuHealth{Output: datacenterErrorString, Status: 2}
+	var wg sync.WaitGroup
+	for i := range datacenters {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			results[i] = d.fetchDatacenterWithDeadline(&datacenters[i])
+		}(i)
+	}
+	wg.Wait()
+
+	now := time.Now()
+	d.Data.Health.Sensu = make(map[string]structs.SensuHealth, len(datacenters))
+	for i, datacenter := range datacenters {
 		d.Data.Health.Uchiwa = "ok"
+		d.mergeDatacenter(datacenter.Name, d.retain(datacenter.Name, results[i], now), now)
 
-		// fetch sensu data from the datacenter
-		stashes, err := datacenter.GetStashes()
-		if err != nil {
-			logger.Warningf("Connection failed to the datacenter %s", datacenter.Name)
-			continue
-		}
-		silenced, err := datacenter.GetSilenced()
-		if err != nil {
-			logger.Warningf("Impossible to retrieve silenced entries from the "+
-				"datacenter %s. Silencing might not be possible, please update Sensu", datacenter.Name)
-		}
-		checks, err := datacenter.GetChecks()
-		if err != nil {
-			logger.Warningf("Connection failed to the datacenter %s", datacenter.Name)
-			continue
-		}
-		clients, err := datacenter.GetClients()
-		if err != nil {
-			logger.Warningf("Connection failed to the datacenter %s", datacenter.Name)
-			continue
-		}
-		events, err := datacenter.GetEvents()
-		if err != nil {
-			logger.Warningf("Connection failed to the datacenter %s", datacenter.Name)
-			continue
-		}
-		info, err := datacenter.GetInfo()
-		if err != nil {
-			logger.Warningf("Connection failed to the datacenter %s", datacenter.Name)
-			continue
-		}
-		aggregates, err := datacenter.GetAggregates()
+		health := d.Data.Health.Sensu[datacenter.Name]
+		health.APIs = datacenter.Health()
+		d.Data.Health.Sensu[datacenter.Name] = health
+	}
+}
+
+// datacenterResult contains the data fetched from a datacenter
+type datacenterResult struct {
+	aggregates []*structs.Aggregate
+	checks     []*structs.Check
+	clients    []*structs.Client
+	err        error
+	events     []*structs.Event
+	info       *structs.Info
+	metrics    *structs.SERawMetrics
+	silenced   []*structs.Silence
+	stashes    []*structs.Stash
+	updated    time.Time
+}
+
+// fetchDatacenterWithDeadline fetches the datacenter but gives up once the
+// deadline is exceeded, so a slow datacenter does not delay the others. The
+// pending requests are then canceled
+func (d *Daemon) fetchDatacenterWithDeadline(datacenter *sensu.Sensu) *datacenterResult {
+	if d.Deadline <= 0 {
+		return d.fetchDatacenter(context.Background(), datacenter)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), d.Deadline)
+	defer cancel()
+
+	result := make(chan *datacenterResult, 1)
+	go func() {
+		result <- d.fetchDatacenter(ctx, datacenter)
+	}()
+
+	timer := time.NewTimer(d.Deadline)
+	defer timer.Stop()
+
+	select {
+	case r := <-result:
+		return r
+	case <-timer.C:
+		return &datacenterResult{err: fmt.Errorf("the datacenter did not respond within %s", d.Deadline)}
+	}
+}
+
+// fetchDatacenter retrieves all endpoints of the datacenter concurrently
+func (d *Daemon) fetchDatacenter(ctx context.Context, datacenter *sensu.Sensu) *datacenterResult {
+	logger.Infof("Updating the datacenter %s", datacenter.Name)
+
+	r := &datacenterResult{}
+	var errs [6]error
+	var silencedErr error
+
+	var wg sync.WaitGroup
+	fetch := func(f func()) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f()
+		}()
+	}
+
+	fetch(func() { r.stashes, errs[0] = datacenter.GetStashes(ctx) })
+	fetch(func() { r.silenced, silencedErr = datacenter.GetSilenced(ctx) })
+	fetch(func() { r.checks, errs[1] = datacenter.GetChecks(ctx) })
+	fetch(func() { r.clients, errs[2] = datacenter.GetClients(ctx) })
+	fetch(func() { r.events, errs[3] = datacenter.GetEvents(ctx) })
+	fetch(func() { r.info, errs[4] = datacenter.GetInfo(ctx) })
+	fetch(func() { r.aggregates, errs[5] = datacenter.GetAggregates(ctx) })
+	if d.Enterprise {
+		fetch(func() { r.metrics = getEnterpriseMetrics(ctx, datacenter, &structs.SERawMetrics{}) })
+	}
+	wg.Wait()
+
+	for _, err := range errs {
 		if err != nil {
-			logger.Warningf("Connection failed to the datacenter %s", datacenter.Name)
-			continue
+			return &datacenterResult{err: err}
 		}
+	}
 
-		if d.Enterprise {
-			d.Data.SERawMetrics = *getEnterpriseMetrics(&datacenter, &d.Data.SERawMetrics)
-		}
+	if silencedErr != nil {
+		logger.Warningf("Impossible to retrieve silenced entries from the "+
+			"datacenter %s. Silencing might not be possible, please update Sensu", datacenter.Name)
+	}
 
-		// Determine the status of the datacenter
-		if !info.Redis.Connected {
-			d.Data.Health.Sensu[datacenter.Name] = structs.SensuHealth{Output: "Not connected to Redis", Status: 1}
-		} else if !info.Transport.Connected {
-			d.Data.Health.Sensu[datacenter.Name] = structs.SensuHealth{Output: "Not connected to the transport", Status: 1}
-		} else {
-			d.Data.Health.Sensu[datacenter.Name] = structs.SensuHealth{Output: "ok", Status: 0}
-		}
+	return r
+}
 
-		// add fetched data into d.Data interface
-		for _, v := range stashes {
-			setDc(v, datacenter.Name)
-			d.Data.Stashes = append(d.Data.Stashes, v)
-		}
-		for _, v := range silenced {
-			setDc(v, datacenter.Name)
-			d.Data.Silenced = append(d.Data.Silenced, v)
-		}
-		for _, v := range checks {
-			setDc(v, datacenter.Name)
-			d.Data.Checks = append(d.Data.Checks, v)
-		}
-		for _, v := range clients {
-			setDc(v, datacenter.Name)
-			d.Data.Clients = append(d.Data.Clients, v)
-		}
-		for _, v := range events {
-			setDc(v, datacenter.Name)
-			d.Data.Events = append(d.Data.Events, v)
-		}
-		for _, v := range aggregates {
-			setDc(v, datacenter.Name)
-			d.Data.Aggregates = append(d.Data.Aggregates, v)
+// retain records the result of a successful fetch as the last known data of
+// the datacenter. When the fetch failed, a stale copy of the last known data is
+// returned instead, as long as it is not older than the staleness limit
+func (d *Daemon) retain(name string, r *datacenterResult, now time.Time) *datacenterResult {
+	if d.lastKnown == nil {
+		d.lastKnown = make(map[string]*datacenterResult)
+	}
+
+	if r.err == nil {
+		r.updated = now
+		d.lastKnown[name] = r
+		return r
+	}
+
+	last, ok := d.lastKnown[name]
+	if !ok {
+		return r
+	}
+
+	if d.StaleLimit <= 0 || now.Sub(last.updated) > d.StaleLimit {
+		logger.Warningf("Dropping the data of the datacenter %s, last updated at %s", name, last.updated.Format(time.RFC3339))
+		delete(d.lastKnown, name)
+		return r
+	}
+
+	// The elements are copied since the previous data might still be in use
+	return &datacenterResult{
+		aggregates: copyAggregates(last.aggregates),
+		checks:     copyChecks(last.checks),
+		clients:    copyClients(last.clients),
+		err:        r.err,
+		events:     copyEvents(last.events),
+		info:       last.info,
+		metrics:    last.metrics,
+		silenced:   copySilenced(last.silenced),
+		stashes:    copyStashes(last.stashes),
+		updated:    last.updated,
+	}
+}
+
+// mergeDatacenter adds the data fetched from the datacenter into d.Data. The
+// data of a datacenter that could not be fetched is stale, if available
+func (d *Daemon) mergeDatacenter(name string, r *datacenterResult, now time.Time) {
+	stale := r.err != nil
+	if stale {
+		logger.Warningf("Connection failed to the datacenter %s: %v", name, r.err)
+		d.Data.Health.Sensu[name] = structs.SensuHealth{Output: r.err.Error(), Status: 2}
+		if r.info == nil {
+			return
 		}
+	}
+
+	if r.metrics != nil {
+		d.Data.SERawMetrics.Clients = append(d.Data.SERawMetrics.Clients, r.metrics.Clients...)
+		d.Data.SERawMetrics.Events = append(d.Data.SERawMetrics.Events, r.metrics.Events...)
+		d.Data.SERawMetrics.KeepalivesAVG60 = append(d.Data.SERawMetrics.KeepalivesAVG60, r.metrics.KeepalivesAVG60...)
+		d.Data.SERawMetrics.Requests = append(d.Data.SERawMetrics.Requests, r.metrics.Requests...)
+		d.Data.SERawMetrics.Results = append(d.Data.SERawMetrics.Results, r.metrics.Results...)
+	}
 
-		// build datacenter
-		dc := d.buildDatacenter(&datacenter.Name, info)
-		dc.Stats["aggregates"] = len(aggregates)
-		dc.Stats["checks"] = len(checks)
-		dc.Stats["clients"] = len(clients)
-		dc.Stats["events"] = len(events)
-		dc.Stats["silenced"] = len(silenced)
-		dc.Stats["stashes"] = len(stashes)
-		d.Data.Dc = append(d.Data.Dc, dc)
+	// Determine the status of the datacenter
+	updated := r.updated
+	age := int64(now.Sub(updated) / time.Second)
+	if stale {
+		d.Data.Health.Sensu[name] = structs.SensuHealth{Output: r.err.Error(), Status: 2, Age: age, LastUpdated: &updated, Stale: true}
+	} else if !r.info.Redis.Connected {
+		d.Data.Health.Sensu[name] = structs.SensuHealth{Output: "Not connected to Redis", Status: 1, LastUpdated: &updated}
+	} else if !r.info.Transport.Connected {
+		d.Data.Health.Sensu[name] = structs.SensuHealth{Output: "Not connected to the transport", Status: 1, LastUpdated: &updated}
+	} else {
+		d.Data.Health.Sensu[name] = structs.SensuHealth{Output: "ok", Status: 0, LastUpdated: &updated}
 	}
+
+	// add fetched data into d.Data interface
+	for _, v := range r.stashes {
+		v.Dc = name
+	}
+	d.Data.Stashes = append(d.Data.Stashes, r.stashes...)
+	for _, v := range r.silenced {
+		v.Dc = name
+	}
+	d.Data.Silenced = append(d.Data.Silenced, r.silenced...)
+	for _, v := range r.checks {
+		v.Dc = name
+	}
+	d.Data.Checks = append(d.Data.Checks, r.checks...)
+	for _, v := range r.clients {
+		v.Dc = name
+	}
+	d.Data.Clients = append(d.Data.Clients, r.clients...)
+	for _, v := range r.events {
+		v.Dc = name
+	}
+	d.Data.Events = append(d.Data.Events, r.events...)
+	for _, v := range r.aggregates {
+		v.Dc = name
+	}
+	d.Data.Aggregates = append(d.Data.Aggregates, r.aggregates...)
+
+	// build datacenter
+	dc := d.buildDatacenter(&name, r.info)
+	dc.Stats["aggregates"] = len(r.aggregates)
+	dc.Stats["checks"] = len(r.checks)
+	dc.Stats["clients"] = len(r.clients)
+	dc.Stats["events"] = len(r.events)
+	dc.Stats["silenced"] = len(r.silenced)
+	dc.Stats["stashes"] = len(r.stashes)
+	dc.Age = age
+	dc.LastUpdated = updated
+	dc.Stale = stale
+	d.Data.Dc = append(d.Data.Dc, dc)
 }
 
 func (d *Daemon) resetData() {
@@ -170,13 +310,13 @@ func (d *Daemon) resetData() {
 }
 
 // getEnterpriseMetrics retrieves Sensu Enterprise metrics
-func getEnterpriseMetrics(datacenter SensuDatacenter, metrics *structs.SERawMetrics) *structs.SERawMetrics {
+func getEnterpriseMetrics(ctx context.Context, datacenter SensuDatacenter, metrics *structs.SERawMetrics) *structs.SERawMetrics {
 	var err error
 	m := make(map[string]*structs.SERawMetric)
 	metricsEndpoints := []string{"clients", "events", "keepalives_avg_60", "check_requests", "results"}
 
 	for _, metric := range metricsEndpoints {
-		m[metric], err = datacenter.Metric(metric)
+		m[metric], err = datacenter.Metric(ctx, metric)
 		if err != nil {
 			logger.Debugf("Could not retrieve the %s enterprise metrics. %s", metric, datacenter.GetName())
 			m[metric] = &structs.SERawMetric{}
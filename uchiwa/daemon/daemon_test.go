@@ -1,9 +1,16 @@
 package daemon
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/sensu/uchiwa/uchiwa/sensu"
 	"github.com/sensu/uchiwa/uchiwa/structs"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -13,7 +20,7 @@ type mockSensu struct {
 	mock.Mock
 }
 
-func (s *mockSensu) Metric(name string) (*structs.SERawMetric, error) {
+func (s *mockSensu) Metric(ctx context.Context, name string) (*structs.SERawMetric, error) {
 	args := s.Called(name)
 	return args.Get(0).(*structs.SERawMetric), args.Error(1)
 }
@@ -36,7 +43,7 @@ func TestGetEnterpriseMetrics(t *testing.T) {
 	datacenter.On("Metric", "results").Return(&structs.SERawMetric{}, nil)
 	metrics := structs.SERawMetrics{}
 
-	metrics = *getEnterpriseMetrics(datacenter, &metrics)
+	metrics = *getEnterpriseMetrics(context.Background(), datacenter, &metrics)
 
 	assert.Equal(t, 2, len(metrics.Clients[0].Points))
 	assert.Equal(t, 0, len(metrics.Events[0].Points))
@@ -44,3 +51,117 @@ func TestGetEnterpriseMetrics(t *testing.T) {
 
 	datacenter.AssertExpectations(t)
 }
+
+// newSensuServer returns a fake Sensu API which waits for the provided delay
+// before answering with a client named after the datacenter
+func newSensuServer(name string, delay time.Duration) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(delay)
+		switch r.URL.Path {
+		case "/info":
+			fmt.Fprint(w, `{"redis":{"connected":true},"transport":{"connected":true}}`)
+		case "/clients":
+			fmt.Fprintf(w, `[{"name":"%s"}]`, name)
+		default:
+			fmt.Fprint(w, `[]`)
+		}
+	}))
+}
+
+func TestFetchData(t *testing.T) {
+	var datacenters []sensu.Sensu
+	for i, delay := range []time.Duration{0, 1500 * time.Millisecond, 0, 0} {
+		name := fmt.Sprintf("dc%d", i)
+		s := newSensuServer(name, delay)
+		defer s.Close()
+
+		api := sensu.NewAPI("", s.URL, 10, "", "", nil, nil)
+		datacenters = append(datacenters, sensu.Sensu{Name: name, APIs: []sensu.API{api}})
+	}
+
+	d := &Daemon{
+		Concurrency: 2,
+		Data:        &structs.Data{},
+		Datacenters: &datacenters,
+		Deadline:    300 * time.Millisecond,
+	}
+
+	start := time.Now()
+	d.fetchData()
+	assert.True(t, time.Since(start) < time.Second, "the slow datacenter must not delay the others")
+
+	// The slow datacenter exceeds its deadline
+	assert.Equal(t, 2, d.Data.Health.Sensu["dc1"].Status)
+	assert.Equal(t, 0, d.Data.Health.Sensu["dc0"].Status)
+	assert.Equal(t, "ok", d.Data.Health.Uchiwa)
+
+	// The results are merged in the order of the datacenters
+	assert.Equal(t, 3, len(d.Data.Dc))
+	var names []string
+	for _, c := range d.Data.Clients {
+		assert.Equal(t, c.Name, c.Dc)
+		names = append(names, c.Name)
+	}
+	assert.Equal(t, []string{"dc0", "dc2", "dc3"}, names)
+	assert.Equal(t, "dc2", d.Data.Dc[1].Name)
+	assert.Equal(t, 1, d.Data.Dc[1].Stats["clients"])
+}
+
+func TestFetchDataStale(t *testing.T) {
+	var failing int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&failing) == 1 {
+			http.Error(w, "", http.StatusBadGateway)
+			return
+		}
+		switch r.URL.Path {
+		case "/info":
+			fmt.Fprint(w, `{"redis":{"connected":true},"transport":{"connected":true}}`)
+		case "/clients":
+			fmt.Fprint(w, `[{"name":"foo"}]`)
+		default:
+			fmt.Fprint(w, `[]`)
+		}
+	}))
+	defer s.Close()
+
+	datacenters := []sensu.Sensu{{Name: "dc0", APIs: []sensu.API{sensu.NewAPI("", s.URL, 10, "", "", nil, nil)}}}
+	d := &Daemon{Concurrency: 1, Datacenters: &datacenters, StaleLimit: time.Minute}
+	fetch := func() {
+		d.resetData()
+		d.fetchData()
+	}
+
+	fetch()
+	assert.Equal(t, 0, d.Data.Health.Sensu["dc0"].Status)
+	assert.False(t, d.Data.Health.Sensu["dc0"].Stale)
+	assert.False(t, d.Data.Dc[0].Stale)
+	updated := d.Data.Dc[0].LastUpdated
+
+	// The last known data is kept when the datacenter is unreachable
+	atomic.StoreInt32(&failing, 1)
+	fetch()
+	assert.Equal(t, 2, d.Data.Health.Sensu["dc0"].Status)
+	assert.True(t, d.Data.Health.Sensu["dc0"].Stale)
+	assert.Equal(t, updated, *d.Data.Health.Sensu["dc0"].LastUpdated)
+	assert.Equal(t, 1, len(d.Data.Clients))
+	assert.Equal(t, 1, len(d.Data.Dc))
+	assert.True(t, d.Data.Dc[0].Stale)
+	assert.Equal(t, updated, d.Data.Dc[0].LastUpdated)
+
+	// The data is dropped once older than the staleness limit
+	d.lastKnown["dc0"].updated = time.Now().Add(-2 * time.Minute)
+	fetch()
+	assert.Equal(t, 2, d.Data.Health.Sensu["dc0"].Status)
+	assert.False(t, d.Data.Health.Sensu["dc0"].Stale)
+	assert.Equal(t, 0, len(d.Data.Clients))
+	assert.Equal(t, 0, len(d.Data.Dc))
+
+	// Fresh data is used again once the datacenter recovers. The API is
+	// replaced since its circuit breaker is only closed once probed
+	atomic.StoreInt32(&failing, 0)
+	datacenters[0].APIs = []sensu.API{sensu.NewAPI("", s.URL, 10, "", "", nil, nil)}
+	fetch()
+	assert.Equal(t, 0, d.Data.Health.Sensu["dc0"].Status)
+	assert.Equal(t, 1, len(d.Data.Clients))
+}
@@ -9,50 +9,30 @@ import (
 
 // BuildEvents constructs events objects for frontend consumption
 func (d *Daemon) buildEvents() {
-	for _, e := range d.Data.Events {
-		m := e.(map[string]interface{})
-
-		// get client name
-		clientMap, ok := m["client"].(map[string]interface{})
-		if !ok {
-			logger.Warningf("Could not assert event's client interface from %+v", clientMap)
-			continue
-		}
-
-		client, ok := clientMap["name"].(string)
-		if !ok {
-			logger.Warningf("Could not assert event's client name from %+v", clientMap)
-			continue
-		}
-
-		// get check name
-		checkMap, ok := m["check"].(map[string]interface{})
-		if !ok {
-			logger.Warningf("Could not assert event's check interface from %+v", checkMap)
+	for _, event := range d.Data.Events {
+		if event.Client == nil || event.Client.Name == "" {
+			logger.Warningf("Could not determine the event's client from %+v", event)
 			continue
 		}
 
-		check, ok := checkMap["name"].(string)
-		if !ok {
-			logger.Warningf("Could not assert event's check name from %+v", checkMap)
+		if event.Check == nil || event.Check.Name == "" {
+			logger.Warningf("Could not determine the event's check from %+v", event)
 			continue
 		}
 
-		// get dc name
-		dc, ok := m["dc"].(string)
-		if !ok {
-			logger.Warningf("Could not assert event's datacenter name from %+v", m)
+		if event.Dc == "" {
+			logger.Warningf("Could not determine the event's datacenter from %+v", event)
 			continue
 		}
 
 		// Set the event unique ID
-		m["_id"] = fmt.Sprintf("%s/%s/%s", dc, client, check)
+		event.UID = fmt.Sprintf("%s/%s/%s", event.Dc, event.Client.Name, event.Check.Name)
 
 		// Determine if the client is silenced
-		m["client"].(map[string]interface{})["silenced"] = helpers.IsClientSilenced(client, dc, d.Data.Silenced)
+		event.Client.Silenced = helpers.IsClientSilenced(event.Client.Name, event.Dc, d.Data.Silenced)
 
 		// Determine if the check is silenced.
 		// See https://github.com/sensu/uchiwa/issues/602
-		m["silenced"], m["silenced_by"] = helpers.IsCheckSilenced(checkMap, client, dc, d.Data.Silenced)
+		event.Silenced, event.SilencedBy = helpers.IsCheckSilenced(event.Check, event.Client.Name, event.Dc, d.Data.Silenced)
 	}
 }
@@ -6,67 +6,103 @@ import (
 
 	"github.com/sensu/uchiwa/uchiwa/logger"
 	"github.com/sensu/uchiwa/uchiwa/sensu"
+	"github.com/sensu/uchiwa/uchiwa/structs"
 )
 
-// FindDcFromInterface ...
-func FindDcFromInterface(data interface{}, datacenters *[]sensu.Sensu) (*sensu.Sensu, map[string]interface{}, error) {
-	m, ok := data.(map[string]interface{})
-	if !ok {
-		logger.Warningf("Type assertion failed. Could not assert the given interface into a map: %+v", data)
-		return nil, nil, errors.New("Could not determine the datacenter.")
+// FindDc returns the datacenter corresponding to the provided name
+func FindDc(name string, datacenters *[]sensu.Sensu) (*sensu.Sensu, error) {
+	if name == "" {
+		return nil, errors.New("Could not determine the datacenter.")
 	}
 
-	id := m["dc"].(string)
-	if id == "" {
-		logger.Warningf("The received interface does not contain any datacenter information: ", data)
-		return nil, nil, errors.New("Could not determine the datacenter.")
+	for i := range *datacenters {
+		if (*datacenters)[i].Name == name {
+			return &(*datacenters)[i], nil
+		}
 	}
 
-	for _, dc := range *datacenters {
-		if dc.Name == id {
-			return &dc, m, nil
-		}
+	logger.Warningf("Could not find the datacenter %s", name)
+	return nil, fmt.Errorf("Could not find the datacenter %s", name)
+}
+
+// setIDs sets the unique ID of every element from its dc and name
+func (d *Daemon) setIDs() {
+	for _, aggregate := range d.Data.Aggregates {
+		aggregate.UID = fmt.Sprintf("%s/%s", aggregate.Dc, aggregate.Name)
+	}
+	for _, check := range d.Data.Checks {
+		check.UID = fmt.Sprintf("%s/%s", check.Dc, check.Name)
 	}
+	for _, silence := range d.Data.Silenced {
+		silence.UID = fmt.Sprintf("%s:%s", silence.Dc, silence.ID)
+	}
+	for _, stash := range d.Data.Stashes {
+		stash.UID = fmt.Sprintf("%s/%s", stash.Dc, stash.Path)
+	}
+}
 
-	logger.Warningf("Could not find the datacenter %s into %+v: ", id, data)
-	return nil, nil, fmt.Errorf("Could not find the datacenter %s", id)
+// The copy functions return shallow copies of the elements, which can be
+// modified by buildData without altering the originals. The attributes are
+// shared since they are never modified
+
+func copyAggregates(aggregates []*structs.Aggregate) []*structs.Aggregate {
+	c := make([]*structs.Aggregate, len(aggregates))
+	for i, aggregate := range aggregates {
+		a := *aggregate
+		c[i] = &a
+	}
+	return c
 }
 
-// setID sets the _id attribute on every element of the slice from the dc and name
-func setID(elements []interface{}, separator string) {
-	for _, e := range elements {
-		element, ok := e.(map[string]interface{})
-		if !ok {
-			continue
-		}
+func copyChecks(checks []*structs.Check) []*structs.Check {
+	c := make([]*structs.Check, len(checks))
+	for i, check := range checks {
+		k := *check
+		c[i] = &k
+	}
+	return c
+}
 
-		dc, ok := element["dc"].(string)
-		if !ok {
-			continue
-		}
+func copyClients(clients []*structs.Client) []*structs.Client {
+	c := make([]*structs.Client, len(clients))
+	for i, client := range clients {
+		k := *client
+		c[i] = &k
+	}
+	return c
+}
 
-		name, ok := element["name"].(string)
-		if !ok {
-			// Support silence entries
-			name, ok = element["id"].(string)
-			if !ok {
-				// Support stashes
-				name, ok = element["path"].(string)
-				if !ok {
-					continue
-				}
-			}
+func copyEvents(events []*structs.Event) []*structs.Event {
+	c := make([]*structs.Event, len(events))
+	for i, event := range events {
+		e := *event
+		if event.Check != nil {
+			check := *event.Check
+			e.Check = &check
+		}
+		if event.Client != nil {
+			client := *event.Client
+			e.Client = &client
 		}
+		c[i] = &e
+	}
+	return c
+}
 
-		element["_id"] = fmt.Sprintf("%s%s%s", dc, separator, name)
+func copySilenced(silenced []*structs.Silence) []*structs.Silence {
+	c := make([]*structs.Silence, len(silenced))
+	for i, silence := range silenced {
+		s := *silence
+		c[i] = &s
 	}
+	return c
 }
 
-func setDc(v interface{}, dc string) {
-	m, ok := v.(map[string]interface{})
-	if !ok {
-		logger.Warningf("Could not assert interface: %+v", v)
-	} else {
-		m["dc"] = dc
+func copyStashes(stashes []*structs.Stash) []*structs.Stash {
+	c := make([]*structs.Stash, len(stashes))
+	for i, stash := range stashes {
+		s := *stash
+		c[i] = &s
 	}
+	return c
 }
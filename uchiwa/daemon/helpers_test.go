@@ -3,23 +3,57 @@ package daemon
 import (
 	"testing"
 
+	"github.com/sensu/uchiwa/uchiwa/sensu"
+	"github.com/sensu/uchiwa/uchiwa/structs"
 	"github.com/stretchr/testify/assert"
 )
 
-func TestSetID(t *testing.T) {
-	elements := []interface{}{
-		map[string]interface{}{"dc": "us-east-1", "name": "foo"},
-		map[string]interface{}{"dc": "us-east-1", "name": "bar"},
-		map[string]interface{}{"dc": "us-west-1", "id": "foo"},
+func TestFindDc(t *testing.T) {
+	datacenters := []sensu.Sensu{{Name: "us-east-1"}, {Name: "us-west-1"}}
+
+	dc, err := FindDc("us-west-1", &datacenters)
+	assert.Nil(t, err)
+	assert.Equal(t, &datacenters[1], dc)
+
+	_, err = FindDc("eu-west-1", &datacenters)
+	assert.NotNil(t, err)
+
+	_, err = FindDc("", &datacenters)
+	assert.NotNil(t, err)
+}
+
+func TestSetIDs(t *testing.T) {
+	d := Daemon{Data: &structs.Data{
+		Aggregates: []*structs.Aggregate{{Dc: "us-east-1", Name: "foo"}},
+		Checks:     []*structs.Check{{Dc: "us-east-1", Name: "foo"}, {Dc: "us-east-1", Name: "bar"}},
+		Silenced:   []*structs.Silence{{Dc: "us-west-1", ID: "foo"}},
+		Stashes:    []*structs.Stash{{Dc: "us-west-1", Path: "silence/foo"}},
+	}}
+
+	d.setIDs()
+
+	assert.Equal(t, "us-east-1/foo", d.Data.Aggregates[0].UID)
+	assert.Equal(t, "us-east-1/foo", d.Data.Checks[0].UID)
+	assert.Equal(t, "us-east-1/bar", d.Data.Checks[1].UID)
+	assert.Equal(t, "us-west-1:foo", d.Data.Silenced[0].UID)
+	assert.Equal(t, "us-west-1/silence/foo", d.Data.Stashes[0].UID)
+}
+
+func TestCopyEvents(t *testing.T) {
+	events := []*structs.Event{
+		{Check: &structs.Check{Name: "cpu"}, Client: &structs.Client{Name: "foo"}},
+		{},
 	}
 
-	setID(elements, "/")
+	c := copyEvents(events)
+	assert.Equal(t, events, c)
 
-	element1 := elements[0].(map[string]interface{})
-	element2 := elements[1].(map[string]interface{})
-	element3 := elements[2].(map[string]interface{})
+	c[0].UID = "us-east-1/foo/cpu"
+	c[0].Check.UID = "us-east-1/cpu"
+	c[0].Client.Silenced = true
+	assert.Equal(t, "", events[0].UID)
+	assert.Equal(t, "", events[0].Check.UID)
+	assert.False(t, events[0].Client.Silenced)
 
-	assert.Equal(t, element1["_id"], "us-east-1/foo")
-	assert.Equal(t, element2["_id"], "us-east-1/bar")
-	assert.Equal(t, element3["_id"], "us-west-1/foo")
+	assert.Equal(t, 0, len(copyEvents(nil)))
 }
@@ -13,8 +13,8 @@ func (d *Daemon) buildMetrics() {
 	d.Data.Metrics.Silenced.Total = len(d.Data.Silenced)
 	d.Data.Metrics.Stashes.Total = len(d.Data.Stashes)
 
-	d.Data.Metrics.Clients = *helpers.BuildClientsMetrics(&d.Data.Clients)
-	d.Data.Metrics.Events = *helpers.BuildEventsMetrics(&d.Data.Events)
+	d.Data.Metrics.Clients = *helpers.BuildClientsMetrics(d.Data.Clients)
+	d.Data.Metrics.Events = *helpers.BuildEventsMetrics(d.Data.Events)
 }
 
 // buildSEMetrics prepares the Sensu Enterprise metrics for frontend consumption by the HUD
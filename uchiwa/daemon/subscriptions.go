@@ -3,22 +3,13 @@ package daemon
 import (
 	"strings"
 
-	"github.com/mitchellh/mapstructure"
-	"github.com/sensu/uchiwa/uchiwa/logger"
 	"github.com/sensu/uchiwa/uchiwa/structs"
 )
 
 // BuildSubscriptions builds a slice of every client subscriptions
 func (d *Daemon) BuildSubscriptions() {
 	for _, client := range d.Data.Clients {
-		var generic structs.GenericClient
-		err := mapstructure.Decode(client, &generic)
-		if err != nil {
-			logger.Debug("%s", err)
-			continue
-		}
-
-		for _, name := range generic.Subscriptions {
+		for _, name := range client.Subscriptions {
 			// Do not add per-client subscriptions to the slice so we don't pollute
 			// the subscriptions filter in the frontend.
 			// See https://github.com/sensu/sensu-settings/pull/40.
@@ -26,7 +17,7 @@ func (d *Daemon) BuildSubscriptions() {
 				continue
 			}
 
-			subscription := structs.Subscription{Dc: generic.Dc, Name: name}
+			subscription := structs.Subscription{Dc: client.Dc, Name: name}
 
 			if !isSubscriptionInSubscriptions(subscription, d.Data.Subscriptions) {
 				d.Data.Subscriptions = append(d.Data.Subscriptions, subscription)
@@ -10,9 +10,9 @@ import (
 func TestBuildSubscriptions(t *testing.T) {
 	// Test basic subscriptions
 	data := structs.Data{
-		Clients: []interface{}{
-			map[string]interface{}{"subscriptions": []string{"foo", "bar"}},
-			map[string]interface{}{"subscriptions": []string{"foo", "qux"}},
+		Clients: []*structs.Client{
+			{Subscriptions: []string{"foo", "bar"}},
+			{Subscriptions: []string{"foo", "qux"}},
 		},
 	}
 	d := Daemon{Data: &data}
@@ -21,9 +21,9 @@ func TestBuildSubscriptions(t *testing.T) {
 
 	// Test per-client subscriptions
 	data = structs.Data{
-		Clients: []interface{}{
-			map[string]interface{}{"subscriptions": []string{"foo", "client:foobar"}},
-			map[string]interface{}{"subscriptions": []string{"CLIENT:BAZ", "qux"}},
+		Clients: []*structs.Client{
+			{Subscriptions: []string{"foo", "client:foobar"}},
+			{Subscriptions: []string{"CLIENT:BAZ", "qux"}},
 		},
 	}
 	d = Daemon{Data: &data}
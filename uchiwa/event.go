@@ -1,17 +1,21 @@
 package uchiwa
 
-import "github.com/sensu/uchiwa/uchiwa/logger"
+import (
+	"context"
+
+	"github.com/sensu/uchiwa/uchiwa/logger"
+)
 
 // ResolveEvent sends a DELETE request in order to
 // resolve an event for a given check on a given client
-func (u *Uchiwa) ResolveEvent(check, client, dc string) error {
+func (u *Uchiwa) ResolveEvent(ctx context.Context, check, client, dc string) error {
 	api, err := getAPI(u.Datacenters, dc)
 	if err != nil {
 		logger.Warning(err)
 		return err
 	}
 
-	err = api.DeleteEvent(check, client)
+	err = api.DeleteEvent(ctx, check, client)
 	if err != nil {
 		logger.Warning(err)
 		return err
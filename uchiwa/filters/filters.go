@@ -1,21 +1,25 @@
 package filters
 
 import (
+	"strings"
+
 	"github.com/dgrijalva/jwt-go"
+	"github.com/sensu/uchiwa/uchiwa/authentication"
+	"github.com/sensu/uchiwa/uchiwa/logger"
 	"github.com/sensu/uchiwa/uchiwa/structs"
 )
 
 // Filters contains the different filtering methods based on the edition
 type Filters interface {
-	Aggregates(*[]interface{}, *jwt.Token) []interface{}
-	Checks(*[]interface{}, *jwt.Token) []interface{}
-	Clients(*[]interface{}, *jwt.Token) []interface{}
+	Aggregates([]*structs.Aggregate, *jwt.Token) []*structs.Aggregate
+	Checks([]*structs.Check, *jwt.Token) []*structs.Check
+	Clients([]*structs.Client, *jwt.Token) []*structs.Client
 	Datacenters([]*structs.Datacenter, *jwt.Token) []*structs.Datacenter
-	Events(*[]interface{}, *jwt.Token) []interface{}
-	// NEED WORK
+	Events([]*structs.Event, *jwt.Token) []*structs.Event
+	GetClientRequest(string, []string, *jwt.Token) bool
 	GetRequest(string, *jwt.Token) bool
-	Silenced(*[]interface{}, *jwt.Token) []interface{}
-	Stashes(*[]interface{}, *jwt.Token) []interface{}
+	Silenced([]*structs.Silence, []*structs.Client, *jwt.Token) []*structs.Silence
+	Stashes([]*structs.Stash, []*structs.Client, *jwt.Token) []*structs.Stash
 	Subscriptions(*[]structs.Subscription, *jwt.Token) []structs.Subscription
 }
 
@@ -23,60 +27,295 @@ type Filters interface {
 type Uchiwa struct{}
 
 // Aggregates filters based on role's datacenters
-func (u *Uchiwa) Aggregates(data *[]interface{}, token *jwt.Token) []interface{} {
-	aggregates := make([]interface{}, len(*data))
-	copy(aggregates, *data)
+func (u *Uchiwa) Aggregates(data []*structs.Aggregate, token *jwt.Token) []*structs.Aggregate {
+	role, ok := getRole(token)
+	aggregates := make([]*structs.Aggregate, 0, len(data))
+	if ok && role == nil {
+		return aggregates
+	}
+
+	for _, aggregate := range data {
+		if !ok || isStringAllowed(role.Datacenters, aggregate.Dc) {
+			aggregates = append(aggregates, aggregate)
+		}
+	}
 	return aggregates
 }
 
 // Checks filters based on role's datacenters and subscriptions
-func (u *Uchiwa) Checks(data *[]interface{}, token *jwt.Token) []interface{} {
-	checks := make([]interface{}, len(*data))
-	copy(checks, *data)
+func (u *Uchiwa) Checks(data []*structs.Check, token *jwt.Token) []*structs.Check {
+	role, ok := getRole(token)
+	checks := make([]*structs.Check, 0, len(data))
+	if ok && role == nil {
+		return checks
+	}
+
+	for _, check := range data {
+		if !ok || isCheckAllowed(role, check) {
+			checks = append(checks, check)
+		}
+	}
 	return checks
 }
 
 // Clients filters based on role's datacenters and subscriptions
-func (u *Uchiwa) Clients(data *[]interface{}, token *jwt.Token) []interface{} {
-	clients := make([]interface{}, len(*data))
-	copy(clients, *data)
+func (u *Uchiwa) Clients(data []*structs.Client, token *jwt.Token) []*structs.Client {
+	role, ok := getRole(token)
+	clients := make([]*structs.Client, 0, len(data))
+	if ok && role == nil {
+		return clients
+	}
+
+	for _, client := range data {
+		if !ok || isClientAllowed(role, client) {
+			clients = append(clients, client)
+		}
+	}
 	return clients
 }
 
 // Datacenters filters based on role's datacenters
 func (u *Uchiwa) Datacenters(data []*structs.Datacenter, token *jwt.Token) []*structs.Datacenter {
-	return data
+	role, ok := getRole(token)
+	if !ok {
+		return data
+	}
+
+	datacenters := make([]*structs.Datacenter, 0)
+	if role == nil {
+		return datacenters
+	}
+
+	for _, datacenter := range data {
+		if isStringAllowed(role.Datacenters, datacenter.Name) {
+			datacenters = append(datacenters, datacenter)
+		}
+	}
+	return datacenters
 }
 
 // Events filters based on role's datacenters and subscriptions
-func (u *Uchiwa) Events(data *[]interface{}, token *jwt.Token) []interface{} {
-	events := make([]interface{}, len(*data))
-	copy(events, *data)
+func (u *Uchiwa) Events(data []*structs.Event, token *jwt.Token) []*structs.Event {
+	role, ok := getRole(token)
+	events := make([]*structs.Event, 0, len(data))
+	if ok && role == nil {
+		return events
+	}
+
+	for _, event := range data {
+		if !ok || isEventAllowed(role, event) {
+			events = append(events, event)
+		}
+	}
 	return events
 }
 
-// Silenced filters based on role's datacenters
-func (u *Uchiwa) Silenced(data *[]interface{}, token *jwt.Token) []interface{} {
-	silenced := make([]interface{}, len(*data))
-	copy(silenced, *data)
+// Silenced filters based on role's datacenters and subscriptions, using the
+// provided clients to resolve the entries targeting a single client
+func (u *Uchiwa) Silenced(data []*structs.Silence, clients []*structs.Client, token *jwt.Token) []*structs.Silence {
+	role, ok := getRole(token)
+	silenced := make([]*structs.Silence, 0, len(data))
+	if ok && role == nil {
+		return silenced
+	}
+
+	for _, silence := range data {
+		if !ok || isSilenceAllowed(role, silence, clients) {
+			silenced = append(silenced, silence)
+		}
+	}
 	return silenced
 }
 
-// Stashes filters based on role's datacenters
-func (u *Uchiwa) Stashes(data *[]interface{}, token *jwt.Token) []interface{} {
-	stashes := make([]interface{}, len(*data))
-	copy(stashes, *data)
+// Stashes filters based on role's datacenters and subscriptions, using the
+// provided clients to resolve the stashes of the silenced clients
+func (u *Uchiwa) Stashes(data []*structs.Stash, clients []*structs.Client, token *jwt.Token) []*structs.Stash {
+	role, ok := getRole(token)
+	stashes := make([]*structs.Stash, 0, len(data))
+	if ok && role == nil {
+		return stashes
+	}
+
+	for _, stash := range data {
+		if !ok || isStashAllowed(role, stash, clients) {
+			stashes = append(stashes, stash)
+		}
+	}
 	return stashes
 }
 
 // Subscriptions filters based on role's subscriptions
 func (u *Uchiwa) Subscriptions(data *[]structs.Subscription, token *jwt.Token) []structs.Subscription {
-	subscriptions := make([]structs.Subscription, len(*data))
-	copy(subscriptions, *data)
+	role, ok := getRole(token)
+	if !ok {
+		subscriptions := make([]structs.Subscription, len(*data))
+		copy(subscriptions, *data)
+		return subscriptions
+	}
+
+	subscriptions := make([]structs.Subscription, 0)
+	if role == nil {
+		return subscriptions
+	}
+
+	for _, subscription := range *data {
+		if isStringAllowed(role.Datacenters, subscription.Dc) && isStringAllowed(role.Subscriptions, subscription.Name) {
+			subscriptions = append(subscriptions, subscription)
+		}
+	}
 	return subscriptions
 }
 
+// GetClientRequest is a function that filters the requests on a client.
+// Returns true if the role is not allowed to access the datacenter or any
+// of the client's subscriptions. An unknown client, without subscriptions, is
+// only allowed if the role has no restriction on the subscriptions
+func (u *Uchiwa) GetClientRequest(dc string, subscriptions []string, token *jwt.Token) bool {
+	role, ok := getRole(token)
+	if !ok {
+		return false
+	}
+	if role == nil {
+		return true
+	}
+
+	return !isStringAllowed(role.Datacenters, dc) || !isSubscriptionAllowed(role, subscriptions)
+}
+
 // GetRequest is a function that filters GET requests.
+// Returns true if the role is not allowed to access the datacenter
 func (u *Uchiwa) GetRequest(dc string, token *jwt.Token) bool {
+	role, ok := getRole(token)
+	if !ok {
+		return false
+	}
+	if role == nil {
+		return true
+	}
+
+	return !isStringAllowed(role.Datacenters, dc)
+}
+
+// getRole returns the role contained in the token. The boolean is false if
+// the data must not be filtered, e.g. when the authentication is disabled,
+// while a nil role means that no data must be returned
+func getRole(token *jwt.Token) (*authentication.Role, bool) {
+	if token == nil {
+		return nil, false
+	}
+
+	role, err := authentication.GetRoleFromToken(token)
+	if err != nil {
+		logger.Debugf("Invalid token: %s", err)
+		return nil, true
+	}
+
+	if len(role.Datacenters) == 0 && len(role.Subscriptions) == 0 {
+		return nil, false
+	}
+
+	return role, true
+}
+
+// isCheckAllowed returns true if the check is part of the role's datacenters
+// and subscriptions
+func isCheckAllowed(role *authentication.Role, check *structs.Check) bool {
+	return isStringAllowed(role.Datacenters, check.Dc) && isSubscriptionAllowed(role, check.Subscribers)
+}
+
+// isClientAllowed returns true if the client is part of the role's
+// datacenters and subscriptions
+func isClientAllowed(role *authentication.Role, client *structs.Client) bool {
+	return isStringAllowed(role.Datacenters, client.Dc) && isSubscriptionAllowed(role, client.Subscriptions)
+}
+
+// isEventAllowed returns true if the event is part of the role's datacenters
+// and if either its client or its check is visible
+func isEventAllowed(role *authentication.Role, event *structs.Event) bool {
+	if !isStringAllowed(role.Datacenters, event.Dc) {
+		return false
+	}
+
+	if event.Client != nil && isSubscriptionAllowed(role, event.Client.Subscriptions) {
+		return true
+	}
+	if event.Check != nil && isSubscriptionAllowed(role, event.Check.Subscribers) {
+		return true
+	}
+	return false
+}
+
+// isSilenceAllowed returns true if the silenced entry is part of the role's
+// datacenters and only targets the role's subscriptions or one of its clients.
+// An entry without subscription targets every client
+func isSilenceAllowed(role *authentication.Role, silence *structs.Silence, clients []*structs.Client) bool {
+	if !isStringAllowed(role.Datacenters, silence.Dc) {
+		return false
+	}
+
+	if strings.HasPrefix(silence.Subscription, "client:") {
+		name := strings.TrimPrefix(silence.Subscription, "client:")
+		return isSubscriptionAllowed(role, clientSubscriptions(clients, name, silence.Dc))
+	}
+	if silence.Subscription == "" {
+		return isSubscriptionAllowed(role, nil)
+	}
+	return isSubscriptionAllowed(role, []string{silence.Subscription})
+}
+
+// isStashAllowed returns true if the stash is part of the role's datacenters
+// and, when the role is restricted to some subscriptions, if it silences one
+// of its clients (silence/:client or silence/:client/:check)
+func isStashAllowed(role *authentication.Role, stash *structs.Stash, clients []*structs.Client) bool {
+	if !isStringAllowed(role.Datacenters, stash.Dc) {
+		return false
+	}
+
+	path := strings.Split(stash.Path, "/")
+	if len(path) < 2 || path[0] != "silence" {
+		return isSubscriptionAllowed(role, nil)
+	}
+	return isSubscriptionAllowed(role, clientSubscriptions(clients, path[1], stash.Dc))
+}
+
+// clientSubscriptions returns the subscriptions of the client in the
+// datacenter, or nil if the client is unknown
+func clientSubscriptions(clients []*structs.Client, name, dc string) []string {
+	for _, client := range clients {
+		if client.Name == name && client.Dc == dc {
+			return client.Subscriptions
+		}
+	}
+	return nil
+}
+
+// isStringAllowed returns true if the allowed slice is empty, which means
+// that there's no restriction, or if it contains the value
+func isStringAllowed(allowed []string, value string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+
+	for _, a := range allowed {
+		if a == value {
+			return true
+		}
+	}
+	return false
+}
+
+// isSubscriptionAllowed returns true if the role has no restriction on the
+// subscriptions or if at least one of the provided subscriptions is part of
+// the role's subscriptions
+func isSubscriptionAllowed(role *authentication.Role, subscriptions []string) bool {
+	if len(role.Subscriptions) == 0 {
+		return true
+	}
+
+	for _, subscription := range subscriptions {
+		if isStringAllowed(role.Subscriptions, subscription) {
+			return true
+		}
+	}
 	return false
 }
@@ -0,0 +1,203 @@
+package filters
+
+import (
+	"testing"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/sensu/uchiwa/uchiwa/authentication"
+	"github.com/sensu/uchiwa/uchiwa/structs"
+	"github.com/stretchr/testify/assert"
+)
+
+func newToken(role authentication.Role) *jwt.Token {
+	token := jwt.New(jwt.GetSigningMethod("RS256"))
+	token.Claims["Role"] = role
+	return token
+}
+
+func TestAggregates(t *testing.T) {
+	f := &Uchiwa{}
+	data := []*structs.Aggregate{
+		{Name: "foo", Dc: "us-east-1"},
+		{Name: "bar", Dc: "us-west-1"},
+	}
+
+	// Authentication disabled
+	assert.Equal(t, 2, len(f.Aggregates(data, nil)))
+
+	// No restriction on the role
+	assert.Equal(t, 2, len(f.Aggregates(data, newToken(authentication.Role{Name: "admin"}))))
+
+	result := f.Aggregates(data, newToken(authentication.Role{Datacenters: []string{"us-west-1"}}))
+	assert.Equal(t, []*structs.Aggregate{data[1]}, result)
+
+	// Invalid token
+	assert.Equal(t, 0, len(f.Aggregates(data, jwt.New(jwt.GetSigningMethod("RS256")))))
+}
+
+func TestChecks(t *testing.T) {
+	f := &Uchiwa{}
+	data := []*structs.Check{
+		{Name: "foo", Dc: "us-east-1", Subscribers: []string{"linux"}},
+		{Name: "bar", Dc: "us-east-1", Subscribers: []string{"windows"}},
+		{Name: "baz", Dc: "us-west-1", Subscribers: []string{"linux"}},
+		{Name: "qux", Dc: "us-east-1"},
+	}
+
+	result := f.Checks(data, newToken(authentication.Role{Subscriptions: []string{"linux"}}))
+	assert.Equal(t, []*structs.Check{data[0], data[2]}, result)
+
+	result = f.Checks(data, newToken(authentication.Role{Datacenters: []string{"us-east-1"}, Subscriptions: []string{"linux"}}))
+	assert.Equal(t, []*structs.Check{data[0]}, result)
+}
+
+func TestClients(t *testing.T) {
+	f := &Uchiwa{}
+	data := []*structs.Client{
+		{Name: "foo", Dc: "us-east-1", Subscriptions: []string{"linux", "web"}},
+		{Name: "bar", Dc: "us-east-1", Subscriptions: []string{"windows"}},
+		{Name: "baz", Dc: "us-west-1", Subscriptions: []string{"web"}},
+	}
+
+	result := f.Clients(data, newToken(authentication.Role{Subscriptions: []string{"web"}}))
+	assert.Equal(t, []*structs.Client{data[0], data[2]}, result)
+
+	result = f.Clients(data, newToken(authentication.Role{Datacenters: []string{"us-east-1"}}))
+	assert.Equal(t, []*structs.Client{data[0], data[1]}, result)
+}
+
+func TestDatacenters(t *testing.T) {
+	f := &Uchiwa{}
+	data := []*structs.Datacenter{{Name: "us-east-1"}, {Name: "us-west-1"}}
+
+	assert.Equal(t, data, f.Datacenters(data, nil))
+
+	result := f.Datacenters(data, newToken(authentication.Role{Datacenters: []string{"us-west-1"}}))
+	assert.Equal(t, []*structs.Datacenter{data[1]}, result)
+
+	// Subscriptions do not restrict the datacenters
+	assert.Equal(t, data, f.Datacenters(data, newToken(authentication.Role{Subscriptions: []string{"linux"}})))
+}
+
+func TestEvents(t *testing.T) {
+	f := &Uchiwa{}
+	data := []*structs.Event{
+		{
+			Dc:     "us-east-1",
+			Client: &structs.Client{Name: "foo", Subscriptions: []string{"linux"}},
+			Check:  &structs.Check{Name: "cpu"},
+		},
+		{
+			Dc:     "us-east-1",
+			Client: &structs.Client{Name: "bar", Subscriptions: []string{"windows"}},
+			Check:  &structs.Check{Name: "disk", Subscribers: []string{"linux"}},
+		},
+		{
+			Dc:     "us-east-1",
+			Client: &structs.Client{Name: "bar", Subscriptions: []string{"windows"}},
+			Check:  &structs.Check{Name: "cpu"},
+		},
+		{
+			Dc:     "us-west-1",
+			Client: &structs.Client{Name: "baz", Subscriptions: []string{"linux"}},
+			Check:  &structs.Check{Name: "cpu"},
+		},
+		{Dc: "us-east-1"},
+	}
+
+	result := f.Events(data, newToken(authentication.Role{Subscriptions: []string{"linux"}}))
+	assert.Equal(t, []*structs.Event{data[0], data[1], data[3]}, result)
+
+	result = f.Events(data, newToken(authentication.Role{Datacenters: []string{"us-west-1"}}))
+	assert.Equal(t, []*structs.Event{data[3]}, result)
+}
+
+func TestSilencedAndStashes(t *testing.T) {
+	f := &Uchiwa{}
+	silenced := []*structs.Silence{
+		{ID: "foo", Dc: "us-east-1"},
+		{ID: "bar", Dc: "us-west-1"},
+	}
+	stashes := []*structs.Stash{
+		{Path: "foo", Dc: "us-east-1"},
+		{Path: "bar", Dc: "us-west-1"},
+	}
+	token := newToken(authentication.Role{Datacenters: []string{"us-east-1"}})
+
+	assert.Equal(t, []*structs.Silence{silenced[0]}, f.Silenced(silenced, nil, token))
+	assert.Equal(t, []*structs.Stash{stashes[0]}, f.Stashes(stashes, nil, token))
+}
+
+func TestSilencedAndStashesSubscriptions(t *testing.T) {
+	f := &Uchiwa{}
+	clients := []*structs.Client{
+		{Name: "web", Dc: "us-east-1", Subscriptions: []string{"linux", "client:web"}},
+		{Name: "db", Dc: "us-east-1", Subscriptions: []string{"windows", "client:db"}},
+	}
+	silenced := []*structs.Silence{
+		{ID: "linux:*", Dc: "us-east-1", Subscription: "linux"},
+		{ID: "windows:*", Dc: "us-east-1", Subscription: "windows"},
+		{ID: "client:web:*", Dc: "us-east-1", Subscription: "client:web"},
+		{ID: "client:db:*", Dc: "us-east-1", Subscription: "client:db"},
+		{ID: "*:cpu", Dc: "us-east-1", Check: "cpu"},
+	}
+	stashes := []*structs.Stash{
+		{Path: "silence/web", Dc: "us-east-1"},
+		{Path: "silence/web/cpu", Dc: "us-east-1"},
+		{Path: "silence/db", Dc: "us-east-1"},
+		{Path: "foo", Dc: "us-east-1"},
+	}
+
+	// Without subscriptions, every entry is visible
+	token := newToken(authentication.Role{Datacenters: []string{"us-east-1"}})
+	assert.Equal(t, silenced, f.Silenced(silenced, clients, token))
+	assert.Equal(t, stashes, f.Stashes(stashes, clients, token))
+
+	// Only the entries targeting the role's subscriptions or its clients
+	token = newToken(authentication.Role{Subscriptions: []string{"linux"}})
+	assert.Equal(t, []*structs.Silence{silenced[0], silenced[2]}, f.Silenced(silenced, clients, token))
+	assert.Equal(t, []*structs.Stash{stashes[0], stashes[1]}, f.Stashes(stashes, clients, token))
+
+	// The entries of unknown clients are hidden
+	assert.Equal(t, []*structs.Silence{silenced[0]}, f.Silenced(silenced, nil, token))
+	assert.Empty(t, f.Stashes(stashes, nil, token))
+}
+
+func TestSubscriptions(t *testing.T) {
+	f := &Uchiwa{}
+	data := []structs.Subscription{
+		{Dc: "us-east-1", Name: "linux"},
+		{Dc: "us-east-1", Name: "windows"},
+		{Dc: "us-west-1", Name: "linux"},
+	}
+
+	assert.Equal(t, data, f.Subscriptions(&data, nil))
+
+	result := f.Subscriptions(&data, newToken(authentication.Role{Subscriptions: []string{"linux"}}))
+	assert.Equal(t, []structs.Subscription{data[0], data[2]}, result)
+
+	result = f.Subscriptions(&data, newToken(authentication.Role{Datacenters: []string{"us-west-1"}, Subscriptions: []string{"linux"}}))
+	assert.Equal(t, []structs.Subscription{data[2]}, result)
+}
+
+func TestGetClientRequest(t *testing.T) {
+	f := &Uchiwa{}
+	linux := newToken(authentication.Role{Datacenters: []string{"us-east-1"}, Subscriptions: []string{"linux"}})
+
+	assert.False(t, f.GetClientRequest("us-east-1", []string{"windows"}, nil))
+	assert.False(t, f.GetClientRequest("us-east-1", nil, newToken(authentication.Role{Datacenters: []string{"us-east-1"}})))
+	assert.False(t, f.GetClientRequest("us-east-1", []string{"linux", "web"}, linux))
+	assert.True(t, f.GetClientRequest("us-west-1", []string{"linux"}, linux))
+	assert.True(t, f.GetClientRequest("us-east-1", []string{"windows"}, linux))
+	assert.True(t, f.GetClientRequest("us-east-1", nil, linux))
+}
+
+func TestGetRequest(t *testing.T) {
+	f := &Uchiwa{}
+
+	assert.False(t, f.GetRequest("us-east-1", nil))
+	assert.False(t, f.GetRequest("us-east-1", newToken(authentication.Role{})))
+	assert.False(t, f.GetRequest("us-east-1", newToken(authentication.Role{Datacenters: []string{"us-east-1"}})))
+	assert.True(t, f.GetRequest("us-west-1", newToken(authentication.Role{Datacenters: []string{"us-east-1"}})))
+	assert.False(t, f.GetRequest("us-west-1", newToken(authentication.Role{Subscriptions: []string{"linux"}})))
+}
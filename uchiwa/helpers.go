@@ -1,13 +1,132 @@
 package uchiwa
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/sensu/uchiwa/uchiwa/audit"
+	"github.com/sensu/uchiwa/uchiwa/authentication"
+	"github.com/sensu/uchiwa/uchiwa/config"
+	"github.com/sensu/uchiwa/uchiwa/helpers"
 	"github.com/sensu/uchiwa/uchiwa/logger"
 	"github.com/sensu/uchiwa/uchiwa/sensu"
+	"github.com/sensu/uchiwa/uchiwa/structs"
 )
 
+// getTLSConfig returns the TLS configuration of the web server. Client
+// certificates are verified against the client CA bundle, if provided, and
+// are required unless the client auth is optional
+func getTLSConfig(ssl config.SSL) (*tls.Config, error) {
+	tlsConfig := &tls.Config{}
+	if ssl.ClientCAFile == "" {
+		return tlsConfig, nil
+	}
+
+	pem, err := ioutil.ReadFile(ssl.ClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("Could not read the client CA bundle: %s", err)
+	}
+
+	tlsConfig.ClientCAs = x509.NewCertPool()
+	if !tlsConfig.ClientCAs.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("Could not find any certificate in the client CA bundle '%s'", ssl.ClientCAFile)
+	}
+
+	switch ssl.ClientAuth {
+	case "", "require":
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	case "optional":
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	default:
+		return nil, fmt.Errorf("Invalid client auth '%s', it must be either require or optional", ssl.ClientAuth)
+	}
+
+	return tlsConfig, nil
+}
+
+// getAuditFilter builds the audit log filter from the query string of the
+// request. The time range is expressed in RFC 3339
+func getAuditFilter(r *http.Request) (audit.Filter, error) {
+	query := r.URL.Query()
+	filter := audit.Filter{
+		Action:     query.Get("action"),
+		Dc:         query.Get("dc"),
+		RemoteAddr: query.Get("remoteaddr"),
+		User:       query.Get("user"),
+	}
+
+	var err error
+	if v := query.Get("from"); v != "" {
+		if filter.From, err = time.Parse(time.RFC3339, v); err != nil {
+			return filter, fmt.Errorf("invalid from parameter: %s", err)
+		}
+	}
+	if v := query.Get("to"); v != "" {
+		if filter.To, err = time.Parse(time.RFC3339, v); err != nil {
+			return filter, fmt.Errorf("invalid to parameter: %s", err)
+		}
+	}
+	if v := query.Get("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil {
+			return filter, fmt.Errorf("invalid limit parameter: %s", err)
+		}
+	}
+	if v := query.Get("offset"); v != "" {
+		if filter.Offset, err = strconv.Atoi(v); err != nil {
+			return filter, fmt.Errorf("invalid offset parameter: %s", err)
+		}
+	}
+
+	return filter, nil
+}
+
+// auditLog records in the audit log the action performed by the user on the
+// datacenter, along with its error if the action failed
+func auditLog(r *http.Request, action, dc string, err error) {
+	log := structs.AuditLog{Action: action, Dc: dc, Level: audit.LevelDefault, URL: r.URL.String()}
+	log.RemoteAddr = helpers.GetIP(r)
+
+	if token := authentication.GetJWTFromContext(r); token != nil {
+		log.User, _ = token.Claims["Username"].(string)
+	}
+
+	if err != nil {
+		log.Output = err.Error()
+	}
+
+	if err := audit.Log(log); err != nil {
+		logger.Warningf("Could not write to the audit log: %s", err)
+	}
+}
+
+// requestContext returns the context of the requests made to the Sensu APIs on
+// behalf of r. It is canceled once the client goes away or the deadline of the
+// datacenters is exceeded
+func (u *Uchiwa) requestContext(r *http.Request) (context.Context, context.CancelFunc) {
+	if u.Config == nil || u.Config.Uchiwa.Deadline <= 0 {
+		return context.WithCancel(r.Context())
+	}
+	return context.WithTimeout(r.Context(), time.Duration(u.Config.Uchiwa.Deadline)*time.Second)
+}
+
+// sensuError replies to the request with an error returned by a datacenter.
+// The status code is determined by the error when every API of the datacenter
+// failed, e.g. 404 if the resource does not exist or 504 if the APIs timed out,
+// and is the provided code otherwise
+func sensuError(w http.ResponseWriter, err error, code int) {
+	if e, ok := err.(*sensu.Error); ok {
+		code = e.StatusCode()
+	}
+	http.Error(w, err.Error(), code)
+}
+
 func getAPI(datacenters *[]sensu.Sensu, name string) (*sensu.Sensu, error) {
 	if len(*datacenters) == 1 {
 		return &(*datacenters)[0], nil
@@ -26,15 +145,10 @@ func getAPI(datacenters *[]sensu.Sensu, name string) (*sensu.Sensu, error) {
 	return nil, fmt.Errorf("Could not find the datacenter '%s'", name)
 }
 
-func findModel(id string, dc string, checks []interface{}) map[string]interface{} {
+func findModel(id string, dc string, checks []*structs.Check) *structs.Check {
 	for _, k := range checks {
-		m, ok := k.(map[string]interface{})
-		if !ok {
-			logger.Warningf("Could not assert check interface %+v", k)
-			continue
-		}
-		if m["name"] == id && m["dc"] == dc {
-			return m
+		if k.Name == id && k.Dc == dc {
+			return k
 		}
 	}
 	return nil
@@ -6,86 +6,62 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"strings"
 
 	"github.com/sensu/uchiwa/uchiwa/logger"
 	"github.com/sensu/uchiwa/uchiwa/structs"
 )
 
-// BuildClientsMetrics builds the metrics for the events
-func BuildClientsMetrics(clients *[]interface{}) *structs.StatusMetrics {
+// BuildClientsMetrics builds the metrics for the clients
+func BuildClientsMetrics(clients []*structs.Client) *structs.StatusMetrics {
 	metrics := structs.StatusMetrics{}
 
-	metrics.Total = len(*clients)
-	for _, c := range *clients {
-		client := c.(map[string]interface{})
-
-		silenced, ok := client["silenced"].(bool)
-		if ok { // Do not ignore the client if we don't have a silenced attribute
-			if silenced {
-				metrics.Silenced++
-				continue
-			}
-		}
-
-		status, ok := client["status"].(int)
-		if !ok {
-			logger.Warningf("Could not assert the status to an int: %+v", client["status"])
+	metrics.Total = len(clients)
+	for _, client := range clients {
+		if client.Silenced {
+			metrics.Silenced++
 			continue
 		}
 
-		if status == 2.0 {
+		switch client.Status {
+		case 2:
 			metrics.Critical++
-			continue
-		} else if status == 1.0 {
+		case 1:
 			metrics.Warning++
-			continue
-		} else if status == 0.0 {
+		case 0:
 			metrics.Healthy++
-			continue
+		default:
+			metrics.Unknown++
 		}
-		metrics.Unknown++
 	}
 
 	return &metrics
 }
 
 // BuildEventsMetrics builds the metrics for the events
-func BuildEventsMetrics(events *[]interface{}) *structs.StatusMetrics {
+func BuildEventsMetrics(events []*structs.Event) *structs.StatusMetrics {
 	metrics := structs.StatusMetrics{}
 
-	metrics.Total = len(*events)
-
-	for _, e := range *events {
-		event := e.(map[string]interface{})
-
-		silenced, ok := event["silenced"].(bool)
-		if ok { // Do not ignore the event if we don't have a silenced attribute
-			if silenced {
-				metrics.Silenced++
-				continue
-			}
-		}
-
-		check, ok := event["check"].(map[string]interface{})
-		if !ok {
-			logger.Warningf("Could not assert this check to an interface: %+v", event["check"])
+	metrics.Total = len(events)
+	for _, event := range events {
+		if event.Silenced {
+			metrics.Silenced++
 			continue
 		}
 
-		status, ok := check["status"].(float64)
-		if !ok {
-			logger.Warningf("Could not assert this status to a flot64: %+v", check["status"])
+		if event.Check == nil {
+			logger.Warningf("The event has no check: %+v", event)
 			continue
 		}
 
-		if status == 2.0 {
+		switch event.Check.Status {
+		case 2:
 			metrics.Critical++
-			continue
-		} else if status == 1.0 {
+		case 1:
 			metrics.Warning++
-			continue
+		default:
+			metrics.Unknown++
 		}
-		metrics.Unknown++
 	}
 
 	return &metrics
@@ -107,50 +83,20 @@ func GetBoolFromInterface(i interface{}) (bool, error) {
 	return b, nil
 }
 
-// GetEvent returns an event associated to a specific check
-func GetEvent(check, client, dc string, events *[]interface{}) (map[string]interface{}, error) {
-	if check == "" || client == "" || dc == "" || len(*events) == 0 {
+// GetEvent returns the event of a specific check on a specific client
+func GetEvent(check, client, dc string, events []*structs.Event) (*structs.Event, error) {
+	if check == "" || client == "" || dc == "" || len(events) == 0 {
 		return nil, errors.New("No parameters should be empty")
 	}
 
-	for _, e := range *events {
-		event, ok := e.(map[string]interface{})
-		if !ok {
-			continue
-		}
-
-		if event["dc"] == nil || event["dc"] != dc {
+	for _, event := range events {
+		if event.Dc != dc || event.Client == nil || event.Check == nil {
 			continue
 		}
 
-		c, ok := event["client"].(map[string]interface{})
-		if !ok {
-			if event["client"] == nil || event["client"] != client {
-				continue
-			}
-		} else if c["name"] == nil || c["name"] != client {
-			continue
-		}
-
-		k, ok := event["check"].(map[string]interface{})
-		if !ok {
-			if event["check"] == nil || event["check"] != check {
-				continue
-			} else {
-				return map[string]interface{}{"check": event["check"], "client": event["client"], "occurrences": event["occurrences"], "output": event["output"], "status": event["status"]}, nil
-			}
-		} else if k["name"] == nil || k["name"] != check {
-			continue
+		if event.Client.Name == client && event.Check.Name == check {
+			return event, nil
 		}
-
-		if event["action"] != nil {
-			k["action"] = event["action"]
-		}
-		if event["occurrences"] != nil {
-			k["occurrences"] = event["occurrences"]
-		}
-
-		return k, nil
 	}
 
 	return nil, errors.New("No event found")
@@ -195,78 +141,130 @@ func GetIP(r *http.Request) string {
 	if xForwardedFor := r.Header.Get("X-FORWARDED-FOR"); len(xForwardedFor) > 0 {
 		return xForwardedFor
 	}
-	ip, _, _ := net.SplitHostPort(r.RemoteAddr)
-	return ip
+	return GetRemoteIP(r)
 }
 
-// IsCheckSilenced determines whether a check for a particular client is silenced.
-// Returns true if the check is silenced and a slice of silence entries IDs
-func IsCheckSilenced(check map[string]interface{}, client, dc string, silenced []interface{}) (bool, []string) {
-	var isSilenced bool
-	var isSilencedBy []string
-	var subscribers []interface{}
+// GetClientIP returns the IP address of the client. The X-Forwarded-For header
+// is only considered when the peer is one of the trusted proxies, in which
+// case the last address appended by an untrusted hop is returned
+func GetClientIP(r *http.Request, trusted []*net.IPNet) string {
+	ip := GetRemoteIP(r)
+	if !IsTrusted(net.ParseIP(ip), trusted) {
+		return ip
+	}
 
-	if client == "" || dc == "" || len(silenced) == 0 {
-		return false, isSilencedBy
+	var hops []string
+	for _, header := range r.Header[http.CanonicalHeaderKey("X-Forwarded-For")] {
+		hops = append(hops, strings.Split(header, ",")...)
 	}
 
-	checkName, ok := check["name"].(string)
-	if !ok {
-		return false, isSilencedBy
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if net.ParseIP(hop) == nil {
+			break
+		}
+		ip = hop
+		if !IsTrusted(net.ParseIP(hop), trusted) {
+			break
+		}
 	}
+	return ip
+}
 
-	if check["subscribers"] != nil {
-		subscribers, ok = check["subscribers"].([]interface{})
-		if !ok {
-			return false, isSilencedBy
+// IsTrusted returns true if the IP address is part of the trusted networks
+func IsTrusted(ip net.IP, trusted []*net.IPNet) bool {
+	if ip == nil {
+		return false
+	}
+
+	for _, network := range trusted {
+		if network.Contains(ip) {
+			return true
 		}
 	}
+	return false
+}
 
-	for _, silence := range silenced {
-		m, ok := silence.(map[string]interface{})
-		if !ok {
-			logger.Warningf("Could not assert this silence entry to a map: %+v", silence)
-			continue
+// ParseNetworks parses the provided CIDR blocks, accepting single IP
+// addresses as well
+func ParseNetworks(cidrs []string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, cidr := range cidrs {
+		if !strings.Contains(cidr, "/") {
+			if ip := net.ParseIP(cidr); ip != nil && ip.To4() != nil {
+				cidr += "/32"
+			} else {
+				cidr += "/128"
+			}
+		}
+
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy '%s': %s", cidr, err)
 		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// GetRemoteIP returns the IP address of the peer, e.g. a reverse proxy,
+// ignoring the X-Forwarded-For header which can be forged by the user
+func GetRemoteIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return ip
+}
 
-		if m["dc"] != dc {
+// IsCheckSilenced determines whether a check for a particular client is silenced.
+// Returns true if the check is silenced and a slice of silence entries IDs
+func IsCheckSilenced(check *structs.Check, client, dc string, silenced []*structs.Silence) (bool, []string) {
+	var isSilenced bool
+	var isSilencedBy []string
+
+	if check == nil || check.Name == "" || client == "" || dc == "" || len(silenced) == 0 {
+		return false, isSilencedBy
+	}
+
+	for _, silence := range silenced {
+		if silence.Dc != dc {
 			continue
 		}
 
 		// Check (e.g. *:check_cpu)
-		if m["id"] == fmt.Sprintf("*:%s", checkName) {
+		if silence.ID == fmt.Sprintf("*:%s", check.Name) {
 			isSilenced = true
-			isSilencedBy = append(isSilencedBy, m["id"].(string))
+			isSilencedBy = append(isSilencedBy, silence.ID)
 			continue
 		}
 
 		// Client subscription (e.g. client:foo:* )
-		if m["id"] == fmt.Sprintf("client:%s:*", client) {
+		if silence.ID == fmt.Sprintf("client:%s:*", client) {
 			isSilenced = true
-			isSilencedBy = append(isSilencedBy, m["id"].(string))
+			isSilencedBy = append(isSilencedBy, silence.ID)
 			continue
 		}
 
 		// Client's check subscription (e.g. client:foo:check_cpu )
-		if m["id"] == fmt.Sprintf("client:%s:%s", client, checkName) {
+		if silence.ID == fmt.Sprintf("client:%s:%s", client, check.Name) {
 			isSilenced = true
-			isSilencedBy = append(isSilencedBy, m["id"].(string))
+			isSilencedBy = append(isSilencedBy, silence.ID)
 			continue
 		}
 
-		for _, s := range subscribers {
-			subscription := s.(string)
+		for _, subscription := range check.Subscribers {
 			// Subscription (e.g. load-balancer:* )
-			if m["id"] == fmt.Sprintf("%s:*", subscription) {
+			if silence.ID == fmt.Sprintf("%s:*", subscription) {
 				isSilenced = true
-				isSilencedBy = append(isSilencedBy, m["id"].(string))
+				isSilencedBy = append(isSilencedBy, silence.ID)
 				continue
 			}
 
 			// Subscription' check (e.g. load-balancer:check_cpu)
-			if m["id"] == fmt.Sprintf("%s:%s", subscription, checkName) {
+			if silence.ID == fmt.Sprintf("%s:%s", subscription, check.Name) {
 				isSilenced = true
-				isSilencedBy = append(isSilencedBy, m["id"].(string))
+				isSilencedBy = append(isSilencedBy, silence.ID)
 				continue
 			}
 		}
@@ -278,18 +276,13 @@ func IsCheckSilenced(check map[string]interface{}, client, dc string, silenced [
 
 // IsClientSilenced determines whether a client is silenced.
 // Returns true if the client is silenced.
-func IsClientSilenced(client, dc string, silenced []interface{}) bool {
+func IsClientSilenced(client, dc string, silenced []*structs.Silence) bool {
 	if client == "" || dc == "" || len(silenced) == 0 {
 		return false
 	}
 
 	for _, silence := range silenced {
-		m, ok := silence.(map[string]interface{})
-		if !ok {
-			continue
-		}
-
-		if m["dc"] == dc && m["id"] == fmt.Sprintf("client:%s:*", client) {
+		if silence.Dc == dc && silence.ID == fmt.Sprintf("client:%s:*", client) {
 			return true
 		}
 	}
@@ -1,6 +1,7 @@
 package helpers
 
 import (
+	"net/http"
 	"testing"
 
 	"github.com/sensu/uchiwa/uchiwa/structs"
@@ -8,26 +9,26 @@ import (
 )
 
 func TestBuildClientsMetrics(t *testing.T) {
-	clients := []interface{}{map[string]interface{}{"status": 0}, map[string]interface{}{"status": 1}, map[string]interface{}{"status": 2}, map[string]interface{}{"status": 3}}
+	clients := []*structs.Client{{Status: 0}, {Status: 1}, {Status: 2}, {Status: 3}}
 	expectedMetrics := structs.StatusMetrics{Critical: 1, Healthy: 1, Total: 4, Unknown: 1, Warning: 1}
-	metrics := BuildClientsMetrics(&clients)
+	metrics := BuildClientsMetrics(clients)
 	assert.Equal(t, expectedMetrics, *metrics)
 
-	clients = []interface{}{map[string]interface{}{"status": 1}, map[string]interface{}{"silenced": true, "status": 1}, map[string]interface{}{"silenced": false, "status": 2}}
+	clients = []*structs.Client{{Status: 1}, {Silenced: true, Status: 1}, {Silenced: false, Status: 2}}
 	expectedMetrics = structs.StatusMetrics{Critical: 1, Silenced: 1, Total: 3, Warning: 1}
-	metrics = BuildClientsMetrics(&clients)
+	metrics = BuildClientsMetrics(clients)
 	assert.Equal(t, expectedMetrics, *metrics)
 }
 
 func TestBuildEventsMetrics(t *testing.T) {
-	events := []interface{}{map[string]interface{}{"check": map[string]interface{}{"status": 1.0}}, map[string]interface{}{"check": map[string]interface{}{"status": 2.0}}, map[string]interface{}{"check": map[string]interface{}{"status": 3.0}}}
+	events := []*structs.Event{{Check: &structs.Check{Status: 1}}, {Check: &structs.Check{Status: 2}}, {Check: &structs.Check{Status: 3}}}
 	expectedMetrics := structs.StatusMetrics{Critical: 1, Total: 3, Unknown: 1, Warning: 1}
-	metrics := BuildEventsMetrics(&events)
+	metrics := BuildEventsMetrics(events)
 	assert.Equal(t, expectedMetrics, *metrics)
 
-	events = []interface{}{map[string]interface{}{"check": map[string]interface{}{"status": 1.0}}, map[string]interface{}{"check": map[string]interface{}{"status": 2.0}, "silenced": true}}
-	expectedMetrics = structs.StatusMetrics{Silenced: 1, Total: 2, Warning: 1}
-	metrics = BuildEventsMetrics(&events)
+	events = []*structs.Event{{Check: &structs.Check{Status: 1}}, {Check: &structs.Check{Status: 2}, Silenced: true}, {}}
+	expectedMetrics = structs.StatusMetrics{Silenced: 1, Total: 3, Warning: 1}
+	metrics = BuildEventsMetrics(events)
 	assert.Equal(t, expectedMetrics, *metrics)
 }
 
@@ -42,47 +43,78 @@ func TestGetBoolFromInterface(t *testing.T) {
 	assert.Equal(t, b, true)
 }
 
+func TestGetClientIP(t *testing.T) {
+	trusted, err := ParseNetworks([]string{"10.0.0.1", "172.16.0.0/12"})
+	assert.Nil(t, err)
+
+	var tests = []struct {
+		remoteAddr   string
+		forwardedFor string
+		expected     string
+	}{
+		{"192.168.0.1:5000", "", "192.168.0.1"},
+		{"192.168.0.1:5000", "192.168.0.2", "192.168.0.1"},
+		{"10.0.0.1:5000", "", "10.0.0.1"},
+		{"10.0.0.1:5000", "192.168.0.2", "192.168.0.2"},
+		{"10.0.0.1:5000", "192.168.0.3, 192.168.0.2", "192.168.0.2"},
+		{"10.0.0.1:5000", "192.168.0.2, 172.16.0.1", "192.168.0.2"},
+		{"10.0.0.1:5000", "172.16.0.2, 172.16.0.1", "172.16.0.2"},
+		{"10.0.0.1:5000", "foo", "10.0.0.1"},
+	}
+
+	for _, tt := range tests {
+		r, _ := http.NewRequest("GET", "/", nil)
+		r.RemoteAddr = tt.remoteAddr
+		if tt.forwardedFor != "" {
+			r.Header.Set("X-Forwarded-For", tt.forwardedFor)
+		}
+		assert.Equal(t, tt.expected, GetClientIP(r, trusted), "%s %s", tt.remoteAddr, tt.forwardedFor)
+	}
+
+	_, err = ParseNetworks([]string{"foo"})
+	assert.NotNil(t, err)
+}
+
 func TestGetEvent(t *testing.T) {
 	var check, client, dc string
-	var events = []interface{}{}
+	var events []*structs.Event
 
-	_, err := GetEvent(check, client, dc, &events)
+	_, err := GetEvent(check, client, dc, events)
 	assert.NotNil(t, err)
 
 	check = "ram"
-	_, err = GetEvent(check, client, dc, &events)
+	_, err = GetEvent(check, client, dc, events)
 	assert.NotNil(t, err)
 
 	client = "bar"
-	_, err = GetEvent(check, client, dc, &events)
+	_, err = GetEvent(check, client, dc, events)
 	assert.NotNil(t, err)
 
 	dc = "us-west-1"
-	_, err = GetEvent(check, client, dc, &events)
+	_, err = GetEvent(check, client, dc, events)
 	assert.NotNil(t, err)
 
-	events = []interface{}{map[string]interface{}{"check": map[string]interface{}{"name": "cpu", "status": "1"}, "client": map[string]interface{}{"name": "foo"}, "dc": "us-east-1"}}
-	_, err = GetEvent(check, client, dc, &events)
+	events = []*structs.Event{{Check: &structs.Check{Name: "cpu", Status: 1}, Client: &structs.Client{Name: "foo"}, Dc: "us-east-1"}}
+	_, err = GetEvent(check, client, dc, events)
 	assert.NotNil(t, err, "Wrong datacenter")
 
 	dc = "us-east-1"
-	_, err = GetEvent(check, client, dc, &events)
+	_, err = GetEvent(check, client, dc, events)
 	assert.NotNil(t, err, "Wrong client")
 
 	client = "foo"
-	_, err = GetEvent(check, client, dc, &events)
+	_, err = GetEvent(check, client, dc, events)
 	assert.NotNil(t, err, "Wrong check")
 
 	check = "cpu"
-	event, err := GetEvent(check, client, dc, &events)
+	event, err := GetEvent(check, client, dc, events)
 	assert.Nil(t, err)
-	assert.Equal(t, map[string]interface{}{"name": "cpu", "status": "1"}, event)
+	assert.Equal(t, events[0], event)
 
-	// Sensu <= 0.12 events
-	events = []interface{}{map[string]interface{}{"check": "cpu", "client": "foo", "dc": "us-east-1", "occurrences": 10, "output": "CRITICAL", "status": "2"}}
-	event, err = GetEvent(check, client, dc, &events)
-	assert.Nil(t, err)
-	assert.Equal(t, map[string]interface{}{"check": "cpu", "client": "foo", "occurrences": 10, "output": "CRITICAL", "status": "2"}, event)
+	// Sensu <= 0.12 events, whose check and client are not objects
+	events = []*structs.Event{{Dc: "us-east-1", Attributes: structs.Attributes{"check": "cpu", "client": "foo"}}}
+	_, err = GetEvent(check, client, dc, events)
+	assert.NotNil(t, err)
 }
 
 func TestGetInterfacesFromBytes(t *testing.T) {
@@ -120,64 +152,64 @@ func TestGetMapFromInterface(t *testing.T) {
 }
 
 func TestIsCheckSilenced(t *testing.T) {
-	var check map[string]interface{}
+	var check *structs.Check
 	var client, dc string
-	var silenced []interface{}
+	var silenced []*structs.Silence
 	var isSilencedBy []string
 
 	isSilenced, _ := IsCheckSilenced(check, client, dc, silenced)
 	assert.False(t, isSilenced)
 
 	// Not silenced
-	check = map[string]interface{}{"name": "check_cpu", "subscribers": []interface{}{"load-balancer"}}
+	check = &structs.Check{Name: "check_cpu", Subscribers: []string{"load-balancer"}}
 	client = "foo"
 	dc = "us-east-1"
 	isSilenced, _ = IsCheckSilenced(check, client, dc, silenced)
 	assert.False(t, isSilenced)
 
 	// Wrong datacenter
-	silenced = []interface{}{map[string]interface{}{"dc": "us-west-1", "client": "foo", "check": "check_cpu"}}
+	silenced = []*structs.Silence{{Dc: "us-west-1", Check: "check_cpu"}}
 	isSilenced, _ = IsCheckSilenced(check, client, dc, silenced)
 	assert.False(t, isSilenced)
 
 	// Silenced check with check
 	// e.g. *:check_cpu
-	silenced = []interface{}{map[string]interface{}{"dc": "us-east-1", "id": "*:check_cpu"}}
+	silenced = []*structs.Silence{{Dc: "us-east-1", ID: "*:check_cpu"}}
 	isSilenced, isSilencedBy = IsCheckSilenced(check, client, dc, silenced)
 	assert.True(t, isSilenced)
 	assert.Equal(t, "*:check_cpu", isSilencedBy[0])
 
 	// Silenced check with client subscription
 	// e.g. client:foo:*
-	silenced = []interface{}{map[string]interface{}{"dc": "us-east-1", "id": "client:foo:*", "subscription": "client:foo"}}
+	silenced = []*structs.Silence{{Dc: "us-east-1", ID: "client:foo:*", Subscription: "client:foo"}}
 	isSilenced, isSilencedBy = IsCheckSilenced(check, client, dc, silenced)
 	assert.True(t, isSilenced)
 	assert.Equal(t, "client:foo:*", isSilencedBy[0])
 
 	// Silenced check with client and check subscription
 	// e.g. client:foo:check_cpu
-	silenced = []interface{}{map[string]interface{}{"dc": "us-east-1", "id": "client:foo:check_cpu", "check": "check_cpu", "subscription": "client:foo"}}
+	silenced = []*structs.Silence{{Dc: "us-east-1", ID: "client:foo:check_cpu", Check: "check_cpu", Subscription: "client:foo"}}
 	isSilenced, isSilencedBy = IsCheckSilenced(check, client, dc, silenced)
 	assert.True(t, isSilenced)
 	assert.Equal(t, "client:foo:check_cpu", isSilencedBy[0])
 
 	// Silenced check with subscription only
 	// e.g. load-balancer:*
-	silenced = []interface{}{map[string]interface{}{"dc": "us-east-1", "id": "load-balancer:*", "subscription": "load-balancer"}}
+	silenced = []*structs.Silence{{Dc: "us-east-1", ID: "load-balancer:*", Subscription: "load-balancer"}}
 	isSilenced, isSilencedBy = IsCheckSilenced(check, client, dc, silenced)
 	assert.True(t, isSilenced)
 	assert.Equal(t, "load-balancer:*", isSilencedBy[0])
 
 	// Silenced check with *check* and *subscription*
 	// e.g. load-balancer:check_cpu
-	silenced = []interface{}{map[string]interface{}{"dc": "us-east-1", "id": "load-balancer:check_cpu", "check": "check_cpu", "subscription": "load-balancer"}}
+	silenced = []*structs.Silence{{Dc: "us-east-1", ID: "load-balancer:check_cpu", Check: "check_cpu", Subscription: "load-balancer"}}
 	isSilenced, isSilencedBy = IsCheckSilenced(check, client, dc, silenced)
 	assert.True(t, isSilenced)
 	assert.Equal(t, "load-balancer:check_cpu", isSilencedBy[0])
 
 	// Silenced check with multiple subscriptions
-	silenced = append(silenced, map[string]interface{}{"dc": "us-east-1", "id": "load-balancer:*", "subscription": "load-balancer"})
-	silenced = append(silenced, map[string]interface{}{"dc": "us-east-1", "id": "client:foo:*", "subscription": "client:foo"})
+	silenced = append(silenced, &structs.Silence{Dc: "us-east-1", ID: "load-balancer:*", Subscription: "load-balancer"})
+	silenced = append(silenced, &structs.Silence{Dc: "us-east-1", ID: "client:foo:*", Subscription: "client:foo"})
 	isSilenced, isSilencedBy = IsCheckSilenced(check, client, dc, silenced)
 	assert.True(t, isSilenced)
 	assert.Equal(t, 3, len(isSilencedBy))
@@ -186,8 +218,8 @@ func TestIsCheckSilenced(t *testing.T) {
 	assert.Equal(t, "client:foo:*", isSilencedBy[2])
 
 	// Standalone check
-	check = map[string]interface{}{"name": "check_cpu"}
-	silenced = []interface{}{map[string]interface{}{"dc": "us-east-1", "id": "*:check_cpu"}}
+	check = &structs.Check{Name: "check_cpu"}
+	silenced = []*structs.Silence{{Dc: "us-east-1", ID: "*:check_cpu"}}
 	isSilenced, isSilencedBy = IsCheckSilenced(check, client, dc, silenced)
 	assert.True(t, isSilenced)
 	assert.Equal(t, "*:check_cpu", isSilencedBy[0])
@@ -195,7 +227,7 @@ func TestIsCheckSilenced(t *testing.T) {
 
 func TestIsClientSilenced(t *testing.T) {
 	var client, dc string
-	var silenced []interface{}
+	var silenced []*structs.Silence
 
 	isSilenced := IsClientSilenced(client, dc, silenced)
 	assert.False(t, isSilenced)
@@ -207,17 +239,17 @@ func TestIsClientSilenced(t *testing.T) {
 	assert.False(t, isSilenced)
 
 	// Wrong datacenter
-	silenced = append(silenced, map[string]interface{}{"dc": "us-west-1", "id": "client:foo:*"})
+	silenced = append(silenced, &structs.Silence{Dc: "us-west-1", ID: "client:foo:*"})
 	isSilenced = IsClientSilenced(client, dc, silenced)
 	assert.False(t, isSilenced)
 
 	// Only a check of the client
-	silenced = append(silenced, map[string]interface{}{"dc": "us-east-1", "id": "client:foo:check_cpu"})
+	silenced = append(silenced, &structs.Silence{Dc: "us-east-1", ID: "client:foo:check_cpu"})
 	isSilenced = IsClientSilenced(client, dc, silenced)
 	assert.False(t, isSilenced)
 
 	// Silenced client
-	silenced = append(silenced, map[string]interface{}{"dc": "us-east-1", "id": "client:foo:*"})
+	silenced = append(silenced, &structs.Silence{Dc: "us-east-1", ID: "client:foo:*"})
 	isSilenced = IsClientSilenced(client, dc, silenced)
 	assert.True(t, isSilenced)
 }
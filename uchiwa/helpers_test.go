@@ -1,11 +1,70 @@
 package uchiwa
 
 import (
+	"crypto/tls"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gorilla/context"
+	"github.com/sensu/uchiwa/uchiwa/audit"
+	"github.com/sensu/uchiwa/uchiwa/authentication"
+	"github.com/sensu/uchiwa/uchiwa/config"
+	"github.com/sensu/uchiwa/uchiwa/sensu"
+	"github.com/sensu/uchiwa/uchiwa/structs"
 	"github.com/stretchr/testify/assert"
 )
 
+func TestAuditLog(t *testing.T) {
+	var logs []structs.AuditLog
+	audit.Log = func(log structs.AuditLog) error {
+		logs = append(logs, log)
+		return nil
+	}
+	defer func() { audit.Log = audit.LogMock }()
+
+	r, _ := http.NewRequest("DELETE", "/clients/foo?dc=us-east-1", nil)
+	r.RemoteAddr = "127.0.0.1:5000"
+	token := jwt.New(jwt.GetSigningMethod("RS256"))
+	token.Claims["Username"] = "alice"
+	context.Set(r, authentication.JWTToken, token)
+
+	auditLog(r, "clientdelete", "us-east-1", nil)
+	auditLog(r, "clientdelete", "us-east-1", errors.New("not found"))
+
+	assert.Equal(t, 2, len(logs))
+	assert.Equal(t, structs.AuditLog{
+		Action:     "clientdelete",
+		Dc:         "us-east-1",
+		Level:      audit.LevelDefault,
+		RemoteAddr: "127.0.0.1",
+		URL:        "/clients/foo?dc=us-east-1",
+		User:       "alice",
+	}, logs[0])
+	assert.Equal(t, "not found", logs[1].Output)
+}
+
+func TestSensuError(t *testing.T) {
+	w := httptest.NewRecorder()
+	sensuError(w, errors.New("Could not find the datacenter 'foo'"), http.StatusNotFound)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "Could not find the datacenter 'foo'\n", w.Body.String())
+
+	err := &sensu.Error{Datacenter: "us-east-1", Errors: []*sensu.APIError{
+		{URL: "http://10.0.0.1:4567", Err: errors.New("connection refused")},
+	}}
+	w = httptest.NewRecorder()
+	sensuError(w, err, http.StatusNotFound)
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+	assert.Equal(t, err.Error()+"\n", w.Body.String())
+}
+
 func TestSliceIntersection(t *testing.T) {
 	var a1, a2 []string
 
@@ -44,3 +103,41 @@ func TestMergeStringSlice(t *testing.T) {
 	assert.Equal(t, []string{"1", "2", "3"}, slice, "if one slice is empty, it should return the other slice")
 
 }
+
+func TestGetTLSConfig(t *testing.T) {
+	tlsConfig, err := getTLSConfig(config.SSL{})
+	assert.Nil(t, err)
+	assert.Nil(t, tlsConfig.ClientCAs)
+	assert.Equal(t, tls.NoClientCert, tlsConfig.ClientAuth)
+
+	_, err = getTLSConfig(config.SSL{ClientCAFile: "/nonexistent/ca.pem"})
+	assert.NotNil(t, err)
+
+	dir, err := ioutil.TempDir("", "uchiwa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	invalid := filepath.Join(dir, "invalid.pem")
+	ioutil.WriteFile(invalid, []byte("foo"), 0600)
+	_, err = getTLSConfig(config.SSL{ClientCAFile: invalid})
+	assert.NotNil(t, err)
+
+	server := httptest.NewTLSServer(nil)
+	server.Close()
+	ca := filepath.Join(dir, "ca.pem")
+	ioutil.WriteFile(ca, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0600)
+
+	tlsConfig, err = getTLSConfig(config.SSL{ClientCAFile: ca})
+	assert.Nil(t, err)
+	assert.NotNil(t, tlsConfig.ClientCAs)
+	assert.Equal(t, tls.RequireAndVerifyClientCert, tlsConfig.ClientAuth)
+
+	tlsConfig, err = getTLSConfig(config.SSL{ClientCAFile: ca, ClientAuth: "optional"})
+	assert.Nil(t, err)
+	assert.Equal(t, tls.VerifyClientCertIfGiven, tlsConfig.ClientAuth)
+
+	_, err = getTLSConfig(config.SSL{ClientCAFile: ca, ClientAuth: "foo"})
+	assert.NotNil(t, err)
+}
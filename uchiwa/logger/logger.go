@@ -6,6 +6,7 @@ import (
 	"os"
 	"runtime"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -38,6 +39,10 @@ type Source struct {
 
 var log = new(Logger)
 
+// mu serializes the messages logged concurrently, since they share the log
+// struct
+var mu sync.Mutex
+
 func init() {
 	configuredLevel = INFO
 }
@@ -68,6 +73,9 @@ func (l *Logger) now() {
 }
 
 func (l *Logger) print(level string, format string, args ...interface{}) {
+	mu.Lock()
+	defer mu.Unlock()
+
 	l.now()
 	l.Message = l.message(format, args)
 	l.Level = &level
@@ -1,7 +1,7 @@
 package uchiwa
 
 import (
-	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/sensu/uchiwa/uchiwa/config"
@@ -15,10 +15,13 @@ import (
 type Uchiwa struct {
 	Config       *config.Config
 	Daemon       *daemon.Daemon
-	Data         *structs.Data
 	Datacenters  *[]sensu.Sensu
-	Mu           *sync.Mutex
 	PublicConfig *config.Config
+
+	// data contains the latest *structs.Data snapshot published by the daemon.
+	// A snapshot is never modified once published, so it can be read without
+	// any lock
+	data atomic.Value
 }
 
 // Init method initializes the Sensu structure with the provided configuration and start the Uchiwa daemon
@@ -28,32 +31,41 @@ func Init(c *config.Config) *Uchiwa {
 	datacenters := initDatacenters(c)
 
 	d := &daemon.Daemon{
+		Concurrency: c.Uchiwa.Concurrency,
 		Data:        &structs.Data{},
 		Datacenters: datacenters,
+		Deadline:    time.Duration(c.Uchiwa.Deadline) * time.Second,
 		Enterprise:  c.Uchiwa.Enterprise,
+		StaleLimit:  time.Duration(c.Uchiwa.StaleLimit) * time.Second,
 	}
 
 	u := &Uchiwa{
 		Config:       c,
 		Daemon:       d,
-		Data:         &structs.Data{},
 		Datacenters:  datacenters,
-		Mu:           &sync.Mutex{},
 		PublicConfig: c.GetPublic(),
 	}
+	u.setData(&structs.Data{})
 
 	// start Uchiwa daemon and listen for results over data channel
 	interval := c.Uchiwa.Refresh
 	data := make(chan *structs.Data, 1)
 	go d.Start(interval, data)
-	go u.listener(interval, data)
+	go u.listener(data)
+
+	// probe the unavailable APIs of each datacenter for recovery
+	for i := range *datacenters {
+		go (*datacenters)[i].Probe()
+	}
 
 	return u
 }
 
 // initDatacenters initializes the Datacenters struct by initalizing each
 // datacenter based on the provided configuration and by associating multiple
-// APIs for the same datacenter for failover/load balancing purposes.
+// APIs for the same datacenter for failover/load balancing purposes. The
+// strategy and retry policies of a datacenter are the first ones configured
+// for its APIs
 func initDatacenters(c *config.Config) *[]sensu.Sensu {
 	var datacenters []sensu.Sensu
 
@@ -63,7 +75,15 @@ OUTER:
 		for i, datacenter := range datacenters {
 			if datacenter.Name == api.Name {
 				// Add this API to the corresponding datacenter
-				datacenter.APIs = append(datacenter.APIs, sensu.NewAPI(api.Path, api.URL, api.Timeout, api.User, api.Pass, api.Insecure))
+				datacenter.APIs = append(datacenter.APIs, newAPI(api))
+				if datacenter.Retries == nil {
+					datacenter.Retries = api.Retries
+				}
+				if datacenter.Strategy == "" {
+					datacenter.Strategy = api.Strategy
+				} else if api.Strategy != "" && api.Strategy != datacenter.Strategy {
+					logger.Warningf("Ignoring the strategy %q of the Sensu API %s, the datacenter %s uses the strategy %q", api.Strategy, api.URL, api.Name, datacenter.Strategy)
+				}
 				datacenters[i] = datacenter
 
 				continue OUTER
@@ -71,33 +91,58 @@ OUTER:
 		}
 		// At this point we didn't find any datacenter with the same name
 		// so we will create a new one and add it to the datacenters slice
-		datacenter := sensu.Sensu{Name: api.Name}
-		datacenter.APIs = append(datacenter.APIs, sensu.NewAPI(api.Path, api.URL, api.Timeout, api.User, api.Pass, api.Insecure))
+		datacenter := sensu.NewSensu(api.Name, api.Strategy)
+		datacenter.Retries = api.Retries
+		datacenter.APIs = append(datacenter.APIs, newAPI(api))
 		datacenters = append(datacenters, datacenter)
 	}
 
 	return &datacenters
 }
 
+// newAPI initializes the Sensu API described by the configuration
+func newAPI(api config.SensuConfig) sensu.API {
+	tlsConfig, err := sensu.NewTLSConfig(sensu.TLSOptions{
+		CAFile:     api.CAFile,
+		CertFile:   api.CertFile,
+		KeyFile:    api.KeyFile,
+		Insecure:   api.Insecure,
+		MinVersion: api.TLSMinVersion,
+		ServerName: api.ServerName,
+	})
+	if err != nil {
+		logger.Fatalf("Sensu API %q: %s", api.Name, err)
+	}
+
+	proxy, err := sensu.ParseProxyURL(api.Proxy)
+	if err != nil {
+		logger.Fatalf("Sensu API %q: %s", api.Name, err)
+	}
+
+	a := sensu.NewAPI(api.Path, api.URL, api.Timeout, api.User, api.Pass, tlsConfig, proxy)
+	a.Headers = api.Headers
+	a.Token = api.Token
+	return a
+}
+
 // listener listens on the data channel for messages from the daemon
-// and updates the Data struct with latest results from the Sensu datacenters
-func (u *Uchiwa) listener(interval int, data chan *structs.Data) {
-	for {
-		select {
-		case result := <-data:
-			logger.Trace("Received results on the 'data' channel")
-
-			u.Mu.Lock()
-			u.Data = result
-			u.Mu.Unlock()
-
-			// sleep during the interval
-			timer := time.NewTimer(time.Second * time.Duration(interval))
-			<-timer.C
-		default:
-			// sleep during 1 second
-			timer := time.NewTimer(time.Second * 1)
-			<-timer.C
-		}
+// and publishes the latest results from the Sensu datacenters
+func (u *Uchiwa) listener(data chan *structs.Data) {
+	for result := range data {
+		logger.Trace("Received results on the 'data' channel")
+		u.setData(result)
 	}
 }
+
+// getData returns the latest snapshot of the data, which must not be modified
+func (u *Uchiwa) getData() *structs.Data {
+	if data, ok := u.data.Load().(*structs.Data); ok {
+		return data
+	}
+	return &structs.Data{}
+}
+
+// setData publishes a new snapshot of the data
+func (u *Uchiwa) setData(data *structs.Data) {
+	u.data.Store(data)
+}
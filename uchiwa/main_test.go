@@ -1,9 +1,16 @@
 package uchiwa
 
 import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/sensu/uchiwa/uchiwa/config"
+	"github.com/sensu/uchiwa/uchiwa/filters"
+	"github.com/sensu/uchiwa/uchiwa/structs"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -59,4 +66,83 @@ func TestInitDatacenters(t *testing.T) {
 	assert.Equal(t, 2, len((*datacenters)[1].APIs))
 	assert.Equal(t, "foo", (*datacenters)[0].Name)
 	assert.Equal(t, "bar", (*datacenters)[1].Name)
+
+	// The strategy of a datacenter is the first one configured
+	conf = config.Config{
+		Sensu: []config.SensuConfig{
+			{Name: "foo", URL: "http://10.0.0.1:4567"},
+			{Name: "foo", URL: "http://10.0.0.2:4567", Strategy: "primary-standby"},
+			{Name: "foo", URL: "http://10.0.0.3:4567", Strategy: "round-robin"},
+		},
+	}
+	datacenters = initDatacenters(&conf)
+	assert.Equal(t, 1, len(*datacenters))
+	assert.Equal(t, "primary-standby", (*datacenters)[0].Strategy)
+}
+
+// newSnapshot returns a snapshot containing n clients, built the way the
+// daemon does
+func newSnapshot(n int) *structs.Data {
+	data := &structs.Data{}
+	for i := 0; i < n; i++ {
+		data.Clients = append(data.Clients, &structs.Client{Dc: "us-east-1", Name: fmt.Sprintf("client%d", i)})
+	}
+	data.Dc = []*structs.Datacenter{{Name: "us-east-1", Stats: map[string]int{"clients": n}}}
+	data.Health = structs.Health{Sensu: map[string]structs.SensuHealth{"us-east-1": {Output: "ok"}}, Uchiwa: "ok"}
+	return data
+}
+
+func TestListener(t *testing.T) {
+	Filters = &filters.Uchiwa{}
+	u := &Uchiwa{}
+
+	// An empty snapshot is returned until the daemon publishes any data
+	assert.NotNil(t, u.getData())
+
+	data := make(chan *structs.Data)
+	go u.listener(data)
+
+	const snapshots = 200
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				// Every snapshot is consistent
+				snapshot := u.getData()
+				if len(snapshot.Dc) != 0 {
+					assert.Equal(t, snapshot.Dc[0].Stats["clients"], len(snapshot.Clients))
+				}
+
+				r, _ := http.NewRequest("GET", "/clients", nil)
+				u.clientsHandler(httptest.NewRecorder(), r)
+				r, _ = http.NewRequest("GET", "/health", nil)
+				u.healthHandler(httptest.NewRecorder(), r)
+			}
+		}()
+	}
+
+	for i := 1; i <= snapshots; i++ {
+		data <- newSnapshot(i)
+	}
+
+	// Wait for the last snapshot to be published
+	deadline := time.Now().Add(5 * time.Second)
+	for len(u.getData().Clients) != snapshots && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	close(done)
+	wg.Wait()
+	close(data)
+
+	assert.Equal(t, snapshots, len(u.getData().Clients))
 }
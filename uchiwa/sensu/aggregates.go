@@ -1,33 +1,46 @@
 package sensu
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+
+	"github.com/sensu/uchiwa/uchiwa/structs"
+)
 
 // DeleteAggregate deletes an aggregate using its check name
-func (s *Sensu) DeleteAggregate(name string) error {
-	return s.delete(fmt.Sprintf("aggregates/%s", name))
+func (s *Sensu) DeleteAggregate(ctx context.Context, name string) error {
+	return s.delete(ctx, fmt.Sprintf("aggregates/%s", name))
 }
 
 // GetAggregates returns a slice of all aggregates
-func (s *Sensu) GetAggregates() ([]interface{}, error) {
-	return s.getSlice("aggregates", NoLimit)
+func (s *Sensu) GetAggregates(ctx context.Context) ([]*structs.Aggregate, error) {
+	var aggregates []*structs.Aggregate
+	err := s.getSlice(ctx, "aggregates", NoLimit, &aggregates)
+	return aggregates, err
 }
 
 // GetAggregate returns a map of a specific aggregate corresponding to the provided check name
-func (s *Sensu) GetAggregate(name string) (map[string]interface{}, error) {
-	return s.getMap(fmt.Sprintf("aggregates/%s", name))
+func (s *Sensu) GetAggregate(ctx context.Context, name string) (map[string]interface{}, error) {
+	return s.getMap(ctx, fmt.Sprintf("aggregates/%s", name))
 }
 
 // GetAggregateChecks returns a slice of all checks members of an aggregate
-func (s *Sensu) GetAggregateChecks(name string) ([]interface{}, error) {
-	return s.getSlice(fmt.Sprintf("aggregates/%s/checks", name), NoLimit)
+func (s *Sensu) GetAggregateChecks(ctx context.Context, name string) ([]interface{}, error) {
+	var checks []interface{}
+	err := s.getSlice(ctx, fmt.Sprintf("aggregates/%s/checks", name), NoLimit, &checks)
+	return checks, err
 }
 
 // GetAggregateClients returns a slice of all clients members of an aggregate
-func (s *Sensu) GetAggregateClients(name string) ([]interface{}, error) {
-	return s.getSlice(fmt.Sprintf("aggregates/%s/clients", name), NoLimit)
+func (s *Sensu) GetAggregateClients(ctx context.Context, name string) ([]interface{}, error) {
+	var clients []interface{}
+	err := s.getSlice(ctx, fmt.Sprintf("aggregates/%s/clients", name), NoLimit, &clients)
+	return clients, err
 }
 
 // GetAggregateResults returns a slice of all check result members by severity
-func (s *Sensu) GetAggregateResults(name, severity string) ([]interface{}, error) {
-	return s.getSlice(fmt.Sprintf("aggregates/%s/results/%s", name, severity), NoLimit)
+func (s *Sensu) GetAggregateResults(ctx context.Context, name, severity string) ([]interface{}, error) {
+	var results []interface{}
+	err := s.getSlice(ctx, fmt.Sprintf("aggregates/%s/results/%s", name, severity), NoLimit, &results)
+	return results, err
 }
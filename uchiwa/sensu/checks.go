@@ -1,26 +1,31 @@
 package sensu
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+
+	"github.com/sensu/uchiwa/uchiwa/structs"
 )
 
 // GetChecks returns a slice of all checks
-func (s *Sensu) GetChecks() ([]interface{}, error) {
-	return s.getSlice("checks", NoLimit)
+func (s *Sensu) GetChecks(ctx context.Context) ([]*structs.Check, error) {
+	var checks []*structs.Check
+	err := s.getSlice(ctx, "checks", NoLimit, &checks)
+	return checks, err
 }
 
 // GetCheck returns a map of a specific check corresponding to the provided check name
-func (s *Sensu) GetCheck(check string) (map[string]interface{}, error) {
-	return s.getMap(fmt.Sprintf("checks/%s", check))
+func (s *Sensu) GetCheck(ctx context.Context, check string) (map[string]interface{}, error) {
+	return s.getMap(ctx, fmt.Sprintf("checks/%s", check))
 }
 
 // IssueCheckExecution send a POST request to the /request endpoint in order
 // to issue a check execution request
-func (s *Sensu) IssueCheckExecution(payload interface{}) (map[string]interface{}, error) {
+func (s *Sensu) IssueCheckExecution(ctx context.Context, payload interface{}) (map[string]interface{}, error) {
 	payloadstr, err := json.Marshal(payload)
 	if err != nil {
 		return nil, fmt.Errorf("Stash parsing error: %q returned: %v", err, err)
 	}
-	return s.postPayload(fmt.Sprintf("request"), string(payloadstr[:]))
+	return s.postPayload(ctx, fmt.Sprintf("request"), string(payloadstr[:]))
 }
@@ -1,23 +1,42 @@
 package sensu
 
-import "fmt"
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/sensu/uchiwa/uchiwa/structs"
+)
 
 // GetClients returns a slice of all clients
-func (s *Sensu) GetClients() ([]interface{}, error) {
-	return s.getSlice("clients", DefaultLimit)
+func (s *Sensu) GetClients(ctx context.Context) ([]*structs.Client, error) {
+	var clients []*structs.Client
+	err := s.getSlice(ctx, "clients", DefaultLimit, &clients)
+	return clients, err
 }
 
-// GetClient returns a map of a specific client corresponding to the provided client name
-func (s *Sensu) GetClient(client string) (map[string]interface{}, error) {
-	return s.getMap(fmt.Sprintf("clients/%s", client))
+// GetClient returns a specific client corresponding to the provided client name
+func (s *Sensu) GetClient(ctx context.Context, client string) (*structs.Client, error) {
+	body, _, err := s.getBytes(ctx, fmt.Sprintf("clients/%s", client))
+	if err != nil {
+		return nil, err
+	}
+
+	var c structs.Client
+	if err := json.Unmarshal(body, &c); err != nil {
+		return nil, fmt.Errorf("Could not parse the JSON-encoded response body: %v", err)
+	}
+	return &c, nil
 }
 
 // GetClientHistory returns a slice containing the history of a specific check corresponding to the provided client name
-func (s *Sensu) GetClientHistory(client string) ([]interface{}, error) {
-	return s.getSlice(fmt.Sprintf("clients/%s/history", client), NoLimit)
+func (s *Sensu) GetClientHistory(ctx context.Context, client string) ([]*structs.ClientHistory, error) {
+	var history []*structs.ClientHistory
+	err := s.getSlice(ctx, fmt.Sprintf("clients/%s/history", client), NoLimit, &history)
+	return history, err
 }
 
 // DeleteClient deletes a client using its name
-func (s *Sensu) DeleteClient(client string) error {
-	return s.delete(fmt.Sprintf("clients/%s", client))
+func (s *Sensu) DeleteClient(ctx context.Context, client string) error {
+	return s.delete(ctx, fmt.Sprintf("clients/%s", client))
 }
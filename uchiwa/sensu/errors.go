@@ -0,0 +1,109 @@
+package sensu
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// APIError describes the failure of a request to a Sensu API
+type APIError struct {
+	URL        string
+	StatusCode int // 0 if the API did not respond
+	Err        error
+}
+
+func newAPIError(url string, err error) *APIError {
+	e := &APIError{URL: url, Err: err}
+	if s, ok := err.(*statusError); ok {
+		e.StatusCode = s.code
+	}
+	return e
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s: %v", e.URL, e.Err)
+}
+
+// TLS returns true if the TLS handshake with the API failed, e.g. because its
+// certificate could not be verified
+func (e *APIError) TLS() bool {
+	return isTLSError(e.Err)
+}
+
+// Timeout returns true if the API did not respond in time
+func (e *APIError) Timeout() bool {
+	t, ok := e.Err.(interface {
+		Timeout() bool
+	})
+	return ok && t.Timeout()
+}
+
+// Error describes the failure of a request to the APIs of a datacenter. It
+// contains an APIError for every attempt on an API
+type Error struct {
+	Datacenter string
+	Errors     []*APIError
+}
+
+func (e *Error) Error() string {
+	if len(e.Errors) == 0 {
+		return fmt.Sprintf("No API is configured for the datacenter %s", e.Datacenter)
+	}
+
+	urls := make(map[string]bool)
+	for _, a := range e.Errors {
+		urls[a.URL] = true
+	}
+
+	subject := fmt.Sprintf("The API of the datacenter %s", e.Datacenter)
+	if len(urls) > 1 {
+		subject = fmt.Sprintf("All %d APIs of the datacenter %s", len(urls), e.Datacenter)
+	}
+
+	verb := "failed"
+	if e.timeout() {
+		verb = "timed out"
+	} else if e.all(func(a *APIError) bool { return a.TLS() }) {
+		verb = "failed the TLS handshake"
+	} else if code := e.Errors[0].StatusCode; code != 0 && e.all(func(a *APIError) bool { return a.StatusCode == code }) {
+		verb = fmt.Sprintf("returned %d %s", code, http.StatusText(code))
+	}
+
+	causes := make([]string, len(e.Errors))
+	for i, a := range e.Errors {
+		causes[i] = a.Error()
+	}
+
+	return fmt.Sprintf("%s %s: %s", subject, verb, strings.Join(causes, "; "))
+}
+
+// StatusCode returns the HTTP status code that best describes the error. An
+// API that rejected the request, e.g. with 404 for an unknown client, takes
+// precedence over the unavailable APIs. Otherwise, the datacenter is considered
+// unavailable, with 504 if every API timed out or 502
+func (e *Error) StatusCode() int {
+	for _, a := range e.Errors {
+		if a.StatusCode >= 400 && a.StatusCode < 500 {
+			return a.StatusCode
+		}
+	}
+
+	if e.timeout() {
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusBadGateway
+}
+
+func (e *Error) timeout() bool {
+	return len(e.Errors) > 0 && e.all(func(a *APIError) bool { return a.Timeout() })
+}
+
+func (e *Error) all(f func(*APIError) bool) bool {
+	for _, a := range e.Errors {
+		if !f(a) {
+			return false
+		}
+	}
+	return true
+}
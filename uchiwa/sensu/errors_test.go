@@ -0,0 +1,66 @@
+package sensu
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestError(t *testing.T) {
+	e := &Error{Datacenter: "dc-east"}
+	assert.Equal(t, "No API is configured for the datacenter dc-east", e.Error())
+	assert.Equal(t, http.StatusBadGateway, e.StatusCode())
+
+	// Every API timed out
+	e.Errors = []*APIError{
+		newAPIError("http://10.0.0.1:4567", timeoutError{}),
+		newAPIError("http://10.0.0.2:4567", timeoutError{}),
+		newAPIError("http://10.0.0.3:4567", timeoutError{}),
+	}
+	assert.Equal(t, "All 3 APIs of the datacenter dc-east timed out: http://10.0.0.1:4567: i/o timeout; "+
+		"http://10.0.0.2:4567: i/o timeout; http://10.0.0.3:4567: i/o timeout", e.Error())
+	assert.Equal(t, http.StatusGatewayTimeout, e.StatusCode())
+
+	// An API is down
+	e.Errors[1] = newAPIError("http://10.0.0.2:4567", &statusError{code: 503, status: "503 Service Unavailable"})
+	assert.Equal(t, 503, e.Errors[1].StatusCode)
+	assert.Contains(t, e.Error(), "All 3 APIs of the datacenter dc-east failed: ")
+	assert.Equal(t, http.StatusBadGateway, e.StatusCode())
+
+	// An API does not know the resource
+	e.Errors[2] = newAPIError("http://10.0.0.3:4567", &statusError{code: 404, status: "404 Not Found"})
+	assert.Equal(t, http.StatusNotFound, e.StatusCode())
+
+	e.Errors = []*APIError{newAPIError("http://10.0.0.1:4567", errors.New("connection refused"))}
+	assert.Equal(t, "The API of the datacenter dc-east failed: http://10.0.0.1:4567: connection refused", e.Error())
+	assert.Equal(t, 0, e.Errors[0].StatusCode)
+	assert.Equal(t, http.StatusBadGateway, e.StatusCode())
+}
+
+func TestFailoverError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	// The other API is not tried since the client does not exist
+	s := newTestSensu(StrategyRoundRobin, server.URL, server.URL)
+	_, err := s.GetClient(context.Background(), "foo")
+	e, ok := err.(*Error)
+	assert.True(t, ok)
+	assert.Equal(t, "us-east-1", e.Datacenter)
+	assert.Equal(t, 1, len(e.Errors))
+	assert.Equal(t, server.URL, e.Errors[0].URL)
+	assert.Equal(t, http.StatusNotFound, e.StatusCode())
+	assert.Contains(t, e.Error(), "The API of the datacenter us-east-1 returned 404 Not Found: ")
+}
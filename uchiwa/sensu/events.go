@@ -1,13 +1,20 @@
 package sensu
 
-import "fmt"
+import (
+	"context"
+	"fmt"
 
-// GetEvents returns a slice of all clients
-func (s *Sensu) GetEvents() ([]interface{}, error) {
-	return s.getSlice("events", NoLimit)
+	"github.com/sensu/uchiwa/uchiwa/structs"
+)
+
+// GetEvents returns a slice of all events
+func (s *Sensu) GetEvents(ctx context.Context) ([]*structs.Event, error) {
+	var events []*structs.Event
+	err := s.getSlice(ctx, "events", NoLimit, &events)
+	return events, err
 }
 
 // DeleteEvent delete an event
-func (s *Sensu) DeleteEvent(check, client string) error {
-	return s.delete(fmt.Sprintf("events/%s/%s", client, check))
+func (s *Sensu) DeleteEvent(ctx context.Context, check, client string) error {
+	return s.delete(ctx, fmt.Sprintf("events/%s/%s", client, check))
 }
@@ -0,0 +1,174 @@
+package sensu
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sensu/uchiwa/uchiwa/logger"
+	"github.com/sensu/uchiwa/uchiwa/structs"
+)
+
+// failureThreshold is the number of consecutive failures after which the
+// circuit breaker of an API opens, so the API is skipped until it recovers
+const failureThreshold = 3
+
+// probeInterval is how often the APIs with an open circuit breaker are probed,
+// either in the background or by letting a single request through
+const probeInterval = 10 * time.Second
+
+// latencyWeight is the weight of the latest request in the average latency
+const latencyWeight = 0.2
+
+// apiHealth tracks the requests to an API. A nil *apiHealth is always
+// available and records nothing
+type apiHealth struct {
+	sync.Mutex
+	errors    uint64
+	failures  int // consecutive
+	lastError string
+	tlsError  bool          // the last error occurred during the TLS handshake
+	latency   time.Duration // exponentially weighted moving average
+	requests  uint64
+	retryAt   time.Time // when an open circuit breaker lets a request through
+}
+
+// record records the outcome of a request and returns true if the request
+// opened the circuit breaker
+func (h *apiHealth) record(latency time.Duration, err error) bool {
+	if h == nil {
+		return false
+	}
+
+	h.Lock()
+	defer h.Unlock()
+
+	h.requests++
+	if isFailure(err) {
+		h.errors++
+		h.failures++
+		h.lastError = err.Error()
+		h.tlsError = isTLSError(err)
+		if h.failures >= failureThreshold {
+			h.retryAt = time.Now().Add(probeInterval)
+		}
+		return h.failures == failureThreshold
+	}
+
+	h.failures = 0
+	h.lastError = ""
+	h.retryAt = time.Time{}
+	h.tlsError = false
+	if h.latency == 0 {
+		h.latency = latency
+	} else {
+		h.latency += time.Duration(latencyWeight * float64(latency-h.latency))
+	}
+	return false
+}
+
+// available returns false while the circuit breaker is open
+func (h *apiHealth) available() bool {
+	if h == nil {
+		return true
+	}
+
+	h.Lock()
+	defer h.Unlock()
+	return h.failures < failureThreshold
+}
+
+// allow returns true if a request can be sent to the API, i.e. if its circuit
+// breaker is closed or if it is time to let a single request through an open
+// circuit breaker (half-open)
+func (h *apiHealth) allow() bool {
+	if h == nil {
+		return true
+	}
+
+	h.Lock()
+	defer h.Unlock()
+	if h.failures < failureThreshold {
+		return true
+	}
+
+	now := time.Now()
+	if now.Before(h.retryAt) {
+		return false
+	}
+	h.retryAt = now.Add(probeInterval)
+	return true
+}
+
+// averageLatency returns the average latency of the requests, or 0 if the API
+// was never reached
+func (h *apiHealth) averageLatency() time.Duration {
+	if h == nil {
+		return 0
+	}
+
+	h.Lock()
+	defer h.Unlock()
+	return h.latency
+}
+
+// isFailure returns true if the error indicates that the API is unhealthy. An
+// API that rejects a request, e.g. for an unknown client, is still healthy
+func isFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	if e, ok := err.(*statusError); ok && e.code < 500 {
+		return false
+	}
+	return true
+}
+
+// Health returns the health of each API of the datacenter
+func (s *Sensu) Health() []structs.SensuAPIHealth {
+	health := make([]structs.SensuAPIHealth, len(s.APIs))
+	for i, api := range s.APIs {
+		health[i] = structs.SensuAPIHealth{URL: api.URL, Available: true}
+		if api.health == nil {
+			continue
+		}
+
+		api.health.Lock()
+		health[i].Available = api.health.failures < failureThreshold
+		health[i].Errors = api.health.errors
+		health[i].LastError = api.health.lastError
+		if api.health.tlsError {
+			health[i].TLSError = api.health.lastError
+		}
+		health[i].Latency = int64(api.health.latency / time.Millisecond)
+		health[i].Requests = api.health.requests
+		api.health.Unlock()
+	}
+	return health
+}
+
+// Probe periodically requests the /info endpoint of the APIs with an open
+// circuit breaker, so they are used again once they recover. It never returns
+func (s *Sensu) Probe() {
+	for _ = range time.Tick(probeInterval) {
+		s.probe()
+	}
+}
+
+func (s *Sensu) probe() {
+	for i := range s.APIs {
+		api := &s.APIs[i]
+		if api.health.available() {
+			continue
+		}
+
+		start := time.Now()
+		_, _, err := api.getBytes(context.Background(), "info")
+		api.health.record(time.Since(start), err)
+		if err != nil {
+			logger.Debugf("The Sensu API %s of the datacenter %s is still unavailable: %v", api.URL, s.Name, err)
+			continue
+		}
+		logger.Infof("The Sensu API %s of the datacenter %s is available again", api.URL, s.Name)
+	}
+}
@@ -1,6 +1,7 @@
 package sensu
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 
@@ -9,8 +10,8 @@ import (
 
 // GetInfo returns a pointer to a structs.Info struct containing the
 // Sensu version and the transport and Redis connection information
-func (s *Sensu) GetInfo() (*structs.Info, error) {
-	body, _, err := s.getBytes("info")
+func (s *Sensu) GetInfo(ctx context.Context) (*structs.Info, error) {
+	body, _, err := s.getBytes(ctx, "info")
 	if err != nil {
 		return nil, err
 	}
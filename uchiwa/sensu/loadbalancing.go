@@ -1,99 +1,229 @@
 package sensu
 
 import (
+	"context"
 	"errors"
 	"math/rand"
 	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/sensu/uchiwa/uchiwa/logger"
 )
 
+// These are the strategies used to select the API of a datacenter that
+// receives a request. The other APIs are used, in order, if it fails
+const (
+	// StrategyRandom selects a random API
+	StrategyRandom = "random"
+	// StrategyRoundRobin selects the APIs in turn
+	StrategyRoundRobin = "round-robin"
+	// StrategyPrimaryStandby selects the APIs in the order of the configuration
+	StrategyPrimaryStandby = "primary-standby"
+	// StrategyLeastLatency selects the API with the lowest average latency
+	StrategyLeastLatency = "least-latency"
+)
+
+// IsStrategy returns true if the provided strategy is supported. An empty
+// strategy defaults to StrategyRandom
+func IsStrategy(strategy string) bool {
+	switch strategy {
+	case "", StrategyRandom, StrategyRoundRobin, StrategyPrimaryStandby, StrategyLeastLatency:
+		return true
+	}
+	return false
+}
+
+// errCircuitOpen is the error of the APIs skipped because of their open
+// circuit breaker
+var errCircuitOpen = errors.New("circuit breaker open")
+
+// random is the source of the random strategy. A rand.Rand is not safe for
+// concurrent use, hence the mutex
+var random = struct {
+	sync.Mutex
+	*rand.Rand
+}{Rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+
 // These are the methods directly used by the public methods of the sensu
 // package in order to handle the failover and load balancing between the APIs of a datacenter
 
-func (s *Sensu) delete(endpoint string) error {
-	apis := shuffle(s.APIs)
+func (s *Sensu) delete(ctx context.Context, endpoint string) error {
+	return s.failover(ctx, "DELETE", endpoint, func(api *API) error {
+		return api.delete(ctx, endpoint)
+	})
+}
 
-	var err error
-	for i := 0; i < len(apis); i++ {
-		logger.Infof("DELETE %s/%s", s.APIs[i].URL, endpoint)
-		err = apis[i].delete(endpoint)
-		if err == nil {
-			return err
-		}
-		logger.Warningf("DELETE %s/%s returned: %v", s.APIs[i].URL, endpoint, err)
+func (s *Sensu) getBytes(ctx context.Context, endpoint string) ([]byte, *http.Response, error) {
+	var bytes []byte
+	var res *http.Response
+	err := s.failover(ctx, "GET", endpoint, func(api *API) error {
+		var err error
+		bytes, res, err = api.getBytes(ctx, endpoint)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
 	}
-
-	return err
+	return bytes, res, nil
 }
 
-func (s *Sensu) getBytes(endpoint string) ([]byte, *http.Response, error) {
-	apis := shuffle(s.APIs)
+func (s *Sensu) getSlice(ctx context.Context, endpoint string, limit int, v interface{}) error {
+	return s.failover(ctx, "GET", endpoint, func(api *API) error {
+		return api.getSlice(ctx, endpoint, limit, v)
+	})
+}
 
-	for i := 0; i < len(apis); i++ {
-		logger.Debugf("GET %s/%s", s.APIs[i].URL, endpoint)
-		bytes, res, err := apis[i].getBytes(endpoint)
-		if err == nil {
-			return bytes, res, err
-		}
-		logger.Warningf("GET %s/%s returned: %v", s.APIs[i].URL, endpoint, err)
+func (s *Sensu) getMap(ctx context.Context, endpoint string) (map[string]interface{}, error) {
+	var m map[string]interface{}
+	err := s.failover(ctx, "GET", endpoint, func(api *API) error {
+		var err error
+		m, err = api.getMap(ctx, endpoint)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
-
-	return nil, nil, errors.New("")
+	return m, nil
 }
 
-func (s *Sensu) getSlice(endpoint string, limit int) ([]interface{}, error) {
-	apis := shuffle(s.APIs)
+func (s *Sensu) postPayload(ctx context.Context, endpoint string, payload string) (map[string]interface{}, error) {
+	var m map[string]interface{}
+	err := s.failover(ctx, "POST", endpoint, func(api *API) error {
+		var err error
+		m, err = api.postPayload(ctx, endpoint, payload)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
 
-	for i := 0; i < len(apis); i++ {
-		logger.Debugf("GET %s/%s", s.APIs[i].URL, endpoint)
-		slice, err := apis[i].getSlice(endpoint, limit)
-		if err == nil {
-			return slice, err
-		}
-		logger.Warningf("GET %s/%s returned: %v", s.APIs[i].URL, endpoint, err)
+// failover calls f with the APIs of the datacenter, in the order of the
+// strategy, until it succeeds. The request is attempted again according to
+// the retry policy of its method, unless it can't be safely retried or the
+// context is done. The outcome of each call is recorded in the health of its
+// API. If the request fails, an *Error describing each failure is returned
+func (s *Sensu) failover(ctx context.Context, method, endpoint string, f func(*API) error) error {
+	e := &Error{Datacenter: s.Name}
+	if len(s.APIs) == 0 {
+		return e
 	}
 
-	return nil, errors.New("")
-}
+	// processed is true once a failed attempt might have been processed
+	var processed bool
+
+	policy := s.retryPolicy(method)
+	for attempt := 1; attempt <= policy.Attempts; attempt++ {
+		if attempt > 1 {
+			delay := policy.backoff(attempt - 1)
+			logger.Debugf("Retrying %s %s on the datacenter %s in %s", method, endpoint, s.Name, delay)
+			if !sleep(ctx, delay) {
+				return e
+			}
+		}
 
-func (s *Sensu) getMap(endpoint string) (map[string]interface{}, error) {
-	apis := shuffle(s.APIs)
+		apis := s.apis()
+		if len(apis) == 0 && len(e.Errors) == 0 {
+			// Every circuit breaker is open
+			for i := range s.APIs {
+				e.Errors = append(e.Errors, newAPIError(s.APIs[i].URL, errCircuitOpen))
+			}
+		}
 
-	for i := 0; i < len(apis); i++ {
-		logger.Debugf("GET %s/%s", s.APIs[i].URL, endpoint)
-		m, err := apis[i].getMap(endpoint)
-		if err == nil {
-			return m, err
+		for _, api := range apis {
+			logger.Debugf("%s %s/%s", method, api.URL, endpoint)
+
+			start := time.Now()
+			err := f(api)
+
+			// A request canceled by its context says nothing about the API
+			if ctx.Err() == nil && api.health.record(time.Since(start), err) {
+				logger.Warningf("The Sensu API %s of the datacenter %s failed %d times in a row and will be skipped until it recovers", api.URL, s.Name, failureThreshold)
+			}
+			if err == nil {
+				return nil
+			}
+			if deletedBefore(method, err, processed) {
+				logger.Infof("%s %s/%s returned %v, the resource was deleted by a previous attempt", method, api.URL, endpoint, err)
+				return nil
+			}
+			logger.Warningf("%s %s/%s returned: %v", method, api.URL, endpoint, err)
+			e.Errors = append(e.Errors, newAPIError(api.URL, err))
+			processed = processed || sent(err)
+
+			if ctx.Err() != nil || !retryable(method, err) {
+				return e
+			}
 		}
-		logger.Warningf("GET %s/%s returned: %v", s.APIs[i].URL, endpoint, err)
 	}
 
-	return nil, errors.New("")
+	return e
 }
 
-func (s *Sensu) postPayload(endpoint string, payload string) (map[string]interface{}, error) {
-	apis := shuffle(s.APIs)
+// sleep waits for the provided duration and returns true, or returns false as
+// soon as the context is done
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
 
-	for i := 0; i < len(apis); i++ {
-		logger.Debugf("POST %s/%s", s.APIs[i].URL, endpoint)
-		m, err := apis[i].postPayload(endpoint, payload)
-		if err == nil {
-			return m, err
+// apis returns the APIs of the datacenter in the order of its strategy. The
+// APIs with an open circuit breaker are skipped until they can be probed
+func (s *Sensu) apis() []*API {
+	var apis []*API
+	for _, i := range s.order() {
+		api := &s.APIs[i]
+		if api.health.allow() {
+			apis = append(apis, api)
 		}
-		logger.Warningf("POST %s/%s returned: %v", s.APIs[i].URL, endpoint, err)
 	}
-
-	return nil, errors.New("")
+	return apis
 }
 
-// shuffle the provided []API
-func shuffle(apis []API) []API {
-	rand.Seed(time.Now().UnixNano())
-	for i := range apis {
-		j := rand.Intn(i + 1)
-		apis[i], apis[j] = apis[j], apis[i]
+// order returns the indexes of the APIs in the order of the strategy
+func (s *Sensu) order() []int {
+	n := len(s.APIs)
+	order := make([]int, n)
+
+	switch s.Strategy {
+	case StrategyRoundRobin:
+		var start int
+		if s.next != nil && n > 0 {
+			start = int((atomic.AddUint32(s.next, 1) - 1) % uint32(n))
+		}
+		for i := range order {
+			order[i] = (start + i) % n
+		}
+	case StrategyPrimaryStandby:
+		for i := range order {
+			order[i] = i
+		}
+	case StrategyLeastLatency:
+		// The APIs that were never reached come first so their latency is known
+		latencies := make([]time.Duration, n)
+		for i := range order {
+			order[i] = i
+			latencies[i] = s.APIs[i].health.averageLatency()
+		}
+		sort.SliceStable(order, func(a, b int) bool {
+			return latencies[order[a]] < latencies[order[b]]
+		})
+	default:
+		random.Lock()
+		order = random.Perm(n)
+		random.Unlock()
 	}
-	return apis
+
+	return order
 }
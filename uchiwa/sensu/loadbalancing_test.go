@@ -0,0 +1,169 @@
+package sensu
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestSensu(strategy string, urls ...string) Sensu {
+	s := NewSensu("us-east-1", strategy)
+	for _, url := range urls {
+		s.APIs = append(s.APIs, NewAPI("", url, 1, "", "", nil, nil))
+	}
+	return s
+}
+
+func TestOrder(t *testing.T) {
+	s := newTestSensu(StrategyPrimaryStandby, "a", "b", "c")
+	assert.Equal(t, []int{0, 1, 2}, s.order())
+	assert.Equal(t, []int{0, 1, 2}, s.order())
+
+	s.Strategy = StrategyRoundRobin
+	assert.Equal(t, []int{0, 1, 2}, s.order())
+	assert.Equal(t, []int{1, 2, 0}, s.order())
+	assert.Equal(t, []int{2, 0, 1}, s.order())
+	assert.Equal(t, []int{0, 1, 2}, s.order())
+
+	// The APIs that were never reached come first
+	s.Strategy = StrategyLeastLatency
+	s.APIs[0].health.record(30*time.Millisecond, nil)
+	s.APIs[2].health.record(10*time.Millisecond, nil)
+	assert.Equal(t, []int{1, 2, 0}, s.order())
+
+	s.Strategy = StrategyRandom
+	order := s.order()
+	sort.Ints(order)
+	assert.Equal(t, []int{0, 1, 2}, order)
+
+	// A datacenter without any API
+	s = Sensu{Name: "us-west-1", Strategy: StrategyRoundRobin}
+	assert.Equal(t, []int{}, s.order())
+	assert.NotNil(t, s.delete(context.Background(), "clients/foo"))
+}
+
+func TestFailover(t *testing.T) {
+	var failing int32 = 1
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&failing) == 1 {
+			http.Error(w, "", http.StatusBadGateway)
+			return
+		}
+		fmt.Fprint(w, `{"redis":{"connected":true}}`)
+	}))
+	defer primary.Close()
+
+	standby := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/clients/foo" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `{"redis":{"connected":true}}`)
+	}))
+	defer standby.Close()
+
+	s := newTestSensu(StrategyPrimaryStandby, primary.URL, standby.URL)
+
+	// The standby API is used while the primary API fails
+	for i := 0; i < failureThreshold; i++ {
+		assert.Equal(t, primary.URL, s.apis()[0].URL, "the circuit breaker opened too early")
+		_, err := s.GetInfo(context.Background())
+		assert.Nil(t, err)
+	}
+
+	// The circuit breaker of the primary API is now open
+	assert.Equal(t, standby.URL, s.apis()[0].URL)
+	health := s.Health()
+	assert.Equal(t, false, health[0].Available)
+	assert.Equal(t, uint64(failureThreshold), health[0].Errors)
+	assert.Equal(t, "502 Bad Gateway", health[0].LastError)
+	assert.Equal(t, true, health[1].Available)
+	assert.Equal(t, uint64(failureThreshold), health[1].Requests)
+
+	// An API rejecting a request is not unhealthy
+	_, err := s.GetClient(context.Background(), "foo")
+	assert.NotNil(t, err)
+	assert.Equal(t, true, s.Health()[1].Available)
+	assert.Equal(t, uint64(0), s.Health()[1].Errors)
+
+	// The primary API is still unavailable when probed
+	s.probe()
+	assert.Equal(t, false, s.Health()[0].Available)
+
+	// The primary API is used again once it recovers
+	atomic.StoreInt32(&failing, 0)
+	s.probe()
+	assert.Equal(t, true, s.Health()[0].Available)
+	assert.Equal(t, "", s.Health()[0].LastError)
+	assert.Equal(t, primary.URL, s.apis()[0].URL)
+}
+
+func TestFailoverUnavailable(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		fmt.Fprint(w, `{"redis":{"connected":true}}`)
+	}))
+	defer server.Close()
+
+	s := newTestSensu(StrategyPrimaryStandby, server.URL)
+	for i := 0; i < failureThreshold; i++ {
+		s.APIs[0].health.record(0, fmt.Errorf("connection refused"))
+	}
+	assert.Equal(t, false, s.Health()[0].Available)
+
+	// The API with an open circuit breaker is skipped until it can be probed
+	_, err := s.GetInfo(context.Background())
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), errCircuitOpen.Error())
+	assert.Equal(t, int32(0), atomic.LoadInt32(&requests))
+
+	// A single request is let through once it can be probed
+	s.APIs[0].health.retryAt = time.Now().Add(-time.Second)
+	assert.Equal(t, 1, len(s.apis()))
+	assert.Equal(t, 0, len(s.apis()))
+
+	s.APIs[0].health.retryAt = time.Now().Add(-time.Second)
+	_, err = s.GetInfo(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+	assert.Equal(t, true, s.Health()[0].Available)
+}
+
+func TestFailoverOpenCircuit(t *testing.T) {
+	var requests int32
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		fmt.Fprint(w, `{"redis":{"connected":true}}`)
+	}))
+	defer primary.Close()
+	standby := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"redis":{"connected":true}}`)
+	}))
+	defer standby.Close()
+
+	// The API with an open circuit breaker is not used, even as a last resort
+	s := newTestSensu(StrategyPrimaryStandby, primary.URL, standby.URL)
+	for i := 0; i < failureThreshold; i++ {
+		s.APIs[0].health.record(0, fmt.Errorf("connection refused"))
+	}
+	assert.Equal(t, 1, len(s.apis()))
+	assert.Equal(t, standby.URL, s.apis()[0].URL)
+
+	_, err := s.GetInfo(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&requests))
+}
+
+func TestIsStrategy(t *testing.T) {
+	assert.Equal(t, true, IsStrategy(""))
+	assert.Equal(t, true, IsStrategy(StrategyLeastLatency))
+	assert.Equal(t, false, IsStrategy("fastest"))
+}
@@ -1,6 +1,8 @@
 package sensu
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -18,17 +20,19 @@ import (
 // handle the pagination for example.
 
 // getBytes returns the body of a GET request as []byte
-func (api *API) getBytes(endpoint string) ([]byte, *http.Response, error) {
-	return api.get(fmt.Sprintf("%s/%s", api.URL, endpoint))
+func (api *API) getBytes(ctx context.Context, endpoint string) ([]byte, *http.Response, error) {
+	return api.get(ctx, fmt.Sprintf("%s/%s", api.URL, endpoint))
 }
 
-// getSlice returns the body of a GET request as []interface{}
-func (api *API) getSlice(endpoint string, limit int) ([]interface{}, error) {
+// getSlice decodes the elements returned by a GET request into v, which must
+// be a pointer to a slice. The pages are retrieved when the endpoint supports
+// pagination
+func (api *API) getSlice(ctx context.Context, endpoint string, limit int, v interface{}) error {
 	var offset int
 
 	u, err := url.Parse(fmt.Sprintf("%s/%s", api.URL, endpoint))
 	if err != nil {
-		return nil, fmt.Errorf("Could not parse the URL '%s': %v", u.String(), err)
+		return fmt.Errorf("Could not parse the URL '%s': %v", u.String(), err)
 	}
 
 	// Add limit & offset parameters when required
@@ -39,58 +43,80 @@ func (api *API) getSlice(endpoint string, limit int) ([]interface{}, error) {
 		u.RawQuery = params.Encode()
 	}
 
-	body, res, err := api.get(u.String())
+	body, res, err := api.get(ctx, u.String())
 	if err != nil {
-		return nil, err
+		return err
+	}
+
+	// Decode the body directly if the endpoint does not support pagination
+	if limit == -1 || res.Header.Get("X-Pagination") == "" {
+		if err := json.Unmarshal(body, v); err != nil {
+			return fmt.Errorf("Could not parse the JSON-encoded response body: %v", err)
+		}
+		return nil
+	}
+
+	var list []json.RawMessage
+	if err := json.Unmarshal(body, &list); err != nil {
+		return fmt.Errorf("Could not parse the JSON-encoded response body: %v", err)
 	}
 
-	list, err := helpers.GetInterfacesFromBytes(body)
+	var xPagination structs.XPagination
+	err = json.Unmarshal([]byte(res.Header.Get("X-Pagination")), &xPagination)
 	if err != nil {
-		return nil, fmt.Errorf("Could not parse the JSON-encoded response body: %v", err)
+		logger.Warning(err)
 	}
-	// Verify if the endpoint supports pagination
-	if limit != -1 && res.Header.Get("X-Pagination") != "" {
-		var xPagination structs.XPagination
 
-		err = json.Unmarshal([]byte(res.Header.Get("X-Pagination")), &xPagination)
+	for len(list) < xPagination.Total {
+		offset += limit
+		params := u.Query()
+		params.Set("offset", strconv.Itoa(offset))
+		u.RawQuery = params.Encode()
+
+		body, _, err := api.get(ctx, u.String())
 		if err != nil {
-			logger.Warning(err)
+			return err
+		}
+
+		var partialList []json.RawMessage
+		if err := json.Unmarshal(body, &partialList); err != nil {
+			return fmt.Errorf("Could not parse the JSON-encoded response body: %v", err)
 		}
 
-		for len(list) < xPagination.Total {
-			offset += limit
-			params := u.Query()
-			params.Set("offset", strconv.Itoa(offset))
-			u.RawQuery = params.Encode()
-
-			body, _, err := api.get(u.String())
-			if err != nil {
-				return nil, err
-			}
-
-			partialList, err := helpers.GetInterfacesFromBytes(body)
-			if err != nil {
-				return nil, fmt.Errorf("Could not parse the JSON-encoded response body: %v", err)
-			}
-
-			if len(partialList) == 0 {
-				logger.Debugf("No additional elements found, exiting pagination for %s endpoint", endpoint)
-				break
-			}
-
-			for _, e := range partialList {
-				list = append(list, e)
-			}
+		if len(partialList) == 0 {
+			logger.Debugf("No additional elements found, exiting pagination for %s endpoint", endpoint)
+			break
+		}
+
+		list = append(list, partialList...)
+	}
+
+	return decodeElements(list, v)
+}
+
+// decodeElements decodes the JSON-encoded elements into v, which must be a
+// pointer to a slice
+func decodeElements(elements []json.RawMessage, v interface{}) error {
+	var b bytes.Buffer
+	b.WriteByte('[')
+	for i, e := range elements {
+		if i > 0 {
+			b.WriteByte(',')
 		}
+		b.Write(e)
 	}
+	b.WriteByte(']')
 
-	return list, nil
+	if err := json.Unmarshal(b.Bytes(), v); err != nil {
+		return fmt.Errorf("Could not parse the JSON-encoded response body: %v", err)
+	}
+	return nil
 }
 
 // getSlice returns the body of a GET request as map[string]inteface{}
-func (api *API) getMap(endpoint string) (map[string]interface{}, error) {
+func (api *API) getMap(ctx context.Context, endpoint string) (map[string]interface{}, error) {
 
-	body, _, err := api.get(fmt.Sprintf("%s/%s", api.URL, endpoint))
+	body, _, err := api.get(ctx, fmt.Sprintf("%s/%s", api.URL, endpoint))
 	if err != nil {
 		return nil, err
 	}
@@ -98,7 +124,7 @@ func (api *API) getMap(endpoint string) (map[string]interface{}, error) {
 }
 
 // postPayload sends a POST request to a provided enpoint with the provided payload
-func (api *API) postPayload(endpoint string, payload string) (map[string]interface{}, error) {
+func (api *API) postPayload(ctx context.Context, endpoint string, payload string) (map[string]interface{}, error) {
 
 	url := fmt.Sprintf("%s/%s", api.URL, endpoint)
 
@@ -110,7 +136,7 @@ func (api *API) postPayload(endpoint string, payload string) (map[string]interfa
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Content-Length", fmt.Sprintf("%d", len(payload)))
 
-	body, _, err := api.doRequest(req)
+	body, _, err := api.doRequest(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -122,7 +148,7 @@ func (api *API) postPayload(endpoint string, payload string) (map[string]interfa
 // their equivalent HTTP method (DELETE, GET and POST).
 
 // delete performs a DELETE HTTP request to the provided endpoint
-func (api *API) delete(endpoint string) error {
+func (api *API) delete(ctx context.Context, endpoint string) error {
 	url := fmt.Sprintf("%s/%s", api.URL, endpoint)
 
 	req, err := http.NewRequest("DELETE", url, nil)
@@ -130,32 +156,18 @@ func (api *API) delete(endpoint string) error {
 		return fmt.Errorf("Parsing error: %q returned: %v", err, err)
 	}
 
-	if api.User != "" && api.Pass != "" {
-		req.SetBasicAuth(api.User, api.Pass)
-	}
-
-	res, err := api.Client.Do(req)
-	if err != nil {
-		return err
-	}
-
-	defer res.Body.Close()
-
-	if res.StatusCode >= 400 {
-		return fmt.Errorf("%v", res.Status)
-	}
-
-	return nil
+	_, _, err = api.doRequest(ctx, req)
+	return err
 }
 
 // get returns the body of a GET HTTP request to a provided URL as []byte
-func (api *API) get(u string) ([]byte, *http.Response, error) {
+func (api *API) get(ctx context.Context, u string) ([]byte, *http.Response, error) {
 	req, err := http.NewRequest("GET", u, nil)
 	if err != nil {
 		return nil, nil, fmt.Errorf("Parsing error: %q returned: %v", err, err)
 	}
 
-	body, res, err := api.doRequest(req)
+	body, res, err := api.doRequest(ctx, req)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -164,7 +176,7 @@ func (api *API) get(u string) ([]byte, *http.Response, error) {
 }
 
 // post performs a POST HTTP request to a provided endpoint
-func (api *API) post(endpoint string) (map[string]interface{}, error) {
+func (api *API) post(ctx context.Context, endpoint string) (map[string]interface{}, error) {
 	url := fmt.Sprintf("%s/%s", api.URL, endpoint)
 
 	req, err := http.NewRequest("POST", url, nil)
@@ -172,7 +184,7 @@ func (api *API) post(endpoint string) (map[string]interface{}, error) {
 		return nil, err
 	}
 
-	body, _, err := api.doRequest(req)
+	body, _, err := api.doRequest(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -1,6 +1,7 @@
 package sensu
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,12 +10,12 @@ import (
 )
 
 // Metric returns the Sensu Enterprise metrics for the clients
-func (s *Sensu) Metric(name string) (*structs.SERawMetric, error) {
+func (s *Sensu) Metric(ctx context.Context, name string) (*structs.SERawMetric, error) {
 	if name == "" {
 		return nil, errors.New("Metric name can't be empty")
 	}
 
-	body, _, err := s.getBytes(fmt.Sprintf("%s/%s", "metrics", name))
+	body, _, err := s.getBytes(ctx, fmt.Sprintf("%s/%s", "metrics", name))
 	if err != nil {
 		return nil, err
 	}
@@ -1,26 +1,51 @@
 package sensu
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strings"
 )
 
-// ...
-func (api *API) doRequest(req *http.Request) ([]byte, *http.Response, error) {
-	if api.User != "" && api.Pass != "" {
+// statusError is returned when the API responds with an error status code
+type statusError struct {
+	code   int
+	status string
+}
+
+func (e *statusError) Error() string {
+	return e.status
+}
+
+// doRequest performs the request, which is canceled once the context is done,
+// and returns the body of the response
+func (api *API) doRequest(ctx context.Context, req *http.Request) ([]byte, *http.Response, error) {
+	req = req.WithContext(ctx)
+
+	for name, value := range api.Headers {
+		if strings.EqualFold(name, "Host") {
+			req.Host = value
+			continue
+		}
+		req.Header.Set(name, value)
+	}
+
+	if api.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+api.Token)
+	} else if api.User != "" && api.Pass != "" {
 		req.SetBasicAuth(api.User, api.Pass)
 	}
 
 	res, err := api.Client.Do(req)
 	if err != nil {
-		return nil, nil, fmt.Errorf("%v", err)
+		return nil, nil, err
 	}
 
 	defer res.Body.Close()
 
 	if res.StatusCode >= 400 {
-		return nil, nil, fmt.Errorf("%v", res.Status)
+		return nil, nil, &statusError{code: res.StatusCode, status: res.Status}
 	}
 
 	body, err := ioutil.ReadAll(res.Body)
@@ -1,8 +1,11 @@
 package sensu
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+)
 
 // DeleteCheckResult deletes a check result for a particular client
-func (s *Sensu) DeleteCheckResult(check, client string) error {
-	return s.delete(fmt.Sprintf("results/%s/%s", client, check))
+func (s *Sensu) DeleteCheckResult(ctx context.Context, check, client string) error {
+	return s.delete(ctx, fmt.Sprintf("results/%s/%s", client, check))
 }
@@ -0,0 +1,101 @@
+package sensu
+
+import (
+	"net"
+	"net/url"
+	"time"
+)
+
+// RetryPolicy defines how many times a request is attempted on the APIs of a
+// datacenter and how long to wait between the attempts. Every attempt tries
+// each available API in turn
+type RetryPolicy struct {
+	Attempts   int // 1 disables the retries
+	Backoff    int // in milliseconds, doubled after every retry
+	MaxBackoff int // in milliseconds
+}
+
+// DefaultRetryPolicies contains the retry policy of each HTTP method, used
+// unless the datacenter configures its own
+var DefaultRetryPolicies = map[string]RetryPolicy{
+	"DELETE": {Attempts: 2, Backoff: 200, MaxBackoff: 2000},
+	"GET":    {Attempts: 2, Backoff: 200, MaxBackoff: 2000},
+	"POST":   {Attempts: 1},
+}
+
+// retryPolicy returns the retry policy of the HTTP method
+func (s *Sensu) retryPolicy(method string) RetryPolicy {
+	policy, ok := s.Retries[method]
+	if !ok {
+		policy = DefaultRetryPolicies[method]
+	}
+
+	if policy.Attempts < 1 {
+		policy.Attempts = 1
+	}
+	return policy
+}
+
+// backoff returns how long to wait before the provided retry, starting at 1.
+// The second half of the delay is random so the retries of concurrent requests
+// are spread
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	d := time.Duration(p.Backoff) * time.Millisecond
+	for i := 1; i < retry && d < time.Hour; i++ {
+		d *= 2
+	}
+	if max := time.Duration(p.MaxBackoff) * time.Millisecond; max > 0 && d > max {
+		d = max
+	}
+	if d <= 0 {
+		return 0
+	}
+
+	random.Lock()
+	jitter := time.Duration(random.Int63n(int64(d/2) + 1))
+	random.Unlock()
+	return d - d/2 + jitter
+}
+
+// idempotent returns true if making a request with the HTTP method more than
+// once has the same effect as making it once
+func idempotent(method string) bool {
+	return method == "GET" || method == "HEAD" || method == "DELETE"
+}
+
+// retryable returns true if the request can be made again, on any API, after
+// it failed with the provided error. A request that is not idempotent is only
+// made again if it never reached the API, since it might have been processed
+// even though no response was received
+func retryable(method string, err error) bool {
+	if !isFailure(err) {
+		return false
+	}
+	return idempotent(method) || !sent(err)
+}
+
+// deletedBefore returns true if the DELETE request failed with a 404 after a
+// previous attempt which might have been processed by the API, in which case
+// the resource was most likely deleted by that attempt
+func deletedBefore(method string, err error, processed bool) bool {
+	if method != "DELETE" || !processed {
+		return false
+	}
+	e, ok := err.(*statusError)
+	return ok && e.code == 404
+}
+
+// sent returns false if the request failed before it was sent to the API,
+// i.e. while connecting to the API or during the TLS handshake
+func sent(err error) bool {
+	if isTLSError(err) {
+		return false
+	}
+	if e, ok := err.(*url.Error); ok {
+		err = e.Err
+	}
+	if e, ok := err.(*net.OpError); ok && e.Op == "dial" {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,150 @@
+package sensu
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoff(t *testing.T) {
+	p := RetryPolicy{Attempts: 5, Backoff: 100, MaxBackoff: 300}
+	for retry, max := range map[int]time.Duration{1: 100, 2: 200, 3: 300, 4: 300} {
+		for i := 0; i < 20; i++ {
+			d := p.backoff(retry)
+			assert.True(t, d >= max*time.Millisecond/2 && d <= max*time.Millisecond, fmt.Sprintf("retry %d waited %s", retry, d))
+		}
+	}
+
+	assert.Equal(t, time.Duration(0), RetryPolicy{Attempts: 2}.backoff(1))
+}
+
+func TestRetryPolicy(t *testing.T) {
+	s := Sensu{Retries: map[string]RetryPolicy{"GET": {Attempts: 3}, "DELETE": {}}}
+	assert.Equal(t, RetryPolicy{Attempts: 3}, s.retryPolicy("GET"))
+	assert.Equal(t, RetryPolicy{Attempts: 1}, s.retryPolicy("DELETE"))
+	assert.Equal(t, DefaultRetryPolicies["POST"], s.retryPolicy("POST"))
+}
+
+func TestRetryable(t *testing.T) {
+	dial := &url.Error{Op: "Post", URL: "http://127.0.0.1:4567/request", Err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}}
+	read := &url.Error{Op: "Post", URL: "http://127.0.0.1:4567/request", Err: &net.OpError{Op: "read", Err: errors.New("connection reset by peer")}}
+	notFound := &statusError{code: 404, status: "404 Not Found"}
+	badGateway := &statusError{code: 502, status: "502 Bad Gateway"}
+
+	assert.Equal(t, true, retryable("GET", read))
+	assert.Equal(t, true, retryable("DELETE", badGateway))
+	assert.Equal(t, false, retryable("GET", notFound))
+
+	// A POST request is only retried if it was not sent
+	assert.Equal(t, true, retryable("POST", dial))
+	assert.Equal(t, false, retryable("POST", read))
+	assert.Equal(t, false, retryable("POST", badGateway))
+}
+
+func TestFailoverRetries(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) <= 2 {
+			http.Error(w, "", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, `{"redis":{"connected":true}}`)
+	}))
+	defer server.Close()
+
+	// The GET request succeeds on the third attempt
+	s := newTestSensu(StrategyPrimaryStandby, server.URL)
+	s.Retries = map[string]RetryPolicy{"GET": {Attempts: 3, Backoff: 1}}
+	_, err := s.GetInfo(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&requests))
+
+	// The POST request is not retried once it was sent
+	atomic.StoreInt32(&requests, 0)
+	s = newTestSensu(StrategyPrimaryStandby, server.URL, server.URL)
+	s.Retries = map[string]RetryPolicy{"POST": {Attempts: 3, Backoff: 1}}
+	_, err = s.IssueCheckExecution(context.Background(), map[string]string{"check": "cpu"})
+	assert.NotNil(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+	assert.Equal(t, 1, len(err.(*Error).Errors))
+
+	// The POST request is made on another API if the first one is unreachable
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	atomic.StoreInt32(&requests, 2)
+	s = newTestSensu(StrategyPrimaryStandby, closed.URL, server.URL)
+	_, err = s.IssueCheckExecution(context.Background(), map[string]string{"check": "cpu"})
+	assert.Nil(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&requests))
+}
+
+func TestFailoverDeleteRetries(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The first request is processed but the response is lost
+		if atomic.AddInt32(&requests, 1) == 1 {
+			http.Error(w, "", http.StatusGatewayTimeout)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	// The resource deleted by a previous attempt is not reported as missing
+	s := newTestSensu(StrategyPrimaryStandby, server.URL)
+	s.Retries = map[string]RetryPolicy{"DELETE": {Attempts: 2, Backoff: 1}}
+	assert.Nil(t, s.DeleteClient(context.Background(), "foo"))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+
+	// A resource missing from the first attempt is still reported
+	err := s.DeleteClient(context.Background(), "foo")
+	assert.NotNil(t, err)
+	assert.Equal(t, http.StatusNotFound, err.(*Error).StatusCode())
+	assert.Equal(t, int32(3), atomic.LoadInt32(&requests))
+}
+
+func TestDeletedBefore(t *testing.T) {
+	notFound := &statusError{code: 404, status: "404 Not Found"}
+
+	assert.Equal(t, true, deletedBefore("DELETE", notFound, true))
+	assert.Equal(t, false, deletedBefore("DELETE", notFound, false))
+	assert.Equal(t, false, deletedBefore("GET", notFound, true))
+	assert.Equal(t, false, deletedBefore("DELETE", &statusError{code: 500, status: "500 Internal Server Error"}, true))
+}
+
+func TestFailoverContext(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		http.Error(w, "", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	// The retries stop once the context is done
+	s := newTestSensu(StrategyPrimaryStandby, server.URL)
+	s.Retries = map[string]RetryPolicy{"GET": {Attempts: 3, Backoff: 10000}}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := s.GetInfo(ctx)
+	assert.NotNil(t, err)
+	assert.True(t, time.Since(start) < 5*time.Second)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+
+	// A canceled request is not sent
+	_, err = s.GetInfo(ctx)
+	assert.NotNil(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+	assert.Equal(t, http.StatusGatewayTimeout, err.(*Error).StatusCode())
+}
@@ -7,6 +7,7 @@ package sensu
 import (
 	"crypto/tls"
 	"net/http"
+	neturl "net/url"
 	"time"
 )
 
@@ -18,8 +19,13 @@ const DefaultLimit int = 1000
 
 // Sensu struct contains the name and all the APIs for a particular datacenter
 type Sensu struct {
-	Name string
-	APIs []API
+	Name     string
+	APIs     []API
+	Strategy string                 // how the API of a request is selected, see the Strategy constants
+	Retries  map[string]RetryPolicy // by HTTP method, see DefaultRetryPolicies
+
+	// next contains the index of the next API to use with the round-robin strategy
+	next *uint32
 }
 
 // API struct contains the details of a specific Sensu API
@@ -29,18 +35,42 @@ type API struct {
 	Timeout int
 	User    string
 	Pass    string
+	Token   string            // bearer token, used instead of the basic auth
+	Headers map[string]string // added to every request
 	Client  http.Client
+
+	// health contains the result of the latest requests to this API, shared
+	// by the copies of the API
+	health *apiHealth
+}
+
+// NewSensu initializes a new Sensu struct for the datacenter
+func NewSensu(name string, strategy string) Sensu {
+	return Sensu{Name: name, Strategy: strategy, next: new(uint32)}
 }
 
-// NewAPI initializes a new Sensu API struct
-func NewAPI(path string, url string, timeout int, username string, password string, insecure bool) API {
+// NewAPI initializes a new Sensu API struct. The TLS configuration, if any, is
+// used for the HTTPS connections to the API, and the API is reached through
+// the proxy, if any
+func NewAPI(path string, url string, timeout int, username string, password string, tlsConfig *tls.Config, proxy *neturl.URL) API {
 	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: insecure},
+		TLSClientConfig: tlsConfig,
+	}
+	if proxy != nil {
+		tr.Proxy = http.ProxyURL(proxy)
 	}
 
 	client := http.Client{Timeout: time.Duration(timeout) * time.Second, Transport: tr}
 
-	return API{path, url, timeout, username, password, client}
+	return API{
+		Path:    path,
+		URL:     url,
+		Timeout: timeout,
+		User:    username,
+		Pass:    password,
+		Client:  client,
+		health:  &apiHealth{},
+	}
 }
 
 // GetName returns the Name attribute
@@ -1,29 +1,34 @@
 package sensu
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+
+	"github.com/sensu/uchiwa/uchiwa/structs"
 )
 
 // ClearSilenced clears an entry from the silenced registry
-func (s *Sensu) ClearSilenced(payload interface{}) (map[string]interface{}, error) {
+func (s *Sensu) ClearSilenced(ctx context.Context, payload interface{}) (map[string]interface{}, error) {
 	payloadstr, err := json.Marshal(payload)
 	if err != nil {
 		return nil, fmt.Errorf("Silence parsing error: %q returned: %v", err, err)
 	}
-	return s.postPayload(fmt.Sprintf("silenced/clear"), string(payloadstr[:]))
+	return s.postPayload(ctx, fmt.Sprintf("silenced/clear"), string(payloadstr[:]))
 }
 
 // GetSilenced returns the complete silenced registry
-func (s *Sensu) GetSilenced() ([]interface{}, error) {
-	return s.getSlice(fmt.Sprintf("silenced"), NoLimit)
+func (s *Sensu) GetSilenced(ctx context.Context) ([]*structs.Silence, error) {
+	var silenced []*structs.Silence
+	err := s.getSlice(ctx, "silenced", NoLimit, &silenced)
+	return silenced, err
 }
 
 // Silence updates the silenced registry with a new entry
-func (s *Sensu) Silence(payload interface{}) (map[string]interface{}, error) {
+func (s *Sensu) Silence(ctx context.Context, payload interface{}) (map[string]interface{}, error) {
 	payloadstr, err := json.Marshal(payload)
 	if err != nil {
 		return nil, fmt.Errorf("Silence parsing error: %q returned: %v", err, err)
 	}
-	return s.postPayload(fmt.Sprintf("silenced"), string(payloadstr[:]))
+	return s.postPayload(ctx, fmt.Sprintf("silenced"), string(payloadstr[:]))
 }
@@ -0,0 +1,97 @@
+package sensu
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+)
+
+// TLSOptions contains the TLS settings of the connections to a Sensu API
+type TLSOptions struct {
+	CAFile     string // CA bundle trusted instead of the system CAs
+	CertFile   string // client certificate, for mutual TLS
+	KeyFile    string
+	Insecure   bool   // skips the verification of the API certificate
+	MinVersion string // e.g. 1.2
+	ServerName string // verified instead of the host of the API URL
+}
+
+// NewTLSConfig returns the TLS configuration described by the options
+func NewTLSConfig(o TLSOptions) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: o.Insecure,
+		ServerName:         o.ServerName,
+	}
+
+	version, err := parseTLSVersion(o.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig.MinVersion = version
+
+	if o.CAFile != "" {
+		pem, err := ioutil.ReadFile(o.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("Could not read the CA bundle: %s", err)
+		}
+
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("Could not find any certificate in the CA bundle '%s'", o.CAFile)
+		}
+	}
+
+	if o.CertFile != "" || o.KeyFile != "" {
+		if o.CertFile == "" || o.KeyFile == "" {
+			return nil, errors.New("The client certificate requires both a certificate and a key file")
+		}
+
+		cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("Could not load the client certificate: %s", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// parseTLSVersion returns the TLS version corresponding to the provided
+// version number, or 0 for the default minimum version if empty
+func parseTLSVersion(version string) (uint16, error) {
+	switch version {
+	case "":
+		return 0, nil
+	case "1.0":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	}
+	return 0, fmt.Errorf("Invalid minimum TLS version '%s', it must be either 1.0, 1.1, 1.2 or 1.3", version)
+}
+
+// isTLSError returns true if the error occurred during the TLS handshake,
+// either because the certificate of the API could not be verified
+// or because the API rejected the handshake, e.g. the client certificate, or
+// does not speak TLS
+func isTLSError(err error) bool {
+	var verification *tls.CertificateVerificationError
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostname x509.HostnameError
+	var invalid x509.CertificateInvalidError
+	var header tls.RecordHeaderError
+	if errors.As(err, &verification) || errors.As(err, &unknownAuthority) || errors.As(err, &hostname) ||
+		errors.As(err, &invalid) || errors.As(err, &header) {
+		return true
+	}
+
+	var op *net.OpError
+	return errors.As(err, &op) && op.Op == "remote error"
+}
@@ -0,0 +1,146 @@
+package sensu
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeClientCertificate writes a self-signed client certificate and its key
+// into the directory and returns their paths
+func writeClientCertificate(t *testing.T, dir string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "uchiwa"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile := filepath.Join(dir, "client.pem")
+	keyFile := filepath.Join(dir, "client-key.pem")
+	ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)
+	return certFile, keyFile
+}
+
+func newTLSSensu(t *testing.T, url string, o TLSOptions) Sensu {
+	tlsConfig, err := NewTLSConfig(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewSensu("us-east-1", StrategyPrimaryStandby)
+	s.APIs = []API{NewAPI("", url, 1, "", "", tlsConfig, nil)}
+	s.Retries = map[string]RetryPolicy{"GET": {Attempts: 1}}
+	return s
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	tlsConfig, err := NewTLSConfig(TLSOptions{Insecure: true, MinVersion: "1.2", ServerName: "sensu.example.com"})
+	assert.Nil(t, err)
+	assert.Equal(t, true, tlsConfig.InsecureSkipVerify)
+	assert.Equal(t, uint16(tls.VersionTLS12), tlsConfig.MinVersion)
+	assert.Equal(t, "sensu.example.com", tlsConfig.ServerName)
+	assert.Nil(t, tlsConfig.RootCAs)
+
+	_, err = NewTLSConfig(TLSOptions{MinVersion: "1.4"})
+	assert.NotNil(t, err)
+
+	_, err = NewTLSConfig(TLSOptions{CAFile: "/nonexistent/ca.pem"})
+	assert.NotNil(t, err)
+
+	dir, err := ioutil.TempDir("", "uchiwa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	invalid := filepath.Join(dir, "invalid.pem")
+	ioutil.WriteFile(invalid, []byte("foo"), 0600)
+	_, err = NewTLSConfig(TLSOptions{CAFile: invalid})
+	assert.NotNil(t, err)
+
+	certFile, keyFile := writeClientCertificate(t, dir)
+	_, err = NewTLSConfig(TLSOptions{CertFile: certFile})
+	assert.NotNil(t, err)
+
+	_, err = NewTLSConfig(TLSOptions{CertFile: certFile, KeyFile: invalid})
+	assert.NotNil(t, err)
+
+	tlsConfig, err = NewTLSConfig(TLSOptions{CertFile: certFile, KeyFile: keyFile})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(tlsConfig.Certificates))
+}
+
+func TestTLS(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"redis":{"connected":true}}`)
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "uchiwa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ca := filepath.Join(dir, "ca.pem")
+	ioutil.WriteFile(ca, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0600)
+	certFile, keyFile := writeClientCertificate(t, dir)
+
+	// The certificate of the API is signed by an unknown authority
+	s := newTLSSensu(t, server.URL, TLSOptions{CertFile: certFile, KeyFile: keyFile})
+	_, err = s.GetInfo(context.Background())
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "The API of the datacenter us-east-1 failed the TLS handshake: ")
+	assert.Contains(t, s.Health()[0].TLSError, "x509")
+
+	// The certificate of the API does not match the server name
+	s = newTLSSensu(t, server.URL, TLSOptions{CAFile: ca, CertFile: certFile, KeyFile: keyFile, ServerName: "sensu.invalid"})
+	_, err = s.GetInfo(context.Background())
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "failed the TLS handshake")
+
+	// The API requires a client certificate
+	s = newTLSSensu(t, server.URL, TLSOptions{CAFile: ca})
+	_, err = s.GetInfo(context.Background())
+	assert.NotNil(t, err)
+	assert.NotEqual(t, "", s.Health()[0].TLSError)
+
+	s = newTLSSensu(t, server.URL, TLSOptions{CAFile: ca, CertFile: certFile, KeyFile: keyFile, MinVersion: "1.2"})
+	_, err = s.GetInfo(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, "", s.Health()[0].TLSError)
+}
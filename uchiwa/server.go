@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/sensu/uchiwa/uchiwa/audit"
 	"github.com/sensu/uchiwa/uchiwa/authentication"
 	"github.com/sensu/uchiwa/uchiwa/authorization"
 	"github.com/sensu/uchiwa/uchiwa/filters"
@@ -27,6 +28,9 @@ func (u *Uchiwa) aggregateHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ctx, cancel := u.requestContext(r)
+	defer cancel()
+
 	resources := strings.Split(r.URL.Path, "/")
 	if len(resources) < 3 || resources[2] == "" {
 		http.Error(w, "", http.StatusBadRequest)
@@ -46,9 +50,11 @@ func (u *Uchiwa) aggregateHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		u.Mu.Lock()
-		visibleAggregates := Filters.Aggregates(&aggregates, token)
-		u.Mu.Unlock()
+		visibleAggregates := Filters.Aggregates(aggregates, token)
+		if len(visibleAggregates) == 0 {
+			http.Error(w, fmt.Sprint(""), http.StatusNotFound)
+			return
+		}
 
 		if len(visibleAggregates) > 1 {
 			// Create header
@@ -81,16 +87,7 @@ func (u *Uchiwa) aggregateHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		c, ok := aggregates[0].(map[string]interface{})
-		if !ok {
-			http.Error(w, fmt.Sprint(err), http.StatusInternalServerError)
-			return
-		}
-		dc, ok = c["dc"].(string)
-		if !ok {
-			http.Error(w, fmt.Sprint(err), http.StatusInternalServerError)
-			return
-		}
+		dc = visibleAggregates[0].Dc
 	}
 
 	unauthorized := Filters.GetRequest(dc, token)
@@ -102,17 +99,18 @@ func (u *Uchiwa) aggregateHandler(w http.ResponseWriter, r *http.Request) {
 	// Are we responding to a /aggregates/:name request?
 	if len(resources) == 3 {
 		if r.Method == "DELETE" {
-			err := u.DeleteAggregate(name, dc)
+			err := u.DeleteAggregate(ctx, name, dc)
+			auditLog(r, "aggregatedelete", dc, err)
 			if err != nil {
-				http.Error(w, fmt.Sprint(err), 500)
+				sensuError(w, err, http.StatusInternalServerError)
 				return
 			}
 			return
 		}
 
-		aggregate, err := u.GetAggregate(name, dc)
+		aggregate, err := u.GetAggregate(ctx, name, dc)
 		if err != nil {
-			http.Error(w, fmt.Sprint(err), 500)
+			sensuError(w, err, http.StatusInternalServerError)
 			return
 		}
 
@@ -131,15 +129,15 @@ func (u *Uchiwa) aggregateHandler(w http.ResponseWriter, r *http.Request) {
 		// We are responding to a /aggregates/:name/[checks|clients] request
 
 		if resources[3] == "checks" {
-			data, err = u.GetAggregateChecks(name, dc)
+			data, err = u.GetAggregateChecks(ctx, name, dc)
 			if err != nil {
-				http.Error(w, fmt.Sprint(err), 500)
+				sensuError(w, err, http.StatusInternalServerError)
 				return
 			}
 		} else if resources[3] == "clients" {
-			data, err = u.GetAggregateClients(name, dc)
+			data, err = u.GetAggregateClients(ctx, name, dc)
 			if err != nil {
-				http.Error(w, fmt.Sprint(err), 500)
+				sensuError(w, err, http.StatusInternalServerError)
 				return
 			}
 		} else {
@@ -150,9 +148,9 @@ func (u *Uchiwa) aggregateHandler(w http.ResponseWriter, r *http.Request) {
 	} else if len(resources) == 5 {
 		// We are responding to a /aggregates/:name/results/:severity request
 		severity := resources[4]
-		data, err = u.GetAggregateResults(name, severity, dc)
+		data, err = u.GetAggregateResults(ctx, name, severity, dc)
 		if err != nil {
-			http.Error(w, fmt.Sprint(err), 500)
+			sensuError(w, err, http.StatusInternalServerError)
 			return
 		}
 	} else {
@@ -178,12 +176,10 @@ func (u *Uchiwa) aggregatesHandler(w http.ResponseWriter, r *http.Request) {
 
 	token := authentication.GetJWTFromContext(r)
 
-	u.Mu.Lock()
-	aggregates := Filters.Aggregates(&u.Data.Aggregates, token)
-	u.Mu.Unlock()
+	aggregates := Filters.Aggregates(u.getData().Aggregates, token)
 
 	if len(aggregates) == 0 {
-		aggregates = make([]interface{}, 0)
+		aggregates = make([]*structs.Aggregate, 0)
 	}
 
 	// Create header
@@ -212,6 +208,62 @@ func (u *Uchiwa) aggregatesHandler(w http.ResponseWriter, r *http.Request) {
 	return
 }
 
+// auditHandler serves the /audit endpoint
+func (u *Uchiwa) auditHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		http.Error(w, "", http.StatusBadRequest)
+		return
+	}
+
+	// verify that the authenticated user is allowed to read the audit log
+	token := authentication.GetJWTFromContext(r)
+	if token == nil {
+		http.Error(w, "Request forbidden: the audit log requires authentication", http.StatusForbidden)
+		return
+	}
+	role, err := authentication.GetRoleFromToken(token)
+	if err != nil || (!role.Admin && !role.Audit) {
+		http.Error(w, "Request forbidden: the role is not allowed to read the audit log", http.StatusForbidden)
+		return
+	}
+
+	filter, err := getAuditFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := audit.Search(filter)
+	if err != nil {
+		logger.Warningf("Could not search the audit log: %s", err)
+		http.Error(w, "Could not search the audit log", http.StatusInternalServerError)
+		return
+	}
+
+	// Create header
+	w.Header().Add("Accept-Charset", "utf-8")
+	w.Header().Add("Content-Type", "application/json")
+
+	// If GZIP compression is not supported by the client
+	if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+		encoder := json.NewEncoder(w)
+		if err := encoder.Encode(result); err != nil {
+			http.Error(w, fmt.Sprintf("Cannot encode response data: %v", err), http.StatusInternalServerError)
+			return
+		}
+		return
+	}
+
+	w.Header().Set("Content-Encoding", "gzip")
+
+	gz := gzip.NewWriter(w)
+	defer gz.Close()
+	if err := json.NewEncoder(gz).Encode(result); err != nil {
+		http.Error(w, fmt.Sprintf("Cannot encode response data: %v", err), http.StatusInternalServerError)
+		return
+	}
+}
+
 // checksHandler serves the /checks endpoint
 func (u *Uchiwa) checksHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "GET" && r.Method != "HEAD" {
@@ -221,12 +273,10 @@ func (u *Uchiwa) checksHandler(w http.ResponseWriter, r *http.Request) {
 
 	token := authentication.GetJWTFromContext(r)
 
-	u.Mu.Lock()
-	checks := Filters.Checks(&u.Data.Checks, token)
-	u.Mu.Unlock()
+	checks := Filters.Checks(u.getData().Checks, token)
 
 	if len(checks) == 0 {
-		checks = make([]interface{}, 0)
+		checks = make([]*structs.Check, 0)
 	}
 
 	// Create header
@@ -262,6 +312,9 @@ func (u *Uchiwa) clientHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ctx, cancel := u.requestContext(r)
+	defer cancel()
+
 	token := authentication.GetJWTFromContext(r)
 
 	// Get the client name
@@ -282,9 +335,11 @@ func (u *Uchiwa) clientHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		u.Mu.Lock()
-		visibleClients := Filters.Clients(&clients, token)
-		u.Mu.Unlock()
+		visibleClients := Filters.Clients(clients, token)
+		if len(visibleClients) == 0 {
+			http.Error(w, fmt.Sprint(""), http.StatusNotFound)
+			return
+		}
 
 		if len(visibleClients) > 1 {
 			// Create header
@@ -317,20 +372,11 @@ func (u *Uchiwa) clientHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		c, ok := clients[0].(map[string]interface{})
-		if !ok {
-			http.Error(w, fmt.Sprint(err), http.StatusInternalServerError)
-			return
-		}
-		dc, ok = c["dc"].(string)
-		if !ok {
-			http.Error(w, fmt.Sprint(err), http.StatusInternalServerError)
-			return
-		}
+		dc = visibleClients[0].Dc
 	}
 
 	// Verify that an authenticated user is authorized to access this resource
-	unauthorized := Filters.GetRequest(dc, token)
+	unauthorized := Filters.GetClientRequest(dc, u.clientSubscriptions(name, dc), token)
 	if unauthorized {
 		http.Error(w, fmt.Sprint(""), http.StatusNotFound)
 		return
@@ -338,9 +384,10 @@ func (u *Uchiwa) clientHandler(w http.ResponseWriter, r *http.Request) {
 
 	// DELETE on /clients/:client
 	if r.Method == "DELETE" {
-		err := u.DeleteClient(dc, name)
+		err := u.DeleteClient(ctx, dc, name)
+		auditLog(r, "clientdelete", dc, err)
 		if err != nil {
-			http.Error(w, fmt.Sprint(err), http.StatusInternalServerError)
+			sensuError(w, err, http.StatusInternalServerError)
 			return
 		}
 
@@ -350,9 +397,9 @@ func (u *Uchiwa) clientHandler(w http.ResponseWriter, r *http.Request) {
 
 	// GET on /clients/:client/history
 	if len(resources) == 4 {
-		data, err := u.GetClientHistory(dc, name)
+		data, err := u.GetClientHistory(ctx, dc, name)
 		if err != nil {
-			http.Error(w, fmt.Sprint(err), http.StatusNotFound)
+			sensuError(w, err, http.StatusNotFound)
 			return
 		}
 
@@ -366,9 +413,9 @@ func (u *Uchiwa) clientHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// GET on /clients/:client
-	data, err := u.GetClient(dc, name)
+	data, err := u.GetClient(ctx, dc, name)
 	if err != nil {
-		http.Error(w, fmt.Sprint(err), http.StatusNotFound)
+		sensuError(w, err, http.StatusNotFound)
 		return
 	}
 
@@ -391,12 +438,10 @@ func (u *Uchiwa) clientsHandler(w http.ResponseWriter, r *http.Request) {
 
 	token := authentication.GetJWTFromContext(r)
 
-	u.Mu.Lock()
-	clients := Filters.Clients(&u.Data.Clients, token)
-	u.Mu.Unlock()
+	clients := Filters.Clients(u.getData().Clients, token)
 
 	if len(clients) == 0 {
-		clients = make([]interface{}, 0)
+		clients = make([]*structs.Client, 0)
 	}
 
 	// Create header
@@ -463,7 +508,7 @@ func (u *Uchiwa) datacentersHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	token := authentication.GetJWTFromContext(r)
-	datacenters := Filters.Datacenters(u.Data.Dc, token)
+	datacenters := Filters.Datacenters(u.getData().Dc, token)
 
 	// Create header
 	w.Header().Add("Accept-Charset", "utf-8")
@@ -497,6 +542,9 @@ func (u *Uchiwa) eventHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ctx, cancel := u.requestContext(r)
+	defer cancel()
+
 	resources := strings.Split(r.URL.Path, "/")
 	if len(resources) != 4 {
 		http.Error(w, "", http.StatusBadRequest)
@@ -517,9 +565,11 @@ func (u *Uchiwa) eventHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		u.Mu.Lock()
-		visibleClients := Filters.Clients(&clients, token)
-		u.Mu.Unlock()
+		visibleClients := Filters.Clients(clients, token)
+		if len(visibleClients) == 0 {
+			http.Error(w, fmt.Sprint(""), http.StatusNotFound)
+			return
+		}
 
 		if len(visibleClients) > 1 {
 			// Create header
@@ -551,28 +601,20 @@ func (u *Uchiwa) eventHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		c, ok := clients[0].(map[string]interface{})
-		if !ok {
-			http.Error(w, fmt.Sprint(err), http.StatusInternalServerError)
-			return
-		}
-		dc, ok = c["dc"].(string)
-		if !ok {
-			http.Error(w, fmt.Sprint(err), http.StatusInternalServerError)
-			return
-		}
+		dc = visibleClients[0].Dc
 	}
 
-	unauthorized := Filters.GetRequest(dc, token)
+	unauthorized := Filters.GetClientRequest(dc, u.clientSubscriptions(client, dc), token)
 	if unauthorized {
 		http.Error(w, fmt.Sprint(""), http.StatusNotFound)
 		return
 	}
 
 	// DELETE on /events/:client/:check
-	err := u.ResolveEvent(check, client, dc)
+	err := u.ResolveEvent(ctx, check, client, dc)
+	auditLog(r, "eventresolve", dc, err)
 	if err != nil {
-		http.Error(w, fmt.Sprint(err), http.StatusInternalServerError)
+		sensuError(w, err, http.StatusInternalServerError)
 		return
 	}
 
@@ -589,12 +631,10 @@ func (u *Uchiwa) eventsHandler(w http.ResponseWriter, r *http.Request) {
 
 	token := authentication.GetJWTFromContext(r)
 
-	u.Mu.Lock()
-	events := Filters.Events(&u.Data.Events, token)
-	u.Mu.Unlock()
+	events := Filters.Events(u.getData().Events, token)
 
 	if len(events) == 0 {
-		events = make([]interface{}, 0)
+		events = make([]*structs.Event, 0)
 	}
 
 	// Create header
@@ -628,31 +668,32 @@ func (u *Uchiwa) healthHandler(w http.ResponseWriter, r *http.Request) {
 	var encoded []byte
 	var err error
 	returnCode := http.StatusOK
+	health := u.getData().Health
 
 	if r.URL.Path[1:] == "health/sensu" {
-		for _, sensu := range u.Data.Health.Sensu {
+		for _, sensu := range health.Sensu {
 			if sensu.Output != "ok" {
 				returnCode = http.StatusServiceUnavailable
 			}
 		}
-		encoded, err = json.Marshal(u.Data.Health.Sensu)
+		encoded, err = json.Marshal(health.Sensu)
 	} else if r.URL.Path[1:] == "health/uchiwa" {
-		if u.Data.Health.Uchiwa != "ok" {
+		if health.Uchiwa != "ok" {
 			returnCode = http.StatusServiceUnavailable
 		}
-		encoded, err = json.Marshal(u.Data.Health.Uchiwa)
+		encoded, err = json.Marshal(health.Uchiwa)
 	} else {
-		for _, sensu := range u.Data.Health.Sensu {
+		for _, sensu := range health.Sensu {
 			if sensu.Output != "ok" {
 				returnCode = http.StatusServiceUnavailable
 			}
 		}
 
-		if u.Data.Health.Uchiwa != "ok" {
+		if health.Uchiwa != "ok" {
 			returnCode = http.StatusServiceUnavailable
 		}
 
-		encoded, err = json.Marshal(u.Data.Health)
+		encoded, err = json.Marshal(health)
 	}
 
 	if err != nil {
@@ -673,7 +714,7 @@ func (u *Uchiwa) metricsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	encoder := json.NewEncoder(w)
-	if err := encoder.Encode(&u.Data.Metrics); err != nil {
+	if err := encoder.Encode(&u.getData().Metrics); err != nil {
 		http.Error(w, fmt.Sprintf("Cannot encode response data: %v", err), http.StatusInternalServerError)
 		return
 	}
@@ -686,6 +727,9 @@ func (u *Uchiwa) requestHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ctx, cancel := u.requestContext(r)
+	defer cancel()
+
 	decoder := json.NewDecoder(r.Body)
 	var data structs.CheckExecution
 	err := decoder.Decode(&data)
@@ -697,14 +741,15 @@ func (u *Uchiwa) requestHandler(w http.ResponseWriter, r *http.Request) {
 	// verify that the authenticated user is authorized to access this resource
 	token := authentication.GetJWTFromContext(r)
 	unauthorized := Filters.GetRequest(data.Dc, token)
-	if unauthorized {
+	if unauthorized || !u.isCheckRequestAllowed(data, token) {
 		http.Error(w, fmt.Sprint(""), http.StatusNotFound)
 		return
 	}
 
-	err = u.IssueCheckExecution(data)
+	err = u.IssueCheckExecution(ctx, data)
+	auditLog(r, "checkrequest", data.Dc, err)
 	if err != nil {
-		http.Error(w, "", http.StatusNotFound)
+		sensuError(w, err, http.StatusNotFound)
 		return
 	}
 
@@ -718,6 +763,9 @@ func (u *Uchiwa) resultsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ctx, cancel := u.requestContext(r)
+	defer cancel()
+
 	resources := strings.Split(r.URL.Path, "/")
 	if len(resources) != 4 {
 		http.Error(w, "", http.StatusBadRequest)
@@ -738,9 +786,11 @@ func (u *Uchiwa) resultsHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		u.Mu.Lock()
-		visibleClients := Filters.Clients(&clients, token)
-		u.Mu.Unlock()
+		visibleClients := Filters.Clients(clients, token)
+		if len(visibleClients) == 0 {
+			http.Error(w, fmt.Sprint(""), http.StatusNotFound)
+			return
+		}
 
 		if len(visibleClients) > 1 {
 			// Create header
@@ -772,27 +822,19 @@ func (u *Uchiwa) resultsHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		c, ok := clients[0].(map[string]interface{})
-		if !ok {
-			http.Error(w, fmt.Sprint(err), http.StatusInternalServerError)
-			return
-		}
-		dc, ok = c["dc"].(string)
-		if !ok {
-			http.Error(w, fmt.Sprint(err), http.StatusInternalServerError)
-			return
-		}
+		dc = visibleClients[0].Dc
 	}
 
-	unauthorized := Filters.GetRequest(dc, token)
+	unauthorized := Filters.GetClientRequest(dc, u.clientSubscriptions(client, dc), token)
 	if unauthorized {
 		http.Error(w, fmt.Sprint(""), http.StatusNotFound)
 		return
 	}
 
-	err := u.DeleteCheckResult(check, client, dc)
+	err := u.DeleteCheckResult(ctx, check, client, dc)
+	auditLog(r, "resultdelete", dc, err)
 	if err != nil {
-		http.Error(w, fmt.Sprint(err), http.StatusInternalServerError)
+		sensuError(w, err, http.StatusInternalServerError)
 		return
 	}
 
@@ -807,6 +849,9 @@ func (u *Uchiwa) stashHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ctx, cancel := u.requestContext(r)
+	defer cancel()
+
 	resources := strings.Split(r.URL.Path, "/")
 	if len(resources) < 2 || resources[2] == "" {
 		http.Error(w, "", http.StatusBadRequest)
@@ -826,9 +871,11 @@ func (u *Uchiwa) stashHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		u.Mu.Lock()
-		visibleStashes := Filters.Stashes(&stashes, token)
-		u.Mu.Unlock()
+		visibleStashes := Filters.Stashes(stashes, u.getData().Clients, token)
+		if len(visibleStashes) == 0 {
+			http.Error(w, fmt.Sprint(""), http.StatusNotFound)
+			return
+		}
 
 		if len(visibleStashes) > 1 {
 			// Create header
@@ -860,16 +907,7 @@ func (u *Uchiwa) stashHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		c, ok := stashes[0].(map[string]interface{})
-		if !ok {
-			http.Error(w, fmt.Sprint(err), http.StatusInternalServerError)
-			return
-		}
-		dc, ok = c["dc"].(string)
-		if !ok {
-			http.Error(w, fmt.Sprint(err), http.StatusInternalServerError)
-			return
-		}
+		dc = visibleStashes[0].Dc
 	}
 
 	unauthorized := Filters.GetRequest(dc, token)
@@ -878,10 +916,11 @@ func (u *Uchiwa) stashHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := u.DeleteStash(dc, path)
+	err := u.DeleteStash(ctx, dc, path)
+	auditLog(r, "stashdelete", dc, err)
 	if err != nil {
 		logger.Warningf("Could not delete the stash '%s': %s", path, err)
-		http.Error(w, "Could not create the stash", http.StatusNotFound)
+		sensuError(w, err, http.StatusNotFound)
 		return
 	}
 
@@ -893,14 +932,16 @@ func (u *Uchiwa) stashHandler(w http.ResponseWriter, r *http.Request) {
 func (u *Uchiwa) silencedHandler(w http.ResponseWriter, r *http.Request) {
 	token := authentication.GetJWTFromContext(r)
 
+	ctx, cancel := u.requestContext(r)
+	defer cancel()
+
 	if r.Method == "GET" || r.Method == "HEAD" {
 		// GET on /silenced
-		u.Mu.Lock()
-		silenced := Filters.Silenced(&u.Data.Silenced, token)
-		u.Mu.Unlock()
+		snapshot := u.getData()
+		silenced := Filters.Silenced(snapshot.Silenced, snapshot.Clients, token)
 
 		if len(silenced) == 0 {
-			silenced = make([]interface{}, 0)
+			silenced = make([]*structs.Silence, 0)
 		}
 
 		// Create header
@@ -937,9 +978,9 @@ func (u *Uchiwa) silencedHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		// verify that the authenticated user is authorized to access this resource
-		unauthorized := Filters.GetRequest(data.Dc, token)
-		if unauthorized {
+		// verify that the authenticated user is authorized to access this
+		// resource, including the clients targeted by the entry
+		if !u.isSilenceAllowed(data, token) {
 			http.Error(w, fmt.Sprint(""), http.StatusNotFound)
 			return
 		}
@@ -950,9 +991,11 @@ func (u *Uchiwa) silencedHandler(w http.ResponseWriter, r *http.Request) {
 
 		resources := strings.Split(r.URL.Path, "/")
 		if len(resources) > 2 && resources[2] == "clear" {
-			err = u.ClearSilenced(data)
+			err = u.ClearSilenced(ctx, data)
+			auditLog(r, "silenceclear", data.Dc, err)
 			if err != nil {
-				http.Error(w, "Could not clear from entry in the silenced registry", http.StatusNotFound)
+				logger.Warningf("Could not clear the entry from the silenced registry: %s", err)
+				sensuError(w, err, http.StatusNotFound)
 				return
 			}
 			return
@@ -968,9 +1011,11 @@ func (u *Uchiwa) silencedHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		err = u.PostSilence(data)
+		err = u.PostSilence(ctx, data)
+		auditLog(r, "silencecreate", data.Dc, err)
 		if err != nil {
-			http.Error(w, "Could not create the entry in the silenced registry", http.StatusNotFound)
+			logger.Warningf("Could not create the entry in the silenced registry: %s", err)
+			sensuError(w, err, http.StatusNotFound)
 			return
 		}
 	} else {
@@ -983,14 +1028,16 @@ func (u *Uchiwa) silencedHandler(w http.ResponseWriter, r *http.Request) {
 func (u *Uchiwa) stashesHandler(w http.ResponseWriter, r *http.Request) {
 	token := authentication.GetJWTFromContext(r)
 
+	ctx, cancel := u.requestContext(r)
+	defer cancel()
+
 	if r.Method == "GET" || r.Method == "HEAD" {
 		// GET on /stashes
-		u.Mu.Lock()
-		stashes := Filters.Stashes(&u.Data.Stashes, token)
-		u.Mu.Unlock()
+		snapshot := u.getData()
+		stashes := Filters.Stashes(snapshot.Stashes, snapshot.Clients, token)
 
 		if len(stashes) == 0 {
-			stashes = make([]interface{}, 0)
+			stashes = make([]*structs.Stash, 0)
 		}
 
 		// Create header
@@ -1027,9 +1074,10 @@ func (u *Uchiwa) stashesHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		// verify that the authenticated user is authorized to access this resource
-		unauthorized := Filters.GetRequest(data.Dc, token)
-		if unauthorized {
+		// verify that the authenticated user is authorized to access this
+		// resource, including the client silenced by the stash
+		target := &structs.Stash{Dc: data.Dc, Path: data.Path}
+		if len(Filters.Stashes([]*structs.Stash{target}, u.getData().Clients, token)) == 0 {
 			http.Error(w, fmt.Sprint(""), http.StatusNotFound)
 			return
 		}
@@ -1038,9 +1086,11 @@ func (u *Uchiwa) stashesHandler(w http.ResponseWriter, r *http.Request) {
 			data.Content["username"] = token.Claims["Username"]
 		}
 
-		err = u.PostStash(data)
+		err = u.PostStash(ctx, data)
+		auditLog(r, "stashcreate", data.Dc, err)
 		if err != nil {
-			http.Error(w, "Could not create the stash", http.StatusNotFound)
+			logger.Warningf("Could not create the stash: %s", err)
+			sensuError(w, err, http.StatusNotFound)
 			return
 		}
 	} else {
@@ -1058,9 +1108,7 @@ func (u *Uchiwa) subscriptionsHandler(w http.ResponseWriter, r *http.Request) {
 
 	token := authentication.GetJWTFromContext(r)
 
-	u.Mu.Lock()
-	subscriptions := Filters.Subscriptions(&u.Data.Subscriptions, token)
-	u.Mu.Unlock()
+	subscriptions := Filters.Subscriptions(&u.getData().Subscriptions, token)
 
 	if len(subscriptions) == 0 {
 		subscriptions = make([]structs.Subscription, 0)
@@ -1086,27 +1134,44 @@ func noCacheHandler(next http.Handler) http.Handler {
 	})
 }
 
+// privateHandlers returns the handlers of the endpoints that require
+// authentication, including the ones of the authentication driver, indexed by
+// their pattern
+func (u *Uchiwa) privateHandlers(auth authentication.Config) map[string]http.Handler {
+	handlers := map[string]http.Handler{
+		"/aggregates":     http.HandlerFunc(u.aggregatesHandler),
+		"/aggregates/":    http.HandlerFunc(u.aggregateHandler),
+		"/audit":          http.HandlerFunc(u.auditHandler),
+		"/checks":         http.HandlerFunc(u.checksHandler),
+		"/clients":        http.HandlerFunc(u.clientsHandler),
+		"/clients/":       http.HandlerFunc(u.clientHandler),
+		"/config":         http.HandlerFunc(u.configHandler),
+		"/datacenters":    http.HandlerFunc(u.datacentersHandler),
+		"/events":         http.HandlerFunc(u.eventsHandler),
+		"/events/":        http.HandlerFunc(u.eventHandler),
+		"/request":        http.HandlerFunc(u.requestHandler),
+		"/results/":       http.HandlerFunc(u.resultsHandler),
+		"/silenced":       http.HandlerFunc(u.silencedHandler),
+		"/silenced/clear": http.HandlerFunc(u.silencedHandler),
+		"/stashes":        http.HandlerFunc(u.stashesHandler),
+		"/stashes/":       http.HandlerFunc(u.stashHandler),
+		"/subscriptions":  http.HandlerFunc(u.subscriptionsHandler),
+	}
+	if u.Config.Uchiwa.Enterprise == false {
+		handlers["/metrics"] = http.HandlerFunc(u.metricsHandler)
+	}
+	for pattern, handler := range auth.PrivateHandlers {
+		handlers[pattern] = handler
+	}
+
+	return handlers
+}
+
 // WebServer starts the web server and serves GET & POST requests
 func (u *Uchiwa) WebServer(publicPath *string, auth authentication.Config) {
 	// Private endpoints
-	http.Handle("/aggregates", auth.Authenticate(Authorization.Handler(http.HandlerFunc(u.aggregatesHandler))))
-	http.Handle("/aggregates/", auth.Authenticate(Authorization.Handler(http.HandlerFunc(u.aggregateHandler))))
-	http.Handle("/checks", auth.Authenticate(Authorization.Handler(http.HandlerFunc(u.checksHandler))))
-	http.Handle("/clients", auth.Authenticate(Authorization.Handler(http.HandlerFunc(u.clientsHandler))))
-	http.Handle("/clients/", auth.Authenticate(Authorization.Handler(http.HandlerFunc(u.clientHandler))))
-	http.Handle("/config", auth.Authenticate(Authorization.Handler(http.HandlerFunc(u.configHandler))))
-	http.Handle("/datacenters", auth.Authenticate(Authorization.Handler(http.HandlerFunc(u.datacentersHandler))))
-	http.Handle("/events", auth.Authenticate(Authorization.Handler(http.HandlerFunc(u.eventsHandler))))
-	http.Handle("/events/", auth.Authenticate(Authorization.Handler(http.HandlerFunc(u.eventHandler))))
-	http.Handle("/request", auth.Authenticate(Authorization.Handler(http.HandlerFunc(u.requestHandler))))
-	http.Handle("/results/", auth.Authenticate(Authorization.Handler(http.HandlerFunc(u.resultsHandler))))
-	http.Handle("/silenced", auth.Authenticate(Authorization.Handler(http.HandlerFunc(u.silencedHandler))))
-	http.Handle("/silenced/clear", auth.Authenticate(Authorization.Handler(http.HandlerFunc(u.silencedHandler))))
-	http.Handle("/stashes", auth.Authenticate(Authorization.Handler(http.HandlerFunc(u.stashesHandler))))
-	http.Handle("/stashes/", auth.Authenticate(Authorization.Handler(http.HandlerFunc(u.stashHandler))))
-	http.Handle("/subscriptions", auth.Authenticate(Authorization.Handler(http.HandlerFunc(u.subscriptionsHandler))))
-	if u.Config.Uchiwa.Enterprise == false {
-		http.Handle("/metrics", auth.Authenticate(Authorization.Handler(http.HandlerFunc(u.metricsHandler))))
+	for pattern, handler := range u.privateHandlers(auth) {
+		http.Handle(pattern, auth.Authenticate(Authorization.Handler(handler)))
 	}
 
 	// Static files
@@ -1117,12 +1182,23 @@ func (u *Uchiwa) WebServer(publicPath *string, auth authentication.Config) {
 	http.Handle("/health", http.HandlerFunc(u.healthHandler))
 	http.Handle("/health/", http.HandlerFunc(u.healthHandler))
 	http.Handle("/login", auth.Login())
+	http.Handle("/login/refresh", auth.Refresh())
+	http.Handle("/logout", auth.Logout())
+	for pattern, handler := range auth.Handlers {
+		http.Handle(pattern, handler)
+	}
 
 	listen := fmt.Sprintf("%s:%d", u.Config.Uchiwa.Host, u.Config.Uchiwa.Port)
 	logger.Warningf("Uchiwa is now listening on %s", listen)
 
 	if u.Config.Uchiwa.SSL.CertFile != "" && u.Config.Uchiwa.SSL.KeyFile != "" {
-		logger.Fatal(http.ListenAndServeTLS(listen, u.Config.Uchiwa.SSL.CertFile, u.Config.Uchiwa.SSL.KeyFile, nil))
+		tlsConfig, err := getTLSConfig(u.Config.Uchiwa.SSL)
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		server := &http.Server{Addr: listen, TLSConfig: tlsConfig}
+		logger.Fatal(server.ListenAndServeTLS(u.Config.Uchiwa.SSL.CertFile, u.Config.Uchiwa.SSL.KeyFile))
 	}
 
 	logger.Fatal(http.ListenAndServe(listen, nil))
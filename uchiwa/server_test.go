@@ -0,0 +1,301 @@
+package uchiwa
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gorilla/context"
+	"github.com/sensu/uchiwa/uchiwa/audit"
+	"github.com/sensu/uchiwa/uchiwa/authentication"
+	"github.com/sensu/uchiwa/uchiwa/authorization"
+	"github.com/sensu/uchiwa/uchiwa/config"
+	"github.com/sensu/uchiwa/uchiwa/filters"
+	"github.com/sensu/uchiwa/uchiwa/sensu"
+	"github.com/sensu/uchiwa/uchiwa/structs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrivateHandlersMethods(t *testing.T) {
+	u := &Uchiwa{Config: &config.Config{}}
+	authz := &authorization.Uchiwa{}
+
+	// An on-call role allowed to silence and to resolve events, but not
+	// to delete clients
+	role := authentication.Role{
+		Name: "oncall",
+		Methods: authentication.Methods{
+			Delete: []string{"/events/"},
+			Post:   []string{"/silenced"},
+		},
+	}
+	allowed := map[string]bool{
+		"DELETE /events/":      true,
+		"POST /silenced":       true,
+		"POST /silenced/clear": true,
+	}
+
+	handlers := u.privateHandlers(authentication.Config{})
+	assert.Equal(t, 18, len(handlers))
+
+	for pattern := range handlers {
+		path := pattern
+		if strings.HasSuffix(pattern, "/") {
+			path += "foo/bar"
+		}
+
+		for _, method := range []string{"DELETE", "GET", "HEAD", "POST"} {
+			r, _ := http.NewRequest(method, path, nil)
+			token := jwt.New(jwt.GetSigningMethod("RS256"))
+			token.Claims["Role"] = role
+			context.Set(r, authentication.JWTToken, token)
+
+			w := httptest.NewRecorder()
+			authz.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(w, r)
+
+			expected := http.StatusForbidden
+			if method == "GET" || method == "HEAD" || allowed[method+" "+pattern] {
+				expected = http.StatusOK
+			}
+			assert.Equal(t, expected, w.Code, "%s %s", method, path)
+		}
+	}
+}
+
+func TestPrivateHandlersAuthentication(t *testing.T) {
+	u := &Uchiwa{Config: &config.Config{}}
+	authz := &authorization.Uchiwa{}
+
+	auth := authentication.New(structs.Auth{})
+	auth.HandlePrivate("/users", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	handlers := u.privateHandlers(auth)
+	assert.Equal(t, 19, len(handlers))
+	assert.NotNil(t, handlers["/users"])
+
+	var tests = []struct {
+		role     authentication.Role
+		method   string
+		expected int
+	}{
+		{authentication.Role{Admin: true}, "POST", http.StatusOK},
+		{authentication.Role{Admin: true, Readonly: true}, "GET", http.StatusOK},
+		{authentication.Role{Admin: true, Readonly: true}, "POST", http.StatusForbidden},
+		{authentication.Role{Admin: true, Methods: authentication.Methods{Post: []string{"/silenced"}}}, "POST", http.StatusForbidden},
+		{authentication.Role{Admin: true, Methods: authentication.Methods{Post: []string{"/users"}}}, "POST", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		r, _ := http.NewRequest(tt.method, "/users", nil)
+		token := jwt.New(jwt.GetSigningMethod("RS256"))
+		token.Claims["Role"] = tt.role
+		context.Set(r, authentication.JWTToken, token)
+
+		w := httptest.NewRecorder()
+		authz.Handler(handlers["/users"]).ServeHTTP(w, r)
+		assert.Equal(t, tt.expected, w.Code, "%s /users as %+v", tt.method, tt.role)
+	}
+}
+
+func TestClientHandlersSubscriptions(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprint(w, `{"name":"web"}`)
+	}))
+	defer server.Close()
+
+	datacenter := sensu.NewSensu("us-east-1", sensu.StrategyPrimaryStandby)
+	datacenter.APIs = []sensu.API{sensu.NewAPI("", server.URL, 1, "", "", nil, nil)}
+
+	Filters = &filters.Uchiwa{}
+	u := &Uchiwa{Config: &config.Config{}, Datacenters: &[]sensu.Sensu{datacenter}}
+	u.setData(&structs.Data{Clients: []*structs.Client{
+		{Name: "web", Dc: "us-east-1", Subscriptions: []string{"linux"}},
+		{Name: "db", Dc: "us-east-1", Subscriptions: []string{"windows"}},
+	}})
+
+	request := func(method, url string, handler http.HandlerFunc) int {
+		r, _ := http.NewRequest(method, url, nil)
+		token := jwt.New(jwt.GetSigningMethod("RS256"))
+		token.Claims["Role"] = authentication.Role{Name: "linux", Subscriptions: []string{"linux"}}
+		context.Set(r, authentication.JWTToken, token)
+		w := httptest.NewRecorder()
+		handler(w, r)
+		return w.Code
+	}
+
+	// The clients outside of the role's subscriptions can't be reached, with
+	// or without the datacenter
+	for _, url := range []string{"/clients/db", "/clients/db?dc=us-east-1", "/clients/qux?dc=us-east-1"} {
+		assert.Equal(t, http.StatusNotFound, request("GET", url, u.clientHandler), url)
+		assert.Equal(t, http.StatusNotFound, request("DELETE", url, u.clientHandler), url)
+	}
+	for _, url := range []string{"/events/db/cpu", "/events/db/cpu?dc=us-east-1"} {
+		assert.Equal(t, http.StatusNotFound, request("DELETE", url, u.eventHandler), url)
+	}
+	for _, url := range []string{"/results/db/cpu", "/results/db/cpu?dc=us-east-1"} {
+		assert.Equal(t, http.StatusNotFound, request("DELETE", url, u.resultsHandler), url)
+	}
+	assert.Empty(t, requests)
+
+	assert.Equal(t, http.StatusOK, request("GET", "/clients/web", u.clientHandler))
+	assert.Equal(t, http.StatusAccepted, request("DELETE", "/clients/web?dc=us-east-1", u.clientHandler))
+	assert.Equal(t, http.StatusAccepted, request("DELETE", "/events/web/cpu", u.eventHandler))
+	assert.Equal(t, http.StatusAccepted, request("DELETE", "/results/web/cpu?dc=us-east-1", u.resultsHandler))
+	assert.Equal(t, 4, len(requests))
+}
+
+func TestAggregateStashHandlersDatacenters(t *testing.T) {
+	var requests []string
+	newDatacenter := func(name string) sensu.Sensu {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests = append(requests, name+" "+r.Method+" "+r.URL.Path)
+			w.WriteHeader(http.StatusAccepted)
+		}))
+		t.Cleanup(server.Close)
+
+		datacenter := sensu.NewSensu(name, sensu.StrategyPrimaryStandby)
+		datacenter.APIs = []sensu.API{sensu.NewAPI("", server.URL, 1, "", "", nil, nil)}
+		return datacenter
+	}
+
+	Filters = &filters.Uchiwa{}
+	u := &Uchiwa{Config: &config.Config{}, Datacenters: &[]sensu.Sensu{newDatacenter("us-east-1"), newDatacenter("us-west-1")}}
+	u.setData(&structs.Data{
+		Aggregates: []*structs.Aggregate{
+			{Name: "web", Dc: "us-east-1"},
+			{Name: "web", Dc: "us-west-1"},
+			{Name: "db", Dc: "us-east-1"},
+		},
+		Stashes: []*structs.Stash{
+			{Path: "silence/web", Dc: "us-east-1"},
+			{Path: "silence/web", Dc: "us-west-1"},
+			{Path: "silence/db", Dc: "us-east-1"},
+		},
+	})
+
+	request := func(url string, handler http.HandlerFunc) int {
+		r, _ := http.NewRequest("DELETE", url, nil)
+		token := jwt.New(jwt.GetSigningMethod("RS256"))
+		token.Claims["Role"] = authentication.Role{Name: "west", Datacenters: []string{"us-west-1"}}
+		context.Set(r, authentication.JWTToken, token)
+		w := httptest.NewRecorder()
+		handler(w, r)
+		return w.Code
+	}
+
+	// Only the datacenters of the role are considered
+	assert.Equal(t, http.StatusNotFound, request("/aggregates/db", u.aggregateHandler))
+	assert.Equal(t, http.StatusNotFound, request("/stashes/silence/db", u.stashHandler))
+	assert.Empty(t, requests)
+
+	assert.Equal(t, http.StatusOK, request("/aggregates/web", u.aggregateHandler))
+	assert.Equal(t, http.StatusAccepted, request("/stashes/silence/web", u.stashHandler))
+	assert.Equal(t, []string{"us-west-1 DELETE /aggregates/web", "us-west-1 DELETE /stashes/silence/web"}, requests)
+}
+
+func TestSilencedHandlerSubscriptions(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	datacenter := sensu.NewSensu("us-east-1", sensu.StrategyPrimaryStandby)
+	datacenter.APIs = []sensu.API{sensu.NewAPI("", server.URL, 1, "", "", nil, nil)}
+
+	Filters = &filters.Uchiwa{}
+	u := &Uchiwa{Config: &config.Config{}, Datacenters: &[]sensu.Sensu{datacenter}}
+	u.setData(&structs.Data{
+		Clients: []*structs.Client{
+			{Name: "web", Dc: "us-east-1", Subscriptions: []string{"linux"}},
+			{Name: "db", Dc: "us-east-1", Subscriptions: []string{"windows"}},
+		},
+		Silenced: []*structs.Silence{
+			{ID: "client:db:*", Dc: "us-east-1", Subscription: "client:db"},
+			{ID: "client:web:*", Dc: "us-east-1", Subscription: "client:web"},
+		},
+	})
+
+	request := func(url, body string) int {
+		r, _ := http.NewRequest("POST", url, strings.NewReader(body))
+		token := jwt.New(jwt.GetSigningMethod("RS256"))
+		token.Claims["Role"] = authentication.Role{Name: "linux", Subscriptions: []string{"linux"}}
+		context.Set(r, authentication.JWTToken, token)
+		w := httptest.NewRecorder()
+		u.silencedHandler(w, r)
+		return w.Code
+	}
+
+	// The entries outside of the role's subscriptions can't be created nor
+	// cleared
+	for _, body := range []string{
+		`{"dc":"us-east-1","subscription":"windows"}`,
+		`{"dc":"us-east-1","subscription":"client:db"}`,
+		`{"dc":"us-east-1","check":"cpu"}`,
+	} {
+		assert.Equal(t, http.StatusNotFound, request("/silenced", body), body)
+	}
+	assert.Equal(t, http.StatusNotFound, request("/silenced/clear", `{"dc":"us-east-1","id":"client:db:*"}`))
+	assert.Empty(t, requests)
+
+	assert.Equal(t, http.StatusOK, request("/silenced", `{"dc":"us-east-1","subscription":"linux"}`))
+	assert.Equal(t, http.StatusOK, request("/silenced", `{"dc":"us-east-1","subscription":"client:web","check":"cpu"}`))
+	assert.Equal(t, http.StatusOK, request("/silenced/clear", `{"dc":"us-east-1","id":"client:web:*"}`))
+	assert.Equal(t, 3, len(requests))
+}
+
+func TestAuditHandler(t *testing.T) {
+	u := &Uchiwa{Config: &config.Config{}}
+
+	var filter audit.Filter
+	audit.Search = func(f audit.Filter) (*audit.Result, error) {
+		filter = f
+		return &audit.Result{Entries: []structs.AuditLog{{Action: "clientdelete"}}, Total: 1}, nil
+	}
+	defer func() { audit.Search = audit.SearchMock }()
+
+	request := func(url string, role *authentication.Role) *httptest.ResponseRecorder {
+		r, _ := http.NewRequest("GET", url, nil)
+		if role != nil {
+			token := jwt.New(jwt.GetSigningMethod("RS256"))
+			token.Claims["Role"] = *role
+			context.Set(r, authentication.JWTToken, token)
+		}
+		w := httptest.NewRecorder()
+		u.auditHandler(w, r)
+		return w
+	}
+
+	// Authentication is required, along with the audit permission
+	assert.Equal(t, http.StatusForbidden, request("/audit", nil).Code)
+	assert.Equal(t, http.StatusForbidden, request("/audit", &authentication.Role{Name: "viewer"}).Code)
+	assert.Equal(t, http.StatusOK, request("/audit", &authentication.Role{Name: "admin", Admin: true}).Code)
+
+	w := request("/audit?user=alice&action=clientdelete&dc=us-east-1&remoteaddr=10.0.0.1&from=2016-01-01T00:00:00Z&limit=10&offset=20", &authentication.Role{Audit: true})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "alice", filter.User)
+	assert.Equal(t, "clientdelete", filter.Action)
+	assert.Equal(t, "us-east-1", filter.Dc)
+	assert.Equal(t, "10.0.0.1", filter.RemoteAddr)
+	assert.Equal(t, 2016, filter.From.Year())
+	assert.True(t, filter.To.IsZero())
+	assert.Equal(t, 10, filter.Limit)
+	assert.Equal(t, 20, filter.Offset)
+
+	var result audit.Result
+	assert.Nil(t, json.NewDecoder(w.Body).Decode(&result))
+	assert.Equal(t, 1, result.Total)
+	assert.Equal(t, "clientdelete", result.Entries[0].Action)
+
+	// Invalid parameters
+	assert.Equal(t, http.StatusBadRequest, request("/audit?from=yesterday", &authentication.Role{Audit: true}).Code)
+	assert.Equal(t, http.StatusBadRequest, request("/audit?limit=foo", &authentication.Role{Audit: true}).Code)
+}
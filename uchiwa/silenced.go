@@ -1,6 +1,12 @@
 package uchiwa
 
-import "github.com/sensu/uchiwa/uchiwa/logger"
+import (
+	"context"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/sensu/uchiwa/uchiwa/logger"
+	"github.com/sensu/uchiwa/uchiwa/structs"
+)
 
 type silence struct {
 	ID              string `json:"id"`
@@ -13,15 +19,35 @@ type silence struct {
 	ExpireOnResolve bool   `json:"expire_on_resolve,omitempty"`
 }
 
+// isSilenceAllowed returns true if the role is allowed to create or clear the
+// silence entry. An entry cleared by its ID targets the subscription of the
+// existing entry
+func (u *Uchiwa) isSilenceAllowed(data silence, token *jwt.Token) bool {
+	snapshot := u.getData()
+
+	target := &structs.Silence{Dc: data.Dc, Subscription: data.Subscription}
+	if data.ID != "" {
+		target.Subscription = ""
+		for _, s := range snapshot.Silenced {
+			if s.ID == data.ID && s.Dc == data.Dc {
+				target.Subscription = s.Subscription
+				break
+			}
+		}
+	}
+
+	return len(Filters.Silenced([]*structs.Silence{target}, snapshot.Clients, token)) == 1
+}
+
 // ClearSilenced send a POST request to the /stashes endpoint in order to create a stash
-func (u *Uchiwa) ClearSilenced(data silence) error {
+func (u *Uchiwa) ClearSilenced(ctx context.Context, data silence) error {
 	api, err := getAPI(u.Datacenters, data.Dc)
 	if err != nil {
 		logger.Warning(err)
 		return err
 	}
 
-	_, err = api.ClearSilenced(data)
+	_, err = api.ClearSilenced(ctx, data)
 	if err != nil {
 		logger.Warning(err)
 		return err
@@ -31,14 +57,14 @@ func (u *Uchiwa) ClearSilenced(data silence) error {
 }
 
 // PostSilence send a POST request to the /stashes endpoint in order to create a stash
-func (u *Uchiwa) PostSilence(data silence) error {
+func (u *Uchiwa) PostSilence(ctx context.Context, data silence) error {
 	api, err := getAPI(u.Datacenters, data.Dc)
 	if err != nil {
 		logger.Warning(err)
 		return err
 	}
 
-	_, err = api.Silence(data)
+	_, err = api.Silence(ctx, data)
 	if err != nil {
 		logger.Warning(err)
 		return err
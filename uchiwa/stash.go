@@ -1,9 +1,11 @@
 package uchiwa
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/sensu/uchiwa/uchiwa/logger"
+	"github.com/sensu/uchiwa/uchiwa/structs"
 )
 
 type stash struct {
@@ -14,14 +16,14 @@ type stash struct {
 }
 
 // PostStash send a POST request to the /stashes endpoint in order to create a stash
-func (u *Uchiwa) PostStash(data stash) error {
+func (u *Uchiwa) PostStash(ctx context.Context, data stash) error {
 	api, err := getAPI(u.Datacenters, data.Dc)
 	if err != nil {
 		logger.Warning(err)
 		return err
 	}
 
-	_, err = api.CreateStash(data)
+	_, err = api.CreateStash(ctx, data)
 	if err != nil {
 		logger.Warning(err)
 		return err
@@ -31,13 +33,13 @@ func (u *Uchiwa) PostStash(data stash) error {
 }
 
 // DeleteStash send a DELETE request to the /stashes/*path* endpoint in order to delete a stash
-func (u *Uchiwa) DeleteStash(dc, path string) error {
+func (u *Uchiwa) DeleteStash(ctx context.Context, dc, path string) error {
 	api, err := getAPI(u.Datacenters, dc)
 	if err != nil {
 		return err
 	}
 
-	err = api.DeleteStash(path)
+	err = api.DeleteStash(ctx, path)
 	if err != nil {
 		return err
 	}
@@ -45,16 +47,11 @@ func (u *Uchiwa) DeleteStash(dc, path string) error {
 	return nil
 }
 
-func (u *Uchiwa) findStash(path string) ([]interface{}, error) {
-	var stashes []interface{}
-	for _, c := range u.Data.Stashes {
-		m, ok := c.(map[string]interface{})
-		if !ok {
-			logger.Warningf("Could not assert this stash to an interface %+v", c)
-			continue
-		}
-		if m["path"] == path {
-			stashes = append(stashes, m)
+func (u *Uchiwa) findStash(path string) ([]*structs.Stash, error) {
+	var stashes []*structs.Stash
+	for _, s := range u.getData().Stashes {
+		if s.Path == path {
+			stashes = append(stashes, s)
 		}
 	}
 
@@ -8,16 +8,13 @@ import (
 )
 
 func TestFindStash(t *testing.T) {
-	u := Uchiwa{
-		Data: &structs.Data{},
-	}
-
-	u.Data.Stashes = []interface{}{
-		map[string]interface{}{"path": "foo", "dc": "us-east-1"},
-		map[string]interface{}{"path": "silence/foo", "dc": "us-east-1"},
-		map[string]interface{}{"path": "bar", "dc": "us-east-1"},
-		map[string]interface{}{"path": "foo", "dc": "us-west-1"},
-	}
+	u := Uchiwa{}
+	u.setData(&structs.Data{Stashes: []*structs.Stash{
+		{Path: "foo", Dc: "us-east-1"},
+		{Path: "silence/foo", Dc: "us-east-1"},
+		{Path: "bar", Dc: "us-east-1"},
+		{Path: "foo", Dc: "us-west-1"},
+	}})
 
 	stashes, err := u.findStash("foo")
 	assert.Nil(t, err)
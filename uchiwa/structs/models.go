@@ -0,0 +1,432 @@
+package structs
+
+import "encoding/json"
+
+// Attributes contains the attributes of a Sensu object that are not part of
+// its model, e.g. the custom attributes of the clients and checks. They are
+// preserved when the object is encoded back to JSON
+type Attributes map[string]interface{}
+
+// Aggregate is a structure for holding an aggregate
+type Aggregate struct {
+	Dc         string
+	Name       string
+	UID        string // unique across the datacenters
+	Attributes Attributes
+}
+
+// Check is a structure for holding a check definition or a check result. The
+// status is only encoded if it was decoded or set, since the check
+// definitions have none
+type Check struct {
+	Dc          string
+	Name        string
+	Output      string
+	Status      int
+	Subscribers []string
+	UID         string // unique across the datacenters
+	Attributes  Attributes
+
+	hasStatus bool
+}
+
+// Client is a structure for holding a client. Its status, output and
+// silenced attributes are determined by Uchiwa from the events
+type Client struct {
+	Dc            string
+	Name          string
+	Output        string
+	Silenced      bool
+	Status        int
+	Subscriptions []string
+	UID           string // unique across the datacenters
+	Attributes    Attributes
+}
+
+// ClientHistory is a structure for holding the history of a check on a client
+type ClientHistory struct {
+	Check      string
+	Client     string
+	Dc         string
+	LastResult *Check
+	LastStatus int
+	Silenced   bool
+	SilencedBy []string
+	Attributes Attributes
+}
+
+// Event is a structure for holding an event. Its check and client are nil
+// if they are not objects, e.g. with Sensu <= 0.12
+type Event struct {
+	Check      *Check
+	Client     *Client
+	Dc         string
+	Silenced   bool
+	SilencedBy []string
+	UID        string // unique across the datacenters
+	Attributes Attributes
+}
+
+// Silence is a structure for holding an entry of the silenced registry
+type Silence struct {
+	Check        string
+	Dc           string
+	ID           string
+	Subscription string
+	UID          string // unique across the datacenters
+	Attributes   Attributes
+}
+
+// Stash is a structure for holding a stash
+type Stash struct {
+	Dc         string
+	Path       string
+	UID        string // unique across the datacenters
+	Attributes Attributes
+}
+
+// MarshalJSON encodes the aggregate along with its attributes
+func (g Aggregate) MarshalJSON() ([]byte, error) {
+	m := g.Attributes.copy()
+	m.setString("dc", g.Dc)
+	m.setRequiredString("name", g.Name)
+	m.setString("_id", g.UID)
+	return json.Marshal(m)
+}
+
+// UnmarshalJSON decodes the aggregate, keeping the unknown attributes
+func (g *Aggregate) UnmarshalJSON(b []byte) error {
+	a, err := unmarshalAttributes(b)
+	if err != nil {
+		return err
+	}
+
+	*g = Aggregate{
+		Dc:         a.popString("dc"),
+		Name:       a.popString("name"),
+		UID:        a.popString("_id"),
+		Attributes: a,
+	}
+	return nil
+}
+
+func newCheck(a Attributes) *Check {
+	_, hasStatus := a["status"].(float64)
+	return &Check{
+		Dc:          a.popString("dc"),
+		Name:        a.popString("name"),
+		Output:      a.popString("output"),
+		Status:      a.popInt("status"),
+		Subscribers: a.popStrings("subscribers"),
+		UID:         a.popString("_id"),
+		Attributes:  a,
+		hasStatus:   hasStatus,
+	}
+}
+
+// MarshalJSON encodes the check along with its attributes
+func (c Check) MarshalJSON() ([]byte, error) {
+	m := c.Attributes.copy()
+	m.setString("dc", c.Dc)
+	m.setRequiredString("name", c.Name)
+	m.setString("output", c.Output)
+	if c.hasStatus || c.Status != 0 {
+		m.setInt("status", c.Status)
+	}
+	if c.Subscribers != nil {
+		m["subscribers"] = c.Subscribers
+	}
+	m.setString("_id", c.UID)
+	return json.Marshal(m)
+}
+
+// UnmarshalJSON decodes the check, keeping the unknown attributes
+func (c *Check) UnmarshalJSON(b []byte) error {
+	a, err := unmarshalAttributes(b)
+	if err != nil {
+		return err
+	}
+	*c = *newCheck(a)
+	return nil
+}
+
+func newClient(a Attributes) *Client {
+	return &Client{
+		Dc:            a.popString("dc"),
+		Name:          a.popString("name"),
+		Output:        a.popString("output"),
+		Silenced:      a.popBool("silenced"),
+		Status:        a.popInt("status"),
+		Subscriptions: a.popStrings("subscriptions"),
+		UID:           a.popString("_id"),
+		Attributes:    a,
+	}
+}
+
+// MarshalJSON encodes the client along with its attributes
+func (c Client) MarshalJSON() ([]byte, error) {
+	m := c.Attributes.copy()
+	m.setString("dc", c.Dc)
+	m.setRequiredString("name", c.Name)
+	m.setString("output", c.Output)
+	m.setBool("silenced", c.Silenced)
+	m.setInt("status", c.Status)
+	if c.Subscriptions != nil {
+		m["subscriptions"] = c.Subscriptions
+	}
+	m.setString("_id", c.UID)
+	return json.Marshal(m)
+}
+
+// UnmarshalJSON decodes the client, keeping the unknown attributes
+func (c *Client) UnmarshalJSON(b []byte) error {
+	a, err := unmarshalAttributes(b)
+	if err != nil {
+		return err
+	}
+	*c = *newClient(a)
+	return nil
+}
+
+// MarshalJSON encodes the client history along with its attributes
+func (h ClientHistory) MarshalJSON() ([]byte, error) {
+	m := h.Attributes.copy()
+	m.setRequiredString("check", h.Check)
+	m.setString("client", h.Client)
+	m.setString("dc", h.Dc)
+	if h.LastResult != nil {
+		m["last_result"] = h.LastResult
+	}
+	m.setInt("last_status", h.LastStatus)
+	m.setBool("silenced", h.Silenced)
+	m.setStrings("silenced_by", h.SilencedBy)
+	return json.Marshal(m)
+}
+
+// UnmarshalJSON decodes the client history, keeping the unknown attributes
+func (h *ClientHistory) UnmarshalJSON(b []byte) error {
+	a, err := unmarshalAttributes(b)
+	if err != nil {
+		return err
+	}
+
+	*h = ClientHistory{
+		Check:      a.popString("check"),
+		Client:     a.popString("client"),
+		Dc:         a.popString("dc"),
+		LastStatus: a.popInt("last_status"),
+		Silenced:   a.popBool("silenced"),
+		SilencedBy: a.popStrings("silenced_by"),
+	}
+	if result, ok := a.popObject("last_result"); ok {
+		h.LastResult = newCheck(result)
+	}
+	h.Attributes = a
+	return nil
+}
+
+// MarshalJSON encodes the event along with its attributes
+func (e Event) MarshalJSON() ([]byte, error) {
+	m := e.Attributes.copy()
+	if e.Check != nil {
+		m["check"] = e.Check
+	}
+	if e.Client != nil {
+		m["client"] = e.Client
+	}
+	m.setString("dc", e.Dc)
+	m.setBool("silenced", e.Silenced)
+	m.setStrings("silenced_by", e.SilencedBy)
+	m.setString("_id", e.UID)
+	return json.Marshal(m)
+}
+
+// UnmarshalJSON decodes the event, keeping the unknown attributes
+func (e *Event) UnmarshalJSON(b []byte) error {
+	a, err := unmarshalAttributes(b)
+	if err != nil {
+		return err
+	}
+
+	*e = Event{
+		Dc:         a.popString("dc"),
+		Silenced:   a.popBool("silenced"),
+		SilencedBy: a.popStrings("silenced_by"),
+		UID:        a.popString("_id"),
+	}
+	if check, ok := a.popObject("check"); ok {
+		e.Check = newCheck(check)
+	}
+	if client, ok := a.popObject("client"); ok {
+		e.Client = newClient(client)
+	}
+	e.Attributes = a
+	return nil
+}
+
+// MarshalJSON encodes the silence entry along with its attributes
+func (s Silence) MarshalJSON() ([]byte, error) {
+	m := s.Attributes.copy()
+	m.setString("check", s.Check)
+	m.setString("dc", s.Dc)
+	m.setRequiredString("id", s.ID)
+	m.setString("subscription", s.Subscription)
+	m.setString("_id", s.UID)
+	return json.Marshal(m)
+}
+
+// UnmarshalJSON decodes the silence entry, keeping the unknown attributes
+func (s *Silence) UnmarshalJSON(b []byte) error {
+	a, err := unmarshalAttributes(b)
+	if err != nil {
+		return err
+	}
+
+	*s = Silence{
+		Check:        a.popString("check"),
+		Dc:           a.popString("dc"),
+		ID:           a.popString("id"),
+		Subscription: a.popString("subscription"),
+		UID:          a.popString("_id"),
+		Attributes:   a,
+	}
+	return nil
+}
+
+// MarshalJSON encodes the stash along with its attributes
+func (s Stash) MarshalJSON() ([]byte, error) {
+	m := s.Attributes.copy()
+	m.setString("dc", s.Dc)
+	m.setRequiredString("path", s.Path)
+	m.setString("_id", s.UID)
+	return json.Marshal(m)
+}
+
+// UnmarshalJSON decodes the stash, keeping the unknown attributes
+func (s *Stash) UnmarshalJSON(b []byte) error {
+	a, err := unmarshalAttributes(b)
+	if err != nil {
+		return err
+	}
+
+	*s = Stash{
+		Dc:         a.popString("dc"),
+		Path:       a.popString("path"),
+		UID:        a.popString("_id"),
+		Attributes: a,
+	}
+	return nil
+}
+
+func unmarshalAttributes(b []byte) (Attributes, error) {
+	var a Attributes
+	if err := json.Unmarshal(b, &a); err != nil {
+		return nil, err
+	}
+	if a == nil {
+		a = Attributes{}
+	}
+	return a, nil
+}
+
+// copy returns a shallow copy of the attributes, with room for the fields of
+// the model
+func (a Attributes) copy() Attributes {
+	c := make(Attributes, len(a)+8)
+	for k, v := range a {
+		c[k] = v
+	}
+	return c
+}
+
+// setString sets the attribute unless the value is empty. An empty attribute
+// of the original data is still encoded since popString leaves it in place
+func (a Attributes) setString(key, value string) {
+	if value != "" {
+		a[key] = value
+	}
+}
+
+// The following set methods always set the attribute, unless the value is
+// zero while the attribute is present. Such an attribute had an unexpected
+// type when decoded, and is encoded back as is rather than replaced by zero
+
+func (a Attributes) setBool(key string, value bool) {
+	if _, ok := a[key]; !ok || value {
+		a[key] = value
+	}
+}
+
+func (a Attributes) setInt(key string, value int) {
+	if _, ok := a[key]; !ok || value != 0 {
+		a[key] = value
+	}
+}
+
+func (a Attributes) setRequiredString(key, value string) {
+	if _, ok := a[key]; !ok || value != "" {
+		a[key] = value
+	}
+}
+
+func (a Attributes) setStrings(key string, value []string) {
+	if _, ok := a[key]; !ok || value != nil {
+		a[key] = value
+	}
+}
+
+// The pop methods remove the attribute and return its value. An attribute of
+// an unexpected type is left in the attributes instead of being discarded, as
+// well as an empty string so it is encoded back even though setString omits
+// the empty values
+
+func (a Attributes) popBool(key string) bool {
+	b, ok := a[key].(bool)
+	if ok {
+		delete(a, key)
+	}
+	return b
+}
+
+func (a Attributes) popInt(key string) int {
+	f, ok := a[key].(float64)
+	if ok {
+		delete(a, key)
+	}
+	return int(f)
+}
+
+func (a Attributes) popObject(key string) (Attributes, bool) {
+	m, ok := a[key].(map[string]interface{})
+	if ok {
+		delete(a, key)
+	}
+	return m, ok
+}
+
+func (a Attributes) popString(key string) string {
+	s, ok := a[key].(string)
+	if ok && s != "" {
+		delete(a, key)
+	}
+	return s
+}
+
+func (a Attributes) popStrings(key string) []string {
+	list, ok := a[key].([]interface{})
+	if !ok {
+		return nil
+	}
+
+	strings := make([]string, len(list))
+	for i, e := range list {
+		s, ok := e.(string)
+		if !ok {
+			return nil
+		}
+		strings[i] = s
+	}
+
+	delete(a, key)
+	return strings
+}
@@ -0,0 +1,142 @@
+package structs
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientJSON(t *testing.T) {
+	var c Client
+	err := json.Unmarshal([]byte(`{"name":"foo","address":"10.0.0.1","subscriptions":["linux"],"team":{"name":"ops"}}`), &c)
+	assert.Nil(t, err)
+	assert.Equal(t, "foo", c.Name)
+	assert.Equal(t, []string{"linux"}, c.Subscriptions)
+	assert.Equal(t, Attributes{"address": "10.0.0.1", "team": map[string]interface{}{"name": "ops"}}, c.Attributes)
+
+	c.Dc = "us-east-1"
+	c.Status = 2
+	c.UID = "us-east-1/foo"
+
+	b, err := json.Marshal(c)
+	assert.Nil(t, err)
+	assertJSON(t, `{"_id":"us-east-1/foo","address":"10.0.0.1","dc":"us-east-1","name":"foo","silenced":false,"status":2,"subscriptions":["linux"],"team":{"name":"ops"}}`, string(b))
+
+	// The custom attributes are not altered by the encoding
+	assert.Equal(t, 2, len(c.Attributes))
+}
+
+func TestEventJSON(t *testing.T) {
+	var e Event
+	err := json.Unmarshal([]byte(`{"id":"abc","action":"create","check":{"name":"cpu","status":2,"output":"CRITICAL","subscribers":["linux"],"interval":60},"client":{"name":"foo"},"occurrences":3}`), &e)
+	assert.Nil(t, err)
+	assert.Equal(t, &Check{Name: "cpu", Output: "CRITICAL", Status: 2, Subscribers: []string{"linux"}, Attributes: Attributes{"interval": float64(60)}, hasStatus: true}, e.Check)
+	assert.Equal(t, "foo", e.Client.Name)
+	assert.Equal(t, Attributes{"id": "abc", "action": "create", "occurrences": float64(3)}, e.Attributes)
+
+	b, err := json.Marshal(&e)
+	assert.Nil(t, err)
+	assertJSON(t, `{"id":"abc","action":"create","check":{"name":"cpu","status":2,"output":"CRITICAL","subscribers":["linux"],"interval":60},"client":{"name":"foo","silenced":false,"status":0},"occurrences":3,"silenced":false,"silenced_by":null}`, string(b))
+
+	// Sensu <= 0.12 events
+	e = Event{}
+	err = json.Unmarshal([]byte(`{"check":"cpu","client":"foo","status":2}`), &e)
+	assert.Nil(t, err)
+	assert.Nil(t, e.Check)
+	assert.Nil(t, e.Client)
+	assert.Equal(t, Attributes{"check": "cpu", "client": "foo", "status": float64(2)}, e.Attributes)
+}
+
+func TestCheckStatusJSON(t *testing.T) {
+	// A check definition has no status
+	var c Check
+	err := json.Unmarshal([]byte(`{"name":"cpu","interval":60}`), &c)
+	assert.Nil(t, err)
+	b, err := json.Marshal(c)
+	assert.Nil(t, err)
+	assertJSON(t, `{"name":"cpu","interval":60}`, string(b))
+
+	// While the OK status of a check result is kept
+	err = json.Unmarshal([]byte(`{"name":"cpu","status":0}`), &c)
+	assert.Nil(t, err)
+	b, err = json.Marshal(c)
+	assert.Nil(t, err)
+	assertJSON(t, `{"name":"cpu","status":0}`, string(b))
+
+	b, err = json.Marshal(Check{Name: "cpu", Status: 2})
+	assert.Nil(t, err)
+	assertJSON(t, `{"name":"cpu","status":2}`, string(b))
+}
+
+func TestEmptyStringsJSON(t *testing.T) {
+	// The empty strings of the original data are kept
+	var c Check
+	err := json.Unmarshal([]byte(`{"name":"cpu","output":"","status":0}`), &c)
+	assert.Nil(t, err)
+	assert.Equal(t, "", c.Output)
+	b, err := json.Marshal(c)
+	assert.Nil(t, err)
+	assertJSON(t, `{"name":"cpu","output":"","status":0}`, string(b))
+
+	// Unless the field was populated since
+	c.Output = "OK"
+	b, err = json.Marshal(c)
+	assert.Nil(t, err)
+	assertJSON(t, `{"name":"cpu","output":"OK","status":0}`, string(b))
+
+	// While the empty fields that were not present are still omitted
+	b, err = json.Marshal(Check{Name: "cpu", Status: 2})
+	assert.Nil(t, err)
+	assertJSON(t, `{"name":"cpu","status":2}`, string(b))
+
+	var s Silence
+	err = json.Unmarshal([]byte(`{"id":"linux:*","subscription":"linux","check":""}`), &s)
+	assert.Nil(t, err)
+	b, err = json.Marshal(s)
+	assert.Nil(t, err)
+	assertJSON(t, `{"id":"linux:*","subscription":"linux","check":""}`, string(b))
+}
+
+func TestUnexpectedAttributes(t *testing.T) {
+	// The attributes of an unexpected type are not discarded
+	var c Check
+	err := json.Unmarshal([]byte(`{"name":"cpu","status":"2","subscribers":["linux",1]}`), &c)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, c.Status)
+	assert.Nil(t, c.Subscribers)
+	assert.Equal(t, Attributes{"status": "2", "subscribers": []interface{}{"linux", float64(1)}}, c.Attributes)
+
+	// They are encoded back as is, unless the field was populated since
+	b, err := json.Marshal(c)
+	assert.Nil(t, err)
+	assertJSON(t, `{"name":"cpu","status":"2","subscribers":["linux",1]}`, string(b))
+
+	var cl Client
+	err = json.Unmarshal([]byte(`{"name":"foo","silenced":"yes","status":"2"}`), &cl)
+	assert.Nil(t, err)
+	b, err = json.Marshal(cl)
+	assert.Nil(t, err)
+	assertJSON(t, `{"name":"foo","silenced":"yes","status":"2"}`, string(b))
+
+	cl.Status = 1
+	b, err = json.Marshal(cl)
+	assert.Nil(t, err)
+	assertJSON(t, `{"name":"foo","silenced":"yes","status":1}`, string(b))
+
+	var s Silence
+	err = json.Unmarshal([]byte(`{"id":"*:cpu","check":"cpu","subscription":null}`), &s)
+	assert.Nil(t, err)
+	b, err = json.Marshal(s)
+	assert.Nil(t, err)
+	assertJSON(t, `{"id":"*:cpu","check":"cpu","subscription":null}`, string(b))
+
+	assert.NotNil(t, json.Unmarshal([]byte(`[]`), &c))
+}
+
+func assertJSON(t *testing.T, expected, actual string) {
+	var e, a interface{}
+	assert.Nil(t, json.Unmarshal([]byte(expected), &e))
+	assert.Nil(t, json.Unmarshal([]byte(actual), &a))
+	assert.Equal(t, e, a)
+}
@@ -6,6 +6,7 @@ import "time"
 type AuditLog struct {
 	Date       time.Time `json:"date"`
 	Action     string    `json:"action"`
+	Dc         string    `json:"dc,omitempty"`
 	Level      string    `json:"level"`
 	Output     string    `json:"output,omitempty"`
 	RemoteAddr string    `json:"remoteaddr"`
@@ -16,9 +17,18 @@ type AuditLog struct {
 // Auth struct contains the generic configuration and details
 // about the authentication
 type Auth struct {
-	Driver     string
-	PrivateKey string
-	PublicKey  string
+	Driver             string
+	LoginAttempts      int
+	LoginAttemptsPerIP int
+	LoginBackoff       int // in seconds
+	LoginLockout       int // in minutes
+	PrivateKey         string
+	PublicKey          string
+	RefreshLifetime    int // in minutes
+	TokenLifetime      int // in minutes
+	TokensFile         string
+	TrustedProxies     []string
+	TwoFactorFile      string
 }
 
 // CheckExecution struct contains the payload for issuing a
@@ -31,25 +41,28 @@ type CheckExecution struct {
 
 // Data is a structure for holding public data fetched from the Sensu APIs and exposed by the endpoints
 type Data struct {
-	Aggregates    []interface{}
-	Checks        []interface{}
-	Clients       []interface{}
+	Aggregates    []*Aggregate
+	Checks        []*Check
+	Clients       []*Client
 	Dc            []*Datacenter
-	Events        []interface{}
+	Events        []*Event
 	Health        Health
 	Metrics       Metrics
 	SEMetrics     SEMetrics
 	SERawMetrics  SERawMetrics `json:"-"`
-	Silenced      []interface{}
-	Stashes       []interface{}
+	Silenced      []*Silence
+	Stashes       []*Stash
 	Subscriptions []Subscription
 }
 
 // Datacenter is a structure for holding the information about a datacenter
 type Datacenter struct {
-	Name  string         `json:"name"`
-	Info  Info           `json:"info"`
-	Stats map[string]int `json:"stats"`
+	Name        string         `json:"name"`
+	Info        Info           `json:"info"`
+	Stats       map[string]int `json:"stats"`
+	Age         int64          `json:"age"` // in seconds
+	LastUpdated time.Time      `json:"lastupdated"`
+	Stale       bool           `json:"stale"`
 }
 
 // Generic is a structure for holding a generic element
@@ -57,28 +70,6 @@ type Generic struct {
 	Dc string `json:"dc"`
 }
 
-// GenericCheck is a structure for holding a generic check
-type GenericCheck struct {
-	Dc          string   `json:"dc"`
-	Output      string   `json:"output"`
-	Status      int      `json:"status"`
-	Subscribers []string `json:"subscribers"`
-}
-
-// GenericClient is a structure for holding a generic client
-type GenericClient struct {
-	Dc            string   `json:"dc"`
-	Name          string   `json:"name"`
-	Subscriptions []string `json:"subscriptions"`
-}
-
-// GenericEvent is a structure for holding a generic event
-type GenericEvent struct {
-	Check  GenericCheck  `json:"check"`
-	Client GenericClient `json:"client"`
-	Dc     string        `json:"dc"`
-}
-
 // Health is a structure for holding health informaton about Sensu & Uchiwa
 type Health struct {
 	Sensu  map[string]SensuHealth `json:"sensu"`
@@ -87,8 +78,23 @@ type Health struct {
 
 // SensuHealth is a structure for holding health information about a specific sensu datacenter
 type SensuHealth struct {
-	Output string `json:"output"`
-	Status int    `json:"status"`
+	Output      string           `json:"output"`
+	Status      int              `json:"status"`
+	Age         int64            `json:"age,omitempty"` // in seconds
+	LastUpdated *time.Time       `json:"lastupdated,omitempty"`
+	Stale       bool             `json:"stale,omitempty"`
+	APIs        []SensuAPIHealth `json:"apis,omitempty"`
+}
+
+// SensuAPIHealth is a structure for holding health information about a specific Sensu API
+type SensuAPIHealth struct {
+	URL       string `json:"url"`
+	Available bool   `json:"available"` // false while its circuit breaker is open
+	Errors    uint64 `json:"errors"`
+	LastError string `json:"lasterror,omitempty"`
+	TLSError  string `json:"tlserror,omitempty"` // the last error, if it occurred during the TLS handshake
+	Latency   int64  `json:"latency"`            // average, in milliseconds
+	Requests  uint64 `json:"requests"`
 }
 
 // Info is a structure for holding the /info API information